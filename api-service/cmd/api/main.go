@@ -13,6 +13,7 @@ import (
 	"reviews-movies/api-service/internal/handler"
 	"reviews-movies/api-service/internal/kafka"
 	logger2 "reviews-movies/api-service/internal/logger"
+	"reviews-movies/api-service/internal/tracing"
 	"strings"
 	"syscall"
 	"time"
@@ -25,7 +26,10 @@ type Application struct {
 
 // test
 func main() {
-	cfg, _ := config.LoadConfig()
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
 
 	logger := logger2.InitLogger(cfg.Env, os.Stdout)
 
@@ -34,7 +38,13 @@ func main() {
 		logger: logger,
 	}
 
-	logger.Debug("Kafka Address: "+strings.Join(cfg.Kafka.Address, ", "), nil)
+	logger.Debug("Kafka Address: " + strings.Join(cfg.Kafka.Address, ", "))
+
+	shutdownTracing, err := tracing.Init("api-service", cfg.Tracing.Output)
+	if err != nil {
+		log.Fatalf("Failed to init tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
 
 	producer, err := kafka.NewProducer(cfg.Kafka.Address)
 	if err != nil {
@@ -43,8 +53,10 @@ func main() {
 	defer producer.Close()
 
 	newHandler := handler.NewHandler(producer, logger, cfg)
-	app.serve(newHandler.Routes())
-	log.Fatal(err, nil)
+	defer newHandler.Close()
+	if err := app.serve(newHandler.Routes()); err != nil {
+		logger.Error(err.Error())
+	}
 }
 
 func (app *Application) serve(handler http.Handler) error {
@@ -65,9 +77,7 @@ func (app *Application) serve(handler http.Handler) error {
 
 		s := <-quit
 
-		app.logger.Info("caught signal", map[string]string{
-			"signal": s.String(),
-		})
+		app.logger.Info("caught signal", "signal", s.String())
 
 		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 		defer cancel()
@@ -75,10 +85,7 @@ func (app *Application) serve(handler http.Handler) error {
 		shutdownError <- srv.Shutdown(ctx)
 	}()
 
-	app.logger.Info("starting server", map[string]string{
-		"env":  app.config.Env,
-		"addr": srv.Addr,
-	})
+	app.logger.Info("starting server", "env", app.config.Env, "addr", srv.Addr)
 
 	err := srv.ListenAndServe()
 	if !errors.Is(err, http.ErrServerClosed) {
@@ -90,9 +97,7 @@ func (app *Application) serve(handler http.Handler) error {
 		return err
 	}
 
-	app.logger.Info("stopped server", map[string]string{
-		"addr": srv.Addr,
-	})
+	app.logger.Info("stopped server", "addr", srv.Addr)
 
 	return nil
 }
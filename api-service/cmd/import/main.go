@@ -0,0 +1,118 @@
+// Команда import загружает каталог фильмов из CSV или JSONL в Kafka тем же
+// конвейером, что и POST /api/imports.
+//
+//	import -file catalog.csv -mapping title=Name,year=Released -errors errors.csv
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reviews-movies/api-service/config"
+	"reviews-movies/api-service/internal/importer"
+	"reviews-movies/api-service/internal/kafka"
+	logger2 "reviews-movies/api-service/internal/logger"
+	"strings"
+	"syscall"
+	"time"
+)
+
+func main() {
+	var (
+		file       = flag.String("file", "-", "path to the catalog, - for stdin")
+		format     = flag.String("format", "", "csv or jsonl, detected from the file extension by default")
+		mapping    = flag.String("mapping", "", "column mapping, e.g. title=Name,year=Released")
+		separators = flag.String("genre-separators", importer.DefaultGenreSeparators, "characters separating genres in a single column")
+		report     = flag.String("errors", "", "path to write the per-row error report as CSV")
+	)
+	flag.Parse()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	logger := logger2.InitLogger(cfg.Env, os.Stderr)
+
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(*file), ".")
+	}
+	f, err := importer.ParseFormat(*format)
+	if err != nil {
+		log.Fatalf("Invalid format: %v", err)
+	}
+	m, err := importer.ParseMapping(*mapping)
+	if err != nil {
+		log.Fatalf("Invalid mapping: %v", err)
+	}
+
+	var (
+		src  io.Reader = os.Stdin
+		size int64
+	)
+	if *file != "-" {
+		in, err := os.Open(*file)
+		if err != nil {
+			log.Fatalf("Failed to open catalog: %v", err)
+		}
+		defer in.Close()
+		if info, err := in.Stat(); err == nil {
+			size = info.Size()
+		}
+		src = in
+	}
+
+	producer, err := kafka.NewProducer(cfg.Kafka.Address)
+	if err != nil {
+		log.Fatalf("Failed to create producer: %v", err)
+	}
+	defer producer.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	job := importer.NewJobStore(1).Create(f, size)
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(2 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				v := job.View()
+				logger.Info("import progress", "progress", v.Progress, "processed", v.Processed, "rejected", v.Rejected)
+			}
+		}
+	}()
+
+	err = importer.New(producer, cfg.Kafka.Topics.Movie, logger).Run(ctx, job, src, importer.Options{
+		Format:          f,
+		Mapping:         m,
+		GenreSeparators: *separators,
+	})
+	close(done)
+
+	v := job.View()
+	logger.Info("import finished", "status", string(v.Status), "processed", v.Processed, "accepted", v.Accepted, "rejected", v.Rejected)
+
+	if *report != "" {
+		out, err := os.Create(*report)
+		if err != nil {
+			log.Fatalf("Failed to create error report: %v", err)
+		}
+		defer out.Close()
+		if err := job.WriteErrorReport(out); err != nil {
+			log.Fatalf("Failed to write error report: %v", err)
+		}
+	}
+
+	if err != nil {
+		logger.Error("import failed", "error", err)
+		os.Exit(1)
+	}
+}
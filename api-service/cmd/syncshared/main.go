@@ -0,0 +1,131 @@
+// Команда syncshared копирует общие пакеты из data-service в api-service.
+// Источник истины — пакеты из списка packages в data-service/internal:
+// правки вносятся там, а копии шлюза пересобираются генератором, который
+// переписывает пути импорта под модуль шлюза.
+//
+//	go generate ./cmd/syncshared
+//	go run ./cmd/syncshared -check
+package main
+
+//go:generate go run . -root ../..
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// packages — пакеты, общие для обоих сервисов.
+var packages = []string{"health", "problem", "requestid", "rules", "tracing", "validator"}
+
+const (
+	srcModule = "data-service/internal/"
+	dstModule = "reviews-movies/api-service/internal/"
+)
+
+func main() {
+	var (
+		root  = flag.String("root", ".", "api-service module root")
+		check = flag.Bool("check", false, "report stale copies instead of rewriting them")
+	)
+	flag.Parse()
+
+	stale, err := syncPackages(*root, !*check)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *check && len(stale) > 0 {
+		log.Fatalf("stale copies, run go generate ./cmd/syncshared: %s", strings.Join(stale, ", "))
+	}
+}
+
+// syncPackages сравнивает копии пакетов в api-service с источником в соседнем
+// data-service и возвращает устаревшие файлы. При write они перезаписываются,
+// а файлы, которых больше нет в источнике, удаляются.
+func syncPackages(root string, write bool) ([]string, error) {
+	var stale []string
+	for _, pkg := range packages {
+		srcDir := filepath.Join(root, "..", "data-service", "internal", pkg)
+		dstDir := filepath.Join(root, "internal", pkg)
+
+		want := map[string][]byte{}
+		sources, err := filepath.Glob(filepath.Join(srcDir, "*.go"))
+		if err != nil {
+			return nil, err
+		}
+		if len(sources) == 0 {
+			return nil, fmt.Errorf("no sources in %s", srcDir)
+		}
+		for _, src := range sources {
+			if strings.HasSuffix(src, "_test.go") {
+				continue
+			}
+			content, err := generate(pkg, src)
+			if err != nil {
+				return nil, err
+			}
+			want[filepath.Base(src)] = content
+		}
+
+		existing, err := filepath.Glob(filepath.Join(dstDir, "*.go"))
+		if err != nil {
+			return nil, err
+		}
+		for _, dst := range existing {
+			if _, ok := want[filepath.Base(dst)]; ok || strings.HasSuffix(dst, "_test.go") {
+				continue
+			}
+			stale = append(stale, dst)
+			if write {
+				if err := os.Remove(dst); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		for name, content := range want {
+			dst := filepath.Join(dstDir, name)
+			current, err := os.ReadFile(dst)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, err
+			}
+			if bytes.Equal(current, content) {
+				continue
+			}
+			stale = append(stale, dst)
+			if write {
+				if err := os.MkdirAll(dstDir, 0o755); err != nil {
+					return nil, err
+				}
+				if err := os.WriteFile(dst, content, 0o644); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return stale, nil
+}
+
+// generate возвращает копию файла src для шлюза: с пометкой о генерации
+// и импортами модуля api-service.
+func generate(pkg, src string) ([]byte, error) {
+	content, err := os.ReadFile(src)
+	if err != nil {
+		return nil, err
+	}
+	header := fmt.Sprintf("// Code generated by cmd/syncshared from data-service/internal/%s. DO NOT EDIT.\n\n", pkg)
+	content = append([]byte(header), bytes.ReplaceAll(content, []byte(`"`+srcModule), []byte(`"`+dstModule))...)
+	// Новые пути импорта сортируются иначе, format.Source восстанавливает
+	// порядок.
+	formatted, err := format.Source(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", src, err)
+	}
+	return formatted, nil
+}
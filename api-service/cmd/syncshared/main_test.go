@@ -0,0 +1,22 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestSharedPackagesInSync падает, если копии пакетов шлюза разошлись с
+// data-service.
+func TestSharedPackagesInSync(t *testing.T) {
+	if _, err := os.Stat(filepath.Join("..", "..", "..", "data-service", "internal")); err != nil {
+		t.Skip("data-service sources are not available")
+	}
+	stale, err := syncPackages(filepath.Join("..", ".."), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range stale {
+		t.Errorf("%s is stale, run go generate ./cmd/syncshared", file)
+	}
+}
@@ -1,8 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -21,7 +24,46 @@ type Config struct {
 		}
 	}
 	Services struct {
-		DataServiceHost string
+		DataServiceHosts  []string
+		Discovery         string
+		LBStrategy        string
+		HealthInterval    time.Duration
+		DiscoveryInterval time.Duration
+	}
+	Tracing struct {
+		Output string
+	}
+	Health struct {
+		CacheTTL time.Duration
+	}
+	Upstream struct {
+		Timeout                 time.Duration
+		RetryMaxAttempts        int
+		RetryBaseDelay          time.Duration
+		RetryMaxDelay           time.Duration
+		BreakerFailures         int
+		BreakerOpenTimeout      time.Duration
+		BreakerHalfOpenRequests int
+		HedgeDelay              time.Duration
+	}
+	// Cache — кэш ответов data-service в памяти процесса. Реплики шлюза не
+	// сообщают друг другу об изменениях, и чужие реплики отдают прежние
+	// ответы до истечения TTL. PendingWindow — сколько не кэшируются ответы,
+	// затронутые записью через Kafka, пока её применяет консьюмер.
+	Cache struct {
+		Size          int
+		TTL           time.Duration
+		PendingWindow time.Duration
+	}
+	Idempotency struct {
+		TTL time.Duration
+	}
+	Batch struct {
+		MaxItems int
+	}
+	Import struct {
+		MaxBytes int64
+		MaxJobs  int
 	}
 }
 
@@ -36,7 +78,27 @@ func LoadConfig() (*Config, error) {
 		Env:  getEnv("API_SERVICE_ENV", "development"),
 	}
 
-	cfg.Services.DataServiceHost = os.Getenv("DATA_SERVICE_HOST")
+	for _, host := range strings.Split(os.Getenv("DATA_SERVICE_HOST"), ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			cfg.Services.DataServiceHosts = append(cfg.Services.DataServiceHosts, host)
+		}
+	}
+	cfg.Services.Discovery = getEnv("DATA_SERVICE_DISCOVERY", "static")
+	cfg.Services.LBStrategy = getEnv("DATA_SERVICE_LB_STRATEGY", "round_robin")
+	if cfg.Services.HealthInterval, err = getEnvDuration("DATA_SERVICE_HEALTH_INTERVAL", "5s"); err != nil {
+		return nil, err
+	}
+	if cfg.Services.DiscoveryInterval, err = getEnvDuration("DATA_SERVICE_DISCOVERY_INTERVAL", "30s"); err != nil {
+		return nil, err
+	}
+	// Интервалы задают тикеры балансировщика, а time.NewTicker не
+	// принимает неположительный период.
+	if cfg.Services.HealthInterval <= 0 {
+		return nil, fmt.Errorf("DATA_SERVICE_HEALTH_INTERVAL must be positive")
+	}
+	if cfg.Services.DiscoveryInterval <= 0 {
+		return nil, fmt.Errorf("DATA_SERVICE_DISCOVERY_INTERVAL must be positive")
+	}
 
 	cfg.Kafka.Address = []string{
 		os.Getenv("BROKER1_HOST"),
@@ -47,6 +109,64 @@ func LoadConfig() (*Config, error) {
 	cfg.Kafka.Topics.Movie = getEnv("MOVIES_TOPIC", "movies-topic")
 	cfg.Kafka.Topics.Review = getEnv("REVIEWS_TOPIC", "reviews-topic")
 
+	cfg.Tracing.Output = getEnv("TRACING_OUTPUT", "")
+
+	if cfg.Health.CacheTTL, err = getEnvDuration("HEALTH_CACHE_TTL", "5s"); err != nil {
+		return nil, err
+	}
+
+	if cfg.Upstream.Timeout, err = getEnvDuration("UPSTREAM_TIMEOUT", "5s"); err != nil {
+		return nil, err
+	}
+	if cfg.Upstream.RetryMaxAttempts, err = getEnvInt("UPSTREAM_RETRY_MAX_ATTEMPTS", "3"); err != nil {
+		return nil, err
+	}
+	if cfg.Upstream.RetryBaseDelay, err = getEnvDuration("UPSTREAM_RETRY_BASE_DELAY", "50ms"); err != nil {
+		return nil, err
+	}
+	if cfg.Upstream.RetryMaxDelay, err = getEnvDuration("UPSTREAM_RETRY_MAX_DELAY", "1s"); err != nil {
+		return nil, err
+	}
+	if cfg.Upstream.BreakerFailures, err = getEnvInt("UPSTREAM_BREAKER_FAILURES", "5"); err != nil {
+		return nil, err
+	}
+	if cfg.Upstream.BreakerOpenTimeout, err = getEnvDuration("UPSTREAM_BREAKER_OPEN_TIMEOUT", "30s"); err != nil {
+		return nil, err
+	}
+	if cfg.Upstream.BreakerHalfOpenRequests, err = getEnvInt("UPSTREAM_BREAKER_HALF_OPEN_REQUESTS", "1"); err != nil {
+		return nil, err
+	}
+	if cfg.Upstream.HedgeDelay, err = getEnvDuration("UPSTREAM_HEDGE_DELAY", "0"); err != nil {
+		return nil, err
+	}
+
+	if cfg.Cache.Size, err = getEnvInt("RESPONSE_CACHE_SIZE", "1000"); err != nil {
+		return nil, err
+	}
+	if cfg.Cache.TTL, err = getEnvDuration("RESPONSE_CACHE_TTL", "30s"); err != nil {
+		return nil, err
+	}
+	if cfg.Cache.PendingWindow, err = getEnvDuration("RESPONSE_CACHE_PENDING_WINDOW", "5s"); err != nil {
+		return nil, err
+	}
+
+	if cfg.Idempotency.TTL, err = getEnvDuration("IDEMPOTENCY_TTL", "24h"); err != nil {
+		return nil, err
+	}
+
+	if cfg.Batch.MaxItems, err = getEnvInt("BATCH_MAX_ITEMS", "500"); err != nil {
+		return nil, err
+	}
+
+	maxBytes, err := getEnvInt("IMPORT_MAX_BYTES", "52428800")
+	if err != nil {
+		return nil, err
+	}
+	cfg.Import.MaxBytes = int64(maxBytes)
+	if cfg.Import.MaxJobs, err = getEnvInt("IMPORT_MAX_JOBS", "100"); err != nil {
+		return nil, err
+	}
+
 	return cfg, nil
 
 }
@@ -57,3 +177,11 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getEnvInt(key, fallback string) (int, error) {
+	return strconv.Atoi(getEnv(key, fallback))
+}
+
+func getEnvDuration(key, fallback string) (time.Duration, error) {
+	return time.ParseDuration(getEnv(key, fallback))
+}
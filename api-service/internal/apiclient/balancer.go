@@ -0,0 +1,207 @@
+package apiclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reviews-movies/api-service/internal/metrics"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	RoundRobin        = "round_robin"
+	LeastOutstanding  = "least_outstanding"
+	healthCheckPath   = "/healthz"
+	healthCheckWindow = 2 * time.Second
+)
+
+type instance struct {
+	url         string
+	healthy     atomic.Bool
+	outstanding atomic.Int64
+}
+
+func (i *instance) acquire() func() {
+	i.outstanding.Add(1)
+	metrics.UpstreamOutstanding.WithLabelValues(i.url).Inc()
+	return func() {
+		i.outstanding.Add(-1)
+		metrics.UpstreamOutstanding.WithLabelValues(i.url).Dec()
+	}
+}
+
+// pool хранит список инстансов, обновляет его из Discovery и
+// периодически проверяет их /healthz.
+type pool struct {
+	discovery Discovery
+	opts      BalancerOptions
+	probe     *http.Client
+
+	mu        sync.RWMutex
+	instances []*instance
+	next      atomic.Uint64
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newPool(discovery Discovery, opts BalancerOptions) *pool {
+	p := &pool{
+		discovery: discovery,
+		opts:      opts,
+		probe:     &http.Client{Timeout: healthCheckWindow},
+		done:      make(chan struct{}),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.refresh(ctx)
+	p.checkHealth(ctx)
+	go p.loop(ctx)
+
+	return p
+}
+
+func (p *pool) loop(ctx context.Context) {
+	defer close(p.done)
+
+	health := time.NewTicker(p.opts.HealthInterval)
+	defer health.Stop()
+	discovery := time.NewTicker(p.opts.DiscoveryInterval)
+	defer discovery.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-discovery.C:
+			p.refresh(ctx)
+		case <-health.C:
+			p.checkHealth(ctx)
+		}
+	}
+}
+
+func (p *pool) close() {
+	p.cancel()
+	<-p.done
+}
+
+func (p *pool) refresh(ctx context.Context) {
+	urls, err := p.discovery.Instances(ctx)
+	if err != nil || len(urls) == 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	current := make(map[string]*instance, len(p.instances))
+	for _, inst := range p.instances {
+		current[inst.url] = inst
+	}
+
+	instances := make([]*instance, 0, len(urls))
+	for _, u := range urls {
+		inst, ok := current[u]
+		if !ok {
+			inst = &instance{url: u}
+			inst.healthy.Store(true)
+		}
+		instances = append(instances, inst)
+	}
+	p.instances = instances
+}
+
+func (p *pool) snapshot() []*instance {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.instances
+}
+
+func (p *pool) checkHealth(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, inst := range p.snapshot() {
+		wg.Add(1)
+		go func(inst *instance) {
+			defer wg.Done()
+			healthy := p.ping(ctx, inst) == nil
+			inst.healthy.Store(healthy)
+			metrics.UpstreamHealthy.WithLabelValues(inst.url).Set(boolToFloat(healthy))
+		}(inst)
+	}
+	wg.Wait()
+}
+
+func (p *pool) ping(ctx context.Context, inst *instance) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, inst.url+healthCheckPath, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.probe.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(resp.Status)
+	}
+	return nil
+}
+
+// remaining сообщает, есть ли в пуле инстансы помимо exclude.
+func (p *pool) remaining(exclude []*instance) bool {
+	for _, inst := range p.snapshot() {
+		if !slices.Contains(exclude, inst) {
+			return true
+		}
+	}
+	return false
+}
+
+// pick выбирает инстанс по стратегии среди здоровых, пропуская exclude.
+// Если здоровых не осталось, выбор идёт среди всех — лучше попытаться,
+// чем гарантированно вернуть ошибку.
+func (p *pool) pick(exclude []*instance) (*instance, error) {
+	var healthy, alive []*instance
+	for _, inst := range p.snapshot() {
+		if slices.Contains(exclude, inst) {
+			continue
+		}
+		alive = append(alive, inst)
+		if inst.healthy.Load() {
+			healthy = append(healthy, inst)
+		}
+	}
+
+	candidates := healthy
+	if len(candidates) == 0 {
+		candidates = alive
+	}
+	if len(candidates) == 0 {
+		return nil, ErrNoInstances
+	}
+
+	if p.opts.Strategy == LeastOutstanding {
+		best := candidates[0]
+		for _, inst := range candidates[1:] {
+			if inst.outstanding.Load() < best.outstanding.Load() {
+				best = inst
+			}
+		}
+		return best, nil
+	}
+
+	n := p.next.Add(1) - 1
+	return candidates[n%uint64(len(candidates))], nil
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
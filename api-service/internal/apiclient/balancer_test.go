@@ -0,0 +1,189 @@
+package apiclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testPool собирает пул без фоновых проверок: все инстансы здоровы.
+func testPool(strategy string, urls ...string) *pool {
+	p := &pool{opts: BalancerOptions{Strategy: strategy}}
+	for _, u := range urls {
+		inst := &instance{url: u}
+		inst.healthy.Store(true)
+		p.instances = append(p.instances, inst)
+	}
+	return p
+}
+
+func pickURLs(t *testing.T, p *pool, n int, exclude ...*instance) []string {
+	t.Helper()
+	var urls []string
+	for range n {
+		inst, err := p.pick(exclude)
+		if err != nil {
+			t.Fatal(err)
+		}
+		urls = append(urls, inst.url)
+	}
+	return urls
+}
+
+func TestPickRoundRobin(t *testing.T) {
+	p := testPool(RoundRobin, "a", "b", "c")
+	if got, want := pickURLs(t, p, 6), []string{"a", "b", "c", "a", "b", "c"}; !slices.Equal(got, want) {
+		t.Errorf("picks = %v, want %v", got, want)
+	}
+
+	// Выбывший инстанс пропускается.
+	p.instances[1].healthy.Store(false)
+	for _, u := range pickURLs(t, p, 4) {
+		if u == "b" {
+			t.Fatal("round robin picked an unhealthy instance")
+		}
+	}
+
+	// exclude тоже.
+	for _, u := range pickURLs(t, p, 4, p.instances[0]) {
+		if u != "c" {
+			t.Fatalf("picked %s, want only c", u)
+		}
+	}
+}
+
+func TestPickLeastOutstanding(t *testing.T) {
+	p := testPool(LeastOutstanding, "a", "b", "c")
+	p.instances[0].outstanding.Store(3)
+	p.instances[1].outstanding.Store(1)
+	p.instances[2].outstanding.Store(2)
+
+	if got := pickURLs(t, p, 1); got[0] != "b" {
+		t.Errorf("picked %s, want b", got[0])
+	}
+
+	release := p.instances[1].acquire()
+	p.instances[1].acquire()
+	if got := pickURLs(t, p, 1); got[0] != "c" {
+		t.Errorf("picked %s after acquire, want c", got[0])
+	}
+	release()
+	if got := pickURLs(t, p, 1, p.instances[2]); got[0] != "b" {
+		t.Errorf("picked %s with c excluded, want b", got[0])
+	}
+}
+
+func TestPickWithoutHealthyInstances(t *testing.T) {
+	p := testPool(RoundRobin, "a", "b")
+	for _, inst := range p.instances {
+		inst.healthy.Store(false)
+	}
+	// Лучше попытаться, чем гарантированно вернуть ошибку.
+	if got := pickURLs(t, p, 2); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("picks = %v, want a, b", got)
+	}
+}
+
+func TestPickSingleInstance(t *testing.T) {
+	p := testPool(RoundRobin, "a")
+	only := p.instances[0]
+
+	if !p.remaining(nil) {
+		t.Error("remaining without exclude = false")
+	}
+	if p.remaining([]*instance{only}) {
+		t.Error("remaining with the only instance excluded = true")
+	}
+	if _, err := p.pick([]*instance{only}); !errors.Is(err, ErrNoInstances) {
+		t.Errorf("pick error = %v, want ErrNoInstances", err)
+	}
+	if _, err := (&pool{}).pick(nil); !errors.Is(err, ErrNoInstances) {
+		t.Errorf("empty pool error = %v, want ErrNoInstances", err)
+	}
+}
+
+func TestHealthEjection(t *testing.T) {
+	var down atomic.Bool
+	flapping := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(flapping.Close)
+	stable := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	t.Cleanup(stable.Close)
+
+	p := newPool(StaticDiscovery{flapping.URL, stable.URL}, BalancerOptions{
+		Strategy: RoundRobin, HealthInterval: time.Hour, DiscoveryInterval: time.Hour,
+	})
+	t.Cleanup(p.close)
+
+	down.Store(true)
+	p.checkHealth(context.Background())
+	for _, u := range pickURLs(t, p, 4) {
+		if u != stable.URL {
+			t.Fatalf("picked %s, want only the healthy instance", u)
+		}
+	}
+
+	down.Store(false)
+	p.checkHealth(context.Background())
+	if got := pickURLs(t, p, 2); !slices.Contains(got, flapping.URL) {
+		t.Errorf("picks = %v, recovered instance was not returned to rotation", got)
+	}
+}
+
+// mutableDiscovery отдаёт список, который тест меняет между обновлениями.
+type mutableDiscovery struct {
+	mu   sync.Mutex
+	urls []string
+	err  error
+}
+
+func (d *mutableDiscovery) set(err error, urls ...string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.urls, d.err = urls, err
+}
+
+func (d *mutableDiscovery) Instances(context.Context) ([]string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.urls, d.err
+}
+
+func TestPoolRefresh(t *testing.T) {
+	d := &mutableDiscovery{urls: []string{"http://a", "http://b"}}
+	p := &pool{discovery: d}
+	p.refresh(context.Background())
+
+	a := p.snapshot()[0]
+	a.healthy.Store(false)
+	a.outstanding.Store(2)
+
+	d.set(nil, "http://a", "http://c")
+	p.refresh(context.Background())
+	got := p.snapshot()
+	if len(got) != 2 || got[0] != a || got[1].url != "http://c" || !got[1].healthy.Load() {
+		t.Fatalf("instances after refresh = %v", got)
+	}
+	// Известный инстанс сохраняет состояние.
+	if a.healthy.Load() || a.outstanding.Load() != 2 {
+		t.Error("refresh reset the state of a known instance")
+	}
+
+	// Ошибка или пустой ответ не опустошают пул.
+	d.set(errors.New("dns down"))
+	p.refresh(context.Background())
+	d.set(nil)
+	p.refresh(context.Background())
+	if len(p.snapshot()) != 2 {
+		t.Errorf("pool lost instances after a failed discovery: %v", p.snapshot())
+	}
+}
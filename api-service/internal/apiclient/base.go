@@ -3,25 +3,167 @@ package apiclient
 import (
 	"context"
 	"fmt"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"io"
+	"net/http"
 	"resty.dev/v3"
+	"reviews-movies/api-service/internal/metrics"
+	"reviews-movies/api-service/internal/requestid"
+	"slices"
+	"strconv"
 	"time"
 )
 
 type BaseClient struct {
-	http *resty.Client
+	http     *resty.Client
+	opts     Options
+	breakers *breakers
+	pool     *pool
 }
 
-func NewBaseClient(baseURL string, timeoutSec int) *BaseClient {
+func NewBaseClient(discovery Discovery, opts Options) *BaseClient {
 	cli := resty.New().
-		SetBaseURL(baseURL).
-		SetTimeout(time.Duration(timeoutSec) * time.Second)
-	return &BaseClient{http: cli}
+		SetTimeout(opts.Timeout)
+	cli.SetTransport(otelhttp.NewTransport(cli.Transport(),
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method + " " + r.URL.Path
+		}),
+	))
+	return &BaseClient{
+		http:     cli,
+		opts:     opts,
+		breakers: newBreakers(opts.Breaker),
+		pool:     newPool(discovery, opts.Balancer),
+	}
+}
+
+func (c *BaseClient) Close() {
+	c.pool.close()
+}
+
+type result struct {
+	body        []byte
+	status      int
+	contentType string
+	err         error
+	inst        *instance
+}
+
+func (r result) failed() bool {
+	return r.err != nil || r.status >= http.StatusInternalServerError
 }
 
 func (c *BaseClient) DoRequest(ctx context.Context, method string, path string, payload []byte, headers map[string][]string) (body []byte, httpStatus int, contentType string, err error) {
+	endpoint := metrics.Endpoint(path)
+
+	maxAttempts := max(c.opts.Retry.MaxAttempts, 1)
+
+	var (
+		res   result
+		tried []*instance
+	)
+	for attempt := 1; ; attempt++ {
+		inst, br, err := c.choose(method, endpoint, tried)
+		if err != nil {
+			if attempt == 1 {
+				return nil, 0, "", err
+			}
+			break
+		}
+
+		primary := func(ctx context.Context) result {
+			return c.attempt(ctx, inst, br, method, endpoint, path, payload, headers)
+		}
+		if method == resty.MethodGet && c.opts.HedgeDelay > 0 {
+			secondary := func(ctx context.Context) result {
+				inst, br, err := c.choose(method, endpoint, nil)
+				if err != nil {
+					return result{err: err}
+				}
+				return c.attempt(ctx, inst, br, method, endpoint, path, payload, headers)
+			}
+			res = hedge(ctx, c.opts.HedgeDelay, endpoint, primary, secondary)
+		} else {
+			res = primary(ctx)
+		}
+
+		if !res.failed() || attempt >= maxAttempts || ctx.Err() != nil {
+			break
+		}
+
+		// Соединение не установилось — запрос до сервера не дошёл, поэтому
+		// его можно сразу повторить на другом инстансе даже для POST/PATCH.
+		// Если непробованных инстансов не осталось, обход начинается заново
+		// и повтор идёт по общим правилам с задержкой.
+		if res.err != nil && IsConnectionError(res.err) && res.inst != nil {
+			tried = append(tried, res.inst)
+			if c.pool.remaining(tried) {
+				metrics.UpstreamRetries.WithLabelValues(method, endpoint).Inc()
+				continue
+			}
+			tried = nil
+		}
+
+		if !isIdempotent(method) || !retryableStatus(res) {
+			break
+		}
+
+		metrics.UpstreamRetries.WithLabelValues(method, endpoint).Inc()
+		if err := sleep(ctx, backoff(c.opts.Retry, attempt)); err != nil {
+			break
+		}
+	}
+
+	return res.body, res.status, res.contentType, res.err
+}
+
+// choose выбирает инстанс, у которого для данного эндпоинта не открыт
+// circuit breaker. Если все такие инстансы отклонены, возвращается ErrCircuitOpen.
+func (c *BaseClient) choose(method, endpoint string, tried []*instance) (*instance, *breaker, error) {
+	exclude := slices.Clone(tried)
+	for {
+		inst, err := c.pool.pick(exclude)
+		if err != nil {
+			if len(exclude) > len(tried) {
+				return nil, nil, fmt.Errorf("%s %s: %w", method, endpoint, ErrCircuitOpen)
+			}
+			return nil, nil, err
+		}
+
+		br := c.breakers.get(inst.url + " " + method + " " + endpoint)
+		if br.allow() {
+			return inst, br, nil
+		}
+		metrics.UpstreamBreakerRejections.WithLabelValues(method, endpoint).Inc()
+		exclude = append(exclude, inst)
+	}
+}
+
+func (c *BaseClient) attempt(ctx context.Context, inst *instance, br *breaker, method, endpoint, path string, payload []byte, headers map[string][]string) result {
+	release := inst.acquire()
+	defer release()
+
+	res := c.send(ctx, method, endpoint, inst.url+path, payload, headers)
+	res.inst = inst
+
+	switch {
+	case ctx.Err() != nil:
+		br.done(outcomeIgnored)
+	case res.failed():
+		br.done(outcomeFailure)
+	default:
+		br.done(outcomeSuccess)
+	}
+	return res
+}
+
+func (c *BaseClient) send(ctx context.Context, method, endpoint, url string, payload []byte, headers map[string][]string) (res result) {
 	req := c.http.R().SetContext(ctx)
 
+	if id := requestid.FromContext(ctx); id != "" {
+		req.SetHeader(requestid.Header, id)
+	}
+
 	if payload != nil {
 		req.SetBody(payload)
 	}
@@ -32,29 +174,56 @@ func (c *BaseClient) DoRequest(ctx context.Context, method string, path string,
 		}
 	}
 
-	var resp *resty.Response
+	start := time.Now()
+	defer func() {
+		status := "error"
+		if res.status != 0 {
+			status = strconv.Itoa(res.status)
+		}
+		metrics.UpstreamDuration.
+			WithLabelValues(method, endpoint, status).
+			Observe(time.Since(start).Seconds())
+	}()
+
+	var (
+		resp *resty.Response
+		err  error
+	)
 	switch method {
 	case resty.MethodGet:
-		resp, err = req.Get(path)
+		resp, err = req.Get(url)
 	case resty.MethodPatch:
-		resp, err = req.Patch(path)
+		resp, err = req.Patch(url)
 	case resty.MethodDelete:
-		resp, err = req.Delete(path)
+		resp, err = req.Delete(url)
 	case resty.MethodPost:
-		resp, err = req.Post(path)
+		resp, err = req.Post(url)
 	case resty.MethodPut:
-		resp, err = req.Put(path)
+		resp, err = req.Put(url)
 	default:
-		return nil, 0, "", fmt.Errorf("unsupported method %q", method)
+		return result{err: fmt.Errorf("unsupported method %q", method)}
 	}
 	if err != nil {
-		return nil, 0, "", fmt.Errorf("resty %s error: %w", method, err)
+		return result{err: fmt.Errorf("resty %s error: %w", method, err)}
 	}
 
-	body, err = io.ReadAll(resp.Body)
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, 0, "", err
+		return result{err: err}
 	}
 
-	return body, resp.StatusCode(), resp.Header().Get("Content-Type"), nil
+	return result{body: body, status: resp.StatusCode(), contentType: resp.Header().Get("Content-Type")}
+}
+
+// Ping считает data-service доступным, если отвечает хотя бы один инстанс.
+func (c *BaseClient) Ping(ctx context.Context) error {
+	var lastErr error = ErrNoInstances
+	for _, inst := range c.pool.snapshot() {
+		if err := c.pool.ping(ctx, inst); err != nil {
+			lastErr = fmt.Errorf("%s: %w", inst.url, err)
+			continue
+		}
+		return nil
+	}
+	return lastErr
 }
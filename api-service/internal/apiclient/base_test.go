@@ -0,0 +1,121 @@
+package apiclient
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// testOptions — параметры клиента для тестов: без задержек между попытками
+// и с редкими фоновыми проверками, чтобы они не мешали тестам.
+func testOptions() Options {
+	return Options{
+		Timeout: time.Second,
+		Retry:   RetryOptions{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+		Balancer: BalancerOptions{
+			Strategy:          RoundRobin,
+			HealthInterval:    time.Hour,
+			DiscoveryInterval: time.Hour,
+		},
+	}
+}
+
+func newTestClient(t *testing.T, opts Options, urls ...string) *BaseClient {
+	t.Helper()
+	c := NewBaseClient(StaticDiscovery(urls), opts)
+	t.Cleanup(c.Close)
+	return c
+}
+
+// refusing отвечает ошибкой соединения на первые refuse запросов, как
+// инстанс, который ещё не принимает подключения.
+type refusing struct {
+	next   http.RoundTripper
+	refuse int32
+	calls  atomic.Int32
+}
+
+func (r *refusing) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.calls.Add(1) <= r.refuse {
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	}
+	return r.next.RoundTrip(req)
+}
+
+func TestDoRequestRetriesRefusedConnectionOnSingleInstance(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != healthCheckPath {
+			hits.Add(1)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	c := newTestClient(t, testOptions(), srv.URL)
+	transport := &refusing{next: c.http.Transport(), refuse: 1}
+	c.http.SetTransport(transport)
+
+	_, status, _, err := c.DoRequest(context.Background(), http.MethodGet, "/api/movies/1", nil, nil)
+	if err != nil {
+		t.Fatalf("DoRequest: %v", err)
+	}
+	if status != http.StatusOK {
+		t.Errorf("status = %d, want %d", status, http.StatusOK)
+	}
+	if got := transport.calls.Load(); got != 2 {
+		t.Errorf("attempts = %d, want 2", got)
+	}
+	if got := hits.Load(); got != 1 {
+		t.Errorf("server hits = %d, want 1", got)
+	}
+}
+
+func TestDoRequestRefusedConnectionKeepsAttemptBudget(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	t.Cleanup(srv.Close)
+
+	c := newTestClient(t, testOptions(), srv.URL)
+	transport := &refusing{next: c.http.Transport(), refuse: 100}
+	c.http.SetTransport(transport)
+
+	_, _, _, err := c.DoRequest(context.Background(), http.MethodGet, "/api/movies/1", nil, nil)
+	if !IsConnectionError(err) {
+		t.Fatalf("err = %v, want a connection error", err)
+	}
+	if got := transport.calls.Load(); got != 3 {
+		t.Errorf("attempts = %d, want 3", got)
+	}
+}
+
+func TestDoRequestRefusedConnectionMovesToNextInstance(t *testing.T) {
+	var hits [2]atomic.Int32
+	var urls []string
+	for i := range hits {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != healthCheckPath {
+				hits[i].Add(1)
+			}
+		}))
+		t.Cleanup(srv.Close)
+		urls = append(urls, srv.URL)
+	}
+
+	c := newTestClient(t, testOptions(), urls...)
+	transport := &refusing{next: c.http.Transport(), refuse: 1}
+	c.http.SetTransport(transport)
+
+	// POST не повторяется по задержке, но отказ в соединении безопасно
+	// переносится на другой инстанс.
+	_, status, _, err := c.DoRequest(context.Background(), http.MethodPost, "/api/movies", []byte(`{}`), nil)
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("DoRequest = %d, %v", status, err)
+	}
+	if hits[0].Load()+hits[1].Load() != 1 {
+		t.Errorf("server hits = %d, %d, want one in total", hits[0].Load(), hits[1].Load())
+	}
+}
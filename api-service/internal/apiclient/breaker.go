@@ -0,0 +1,137 @@
+package apiclient
+
+import (
+	"reviews-movies/api-service/internal/metrics"
+	"sync"
+	"time"
+)
+
+type breakerState int
+
+const (
+	stateClosed breakerState = iota
+	stateHalfOpen
+	stateOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case stateClosed:
+		return "closed"
+	case stateHalfOpen:
+		return "half-open"
+	default:
+		return "open"
+	}
+}
+
+type outcome int
+
+const (
+	outcomeSuccess outcome = iota
+	outcomeFailure
+	outcomeIgnored
+)
+
+// breaker — автомат closed -> open -> half-open для одного эндпоинта.
+// В open все запросы отклоняются сразу, после OpenTimeout пропускается
+// ограниченное число пробных запросов.
+type breaker struct {
+	name string
+	opts BreakerOptions
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probes   int
+}
+
+func (b *breaker) allow() bool {
+	if b.opts.FailureThreshold <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case stateOpen:
+		if time.Since(b.openedAt) < b.opts.OpenTimeout {
+			return false
+		}
+		b.setState(stateHalfOpen)
+		b.probes = 0
+		fallthrough
+	case stateHalfOpen:
+		if b.probes >= b.opts.HalfOpenMaxRequests {
+			return false
+		}
+		b.probes++
+	}
+	return true
+}
+
+func (b *breaker) done(o outcome) {
+	if b.opts.FailureThreshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case stateHalfOpen:
+		b.probes--
+		switch o {
+		case outcomeSuccess:
+			b.failures = 0
+			b.setState(stateClosed)
+		case outcomeFailure:
+			b.trip()
+		}
+	case stateClosed:
+		switch o {
+		case outcomeSuccess:
+			b.failures = 0
+		case outcomeFailure:
+			b.failures++
+			if b.failures >= b.opts.FailureThreshold {
+				b.trip()
+			}
+		}
+	}
+}
+
+func (b *breaker) trip() {
+	b.openedAt = time.Now()
+	b.setState(stateOpen)
+}
+
+func (b *breaker) setState(s breakerState) {
+	b.state = s
+	metrics.UpstreamBreakerState.WithLabelValues(b.name).Set(float64(s))
+}
+
+type breakers struct {
+	opts BreakerOptions
+
+	mu    sync.Mutex
+	byKey map[string]*breaker
+}
+
+func newBreakers(opts BreakerOptions) *breakers {
+	return &breakers{opts: opts, byKey: make(map[string]*breaker)}
+}
+
+func (bs *breakers) get(name string) *breaker {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	b, ok := bs.byKey[name]
+	if !ok {
+		b = &breaker{name: name, opts: bs.opts}
+		bs.byKey[name] = b
+	}
+	return b
+}
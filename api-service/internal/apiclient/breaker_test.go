@@ -0,0 +1,109 @@
+package apiclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBreakerStates(t *testing.T) {
+	b := &breaker{name: "test", opts: BreakerOptions{FailureThreshold: 3, OpenTimeout: time.Minute, HalfOpenMaxRequests: 2}}
+
+	// closed: успех сбрасывает счётчик, отменённые запросы не считаются.
+	for _, o := range []outcome{outcomeFailure, outcomeFailure, outcomeSuccess, outcomeFailure, outcomeIgnored, outcomeFailure} {
+		if !b.allow() {
+			t.Fatal("closed breaker rejected a request")
+		}
+		b.done(o)
+	}
+	if b.state != stateClosed {
+		t.Fatalf("state = %s, want closed", b.state)
+	}
+
+	// Третья ошибка подряд открывает breaker.
+	b.allow()
+	b.done(outcomeFailure)
+	if b.state != stateOpen {
+		t.Fatalf("state = %s, want open", b.state)
+	}
+	if b.allow() {
+		t.Fatal("open breaker allowed a request before OpenTimeout")
+	}
+
+	// После OpenTimeout пропускается HalfOpenMaxRequests проб.
+	b.openedAt = time.Now().Add(-time.Minute)
+	if !b.allow() || !b.allow() {
+		t.Fatal("half-open breaker rejected a probe")
+	}
+	if b.state != stateHalfOpen {
+		t.Fatalf("state = %s, want half-open", b.state)
+	}
+	if b.allow() {
+		t.Fatal("half-open breaker allowed more than HalfOpenMaxRequests probes")
+	}
+
+	// Неудачная проба снова открывает breaker.
+	b.done(outcomeFailure)
+	if b.state != stateOpen || b.allow() {
+		t.Fatalf("state = %s after a failed probe, want open", b.state)
+	}
+
+	// Удачная проба закрывает его.
+	b.openedAt = time.Now().Add(-time.Minute)
+	if !b.allow() {
+		t.Fatal("half-open breaker rejected a probe")
+	}
+	b.done(outcomeSuccess)
+	if b.state != stateClosed || b.failures != 0 {
+		t.Fatalf("state = %s, failures = %d after a successful probe, want closed, 0", b.state, b.failures)
+	}
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	b := &breaker{name: "test"}
+	for range 10 {
+		if !b.allow() {
+			t.Fatal("disabled breaker rejected a request")
+		}
+		b.done(outcomeFailure)
+	}
+}
+
+func TestDoRequestOpensBreaker(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == healthCheckPath {
+			return
+		}
+		hits.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(srv.Close)
+
+	opts := testOptions()
+	opts.Retry.MaxAttempts = 1
+	opts.Breaker = BreakerOptions{FailureThreshold: 2, OpenTimeout: time.Minute, HalfOpenMaxRequests: 1}
+	c := newTestClient(t, opts, srv.URL)
+
+	for range 2 {
+		if _, status, _, err := c.DoRequest(context.Background(), http.MethodGet, "/api/movies/1", nil, nil); err != nil || status != http.StatusInternalServerError {
+			t.Fatalf("DoRequest = %d, %v", status, err)
+		}
+	}
+	_, _, _, err := c.DoRequest(context.Background(), http.MethodGet, "/api/movies/1", nil, nil)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v, want ErrCircuitOpen", err)
+	}
+	if got := hits.Load(); got != 2 {
+		t.Errorf("server hits = %d, want 2", got)
+	}
+
+	// Breaker свой для каждого эндпоинта.
+	if _, _, _, err := c.DoRequest(context.Background(), http.MethodGet, "/api/reviews/1", nil, nil); err != nil {
+		t.Errorf("other endpoint: %v", err)
+	}
+}
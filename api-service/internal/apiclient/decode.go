@@ -0,0 +1,70 @@
+package apiclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+)
+
+// Metadata — сведения о пагинации в списочных ответах data-service.
+type Metadata struct {
+	CurrentPage  int `json:"current_page,omitempty"`
+	PageSize     int `json:"page_size,omitempty"`
+	FirstPage    int `json:"first_page,omitempty"`
+	LastPage     int `json:"last_page,omitempty"`
+	TotalRecords int `json:"total_record,omitempty"`
+	// TotalEstimated — TotalRecords взят из оценки планировщика.
+	TotalEstimated bool   `json:"total_estimated,omitempty"`
+	NextCursor     string `json:"next_cursor,omitempty"`
+	PrevCursor     string `json:"prev_cursor,omitempty"`
+}
+
+// Do выполняет запрос с JSON-телом in и разбирает ответ в out. Неуспешный
+// ответ возвращается как *StatusError.
+func (c *BaseClient) Do(ctx context.Context, method, path string, headers map[string][]string, in, out any) error {
+	var payload []byte
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("encode request: %w", err)
+		}
+		payload = b
+		headers = maps.Clone(headers)
+		if headers == nil {
+			headers = make(map[string][]string, 1)
+		}
+		headers["Content-Type"] = []string{"application/json"}
+	}
+
+	body, status, contentType, err := c.DoRequest(ctx, method, path, payload, headers)
+	if err != nil {
+		return err
+	}
+	return Decode(body, status, contentType, out)
+}
+
+// Decode разбирает ответ, полученный через DoRequest или RawClient.
+// Пригодится, когда тело нужно и в сыром виде, и в виде структуры.
+func Decode(body []byte, status int, contentType string, out any) error {
+	if status < http.StatusOK || status >= http.StatusMultipleChoices {
+		return newStatusError(status, contentType, body)
+	}
+	if out == nil || len(body) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidResponse, err)
+	}
+	return nil
+}
+
+// Precondition возвращает заголовки для условного запроса с If-Match.
+// Пустой etag даёт безусловный запрос.
+func Precondition(etag string) map[string][]string {
+	if etag == "" {
+		return nil
+	}
+	return map[string][]string{"If-Match": {etag}}
+}
@@ -0,0 +1,56 @@
+package apiclient
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+)
+
+// Discovery возвращает текущий список базовых URL инстансов data-service.
+type Discovery interface {
+	Instances(ctx context.Context) ([]string, error)
+}
+
+type StaticDiscovery []string
+
+func (d StaticDiscovery) Instances(context.Context) ([]string, error) {
+	return d, nil
+}
+
+// DNSDiscovery резолвит хост из шаблона (например http://data:8081)
+// во все его A/AAAA записи, сохраняя схему и порт.
+type DNSDiscovery struct {
+	Template string
+	Resolver *net.Resolver
+}
+
+func (d DNSDiscovery) Instances(ctx context.Context) ([]string, error) {
+	u, err := url.Parse(d.Template)
+	if err != nil {
+		return nil, fmt.Errorf("parse discovery template: %w", err)
+	}
+
+	resolver := d.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	addrs, err := resolver.LookupHost(ctx, u.Hostname())
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s: %w", u.Hostname(), err)
+	}
+	sort.Strings(addrs)
+
+	instances := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		host := addr
+		if port := u.Port(); port != "" {
+			host = net.JoinHostPort(addr, port)
+		} else if net.ParseIP(addr).To4() == nil {
+			host = "[" + addr + "]"
+		}
+		instances = append(instances, u.Scheme+"://"+host)
+	}
+	return instances, nil
+}
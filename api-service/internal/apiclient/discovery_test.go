@@ -0,0 +1,166 @@
+package apiclient
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDNS — UDP-сервер, который на любой A-запрос отвечает текущим
+// списком адресов, а на остальные — пустым ответом.
+type fakeDNS struct {
+	conn net.PacketConn
+
+	mu    sync.Mutex
+	addrs []net.IP
+}
+
+func newFakeDNS(t *testing.T) *fakeDNS {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	d := &fakeDNS{conn: conn}
+	go d.serve()
+	return d
+}
+
+func (d *fakeDNS) set(addrs ...string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.addrs = d.addrs[:0]
+	for _, a := range addrs {
+		d.addrs = append(d.addrs, net.ParseIP(a).To4())
+	}
+}
+
+func (d *fakeDNS) serve() {
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := d.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if resp := d.answer(buf[:n]); resp != nil {
+			d.conn.WriteTo(resp, addr)
+		}
+	}
+}
+
+// answer собирает ответ: заголовок и вопрос из запроса, затем A-записи
+// со ссылкой на имя из вопроса.
+func (d *fakeDNS) answer(query []byte) []byte {
+	if len(query) < 12 {
+		return nil
+	}
+	end := 12
+	for end < len(query) && query[end] != 0 {
+		end += int(query[end]) + 1
+	}
+	end += 5 // нулевая метка, тип и класс
+	if end > len(query) {
+		return nil
+	}
+	qtype := binary.BigEndian.Uint16(query[end-4:])
+
+	d.mu.Lock()
+	var addrs []net.IP
+	if qtype == 1 {
+		addrs = slices.Clone(d.addrs)
+	}
+	d.mu.Unlock()
+
+	resp := slices.Clone(query[:end])
+	binary.BigEndian.PutUint16(resp[2:], 0x8180) // ответ, рекурсия доступна
+	binary.BigEndian.PutUint16(resp[6:], uint16(len(addrs)))
+	binary.BigEndian.PutUint16(resp[8:], 0)
+	binary.BigEndian.PutUint16(resp[10:], 0)
+	for _, ip := range addrs {
+		resp = append(resp, 0xc0, 12, 0, 1, 0, 1, 0, 0, 0, 1, 0, 4)
+		resp = append(resp, ip...)
+	}
+	return resp
+}
+
+func (d *fakeDNS) resolver() *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "udp", d.conn.LocalAddr().String())
+		},
+	}
+}
+
+func TestDNSDiscoveryInstances(t *testing.T) {
+	dns := newFakeDNS(t)
+	dns.set("10.0.0.2", "10.0.0.1")
+
+	got, err := DNSDiscovery{Template: "http://data.test:8081", Resolver: dns.resolver()}.Instances(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"http://10.0.0.1:8081", "http://10.0.0.2:8081"}; !slices.Equal(got, want) {
+		t.Errorf("instances = %v, want %v", got, want)
+	}
+}
+
+func TestDNSDiscoveryLiterals(t *testing.T) {
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{template: "http://127.0.0.1:8081", want: "http://127.0.0.1:8081"},
+		{template: "https://[::1]:8443", want: "https://[::1]:8443"},
+		{template: "http://[::1]", want: "http://[::1]"},
+		{template: "http://127.0.0.1", want: "http://127.0.0.1"},
+	}
+	for _, tt := range tests {
+		got, err := DNSDiscovery{Template: tt.template}.Instances(context.Background())
+		if err != nil {
+			t.Errorf("%s: %v", tt.template, err)
+			continue
+		}
+		if !slices.Equal(got, []string{tt.want}) {
+			t.Errorf("%s: instances = %v, want %s", tt.template, got, tt.want)
+		}
+	}
+
+	if _, err := (DNSDiscovery{Template: "://bad"}).Instances(context.Background()); err == nil {
+		t.Error("bad template: no error")
+	}
+}
+
+func TestPoolFollowsDNSChanges(t *testing.T) {
+	dns := newFakeDNS(t)
+	dns.set("10.0.0.1")
+
+	p := &pool{discovery: DNSDiscovery{Template: "http://data.test:8081", Resolver: dns.resolver()}}
+	p.refresh(context.Background())
+	first := p.snapshot()
+	if len(first) != 1 || first[0].url != "http://10.0.0.1:8081" {
+		t.Fatalf("instances = %v", first)
+	}
+
+	dns.set("10.0.0.1", "10.0.0.3")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	p.refresh(ctx)
+
+	var urls []string
+	for _, inst := range p.snapshot() {
+		urls = append(urls, inst.url)
+	}
+	if want := []string{"http://10.0.0.1:8081", "http://10.0.0.3:8081"}; !slices.Equal(urls, want) {
+		t.Errorf("instances after re-resolution = %v, want %v", urls, want)
+	}
+	if p.snapshot()[0] != first[0] {
+		t.Error("re-resolution replaced a known instance")
+	}
+}
@@ -0,0 +1,92 @@
+package apiclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"reviews-movies/api-service/internal/problem"
+	"syscall"
+)
+
+var (
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+	ErrNoInstances = errors.New("no data-service instances available")
+)
+
+// IsConnectionError сообщает, что соединение с инстансом не было
+// установлено, то есть запрос до сервера не дошёл.
+func IsConnectionError(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func IsUnavailable(err error) bool {
+	return errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrNoInstances)
+}
+
+// Ошибки, которыми типизированные клиенты сообщают о неуспешных ответах
+// data-service. Проверяются через errors.Is на *StatusError.
+var (
+	ErrNotFound        = errors.New("resource not found")
+	ErrGone            = errors.New("resource is deleted")
+	ErrConflict        = errors.New("edit conflict")
+	ErrValidation      = errors.New("validation failed")
+	ErrPrecondition    = errors.New("precondition failed")
+	ErrInvalidResponse = errors.New("invalid data-service response")
+)
+
+// StatusError — ответ data-service вне диапазона 2xx. Problem заполнен,
+// если тело пришло в формате application/problem+json.
+type StatusError struct {
+	Status  int
+	Problem *problem.Problem
+}
+
+func newStatusError(status int, contentType string, body []byte) *StatusError {
+	e := &StatusError{Status: status}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && mediaType == problem.ContentType {
+		var p problem.Problem
+		if json.Unmarshal(body, &p) == nil {
+			e.Problem = &p
+		}
+	}
+	return e
+}
+
+func (e *StatusError) Error() string {
+	if e.Problem != nil {
+		return fmt.Sprintf("data-service responded %d: %s", e.Status, e.Problem.Error())
+	}
+	return fmt.Sprintf("data-service responded %d", e.Status)
+}
+
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.Status == http.StatusNotFound
+	case ErrGone:
+		return e.Status == http.StatusGone
+	case ErrConflict:
+		return e.Status == http.StatusConflict
+	case ErrValidation:
+		return e.Status == http.StatusUnprocessableEntity
+	case ErrPrecondition:
+		return e.Status == http.StatusPreconditionFailed || e.Status == http.StatusPreconditionRequired
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package apiclient
+
+import (
+	"encoding/json"
+	"net/url"
+)
+
+// Fieldset — параметры fields и include. Шлюз передаёт их в data-service
+// как есть, а ответ разбирает в Sparse, чтобы не дописывать в него нулевые
+// значения невыбранных полей.
+type Fieldset struct {
+	Fields  string
+	Include string
+}
+
+// Sparse сообщает, что ответ будет в выборочном представлении.
+func (f Fieldset) Sparse() bool {
+	return f.Fields != "" || f.Include != ""
+}
+
+func (f Fieldset) Apply(query url.Values) {
+	if f.Fields != "" {
+		query.Set("fields", f.Fields)
+	}
+	if f.Include != "" {
+		query.Set("include", f.Include)
+	}
+}
+
+// Sparse — запись в выборочном представлении: только запрошенные поля и
+// встроенные связи. id и version data-service отдаёт всегда.
+type Sparse map[string]json.RawMessage
+
+func (s Sparse) Version() int32 {
+	var version int32
+	_ = json.Unmarshal(s["version"], &version)
+	return version
+}
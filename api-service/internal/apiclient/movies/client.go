@@ -6,37 +6,298 @@ import (
 	"net/url"
 	"resty.dev/v3"
 	"reviews-movies/api-service/internal/apiclient"
+	"reviews-movies/api-service/internal/cache"
+	"strconv"
+	"time"
 )
 
+// Client разбирает ответы data-service в структуры. Неуспешные ответы
+// возвращаются как *apiclient.StatusError и проверяются через
+// apiclient.ErrNotFound, apiclient.ErrGone, apiclient.ErrConflict,
+// apiclient.ErrValidation и apiclient.ErrPrecondition.
+//
+// Успешные GET-ответы кэшируются в cache; nil выключает кэш. Держатель
+// клиента обязан сообщать об изменениях через Invalidate* после ответа
+// data-service, а об отправленных в Kafka — через Pending*. pending — сколько
+// после Pending* затронутые ответы не кэшируются, пока консьюмер пишет
+// изменение в базу.
 type Client struct {
-	base *apiclient.BaseClient
+	base    *apiclient.BaseClient
+	cache   *cache.Cache
+	pending time.Duration
 }
 
-func NewMovieClient(base *apiclient.BaseClient) *Client {
-	return &Client{base: base}
+func NewMovieClient(base *apiclient.BaseClient, responses *cache.Cache, pending time.Duration) *Client {
+	return &Client{base: base, cache: responses, pending: pending}
 }
 
-func (c *Client) GetByID(ctx context.Context, id uint64) (body []byte, status int, contentType string, err error) {
+func (c *Client) GetByID(ctx context.Context, id uint64) (*Movie, error) {
 	path := fmt.Sprintf("/api/movies/%d", id)
-	return c.base.DoRequest(ctx, resty.MethodGet, path, nil, nil)
+	return cache.Fetch(ctx, c.cache, path, []string{movieTag(id)}, func(ctx context.Context) (*Movie, error) {
+		var out struct {
+			Movie Movie `json:"movie"`
+		}
+		if err := c.base.Do(ctx, resty.MethodGet, path, nil, nil, &out); err != nil {
+			return nil, err
+		}
+		return &out.Movie, nil
+	})
+}
+
+// GetSparseByID читает фильм с fields и include. Встроенные отзывы и
+// статистика зависят от рецензий, поэтому такая запись кэшируется и под
+// тегами статистики.
+func (c *Client) GetSparseByID(ctx context.Context, id uint64, fs apiclient.Fieldset) (apiclient.Sparse, error) {
+	query := url.Values{}
+	fs.Apply(query)
+	path := fmt.Sprintf("/api/movies/%d?%s", id, query.Encode())
+
+	tags := []string{movieTag(id)}
+	if fs.Include != "" {
+		tags = append(tags, statsTag, movieStatsTag(id))
+	}
+	return cache.Fetch(ctx, c.cache, path, tags, func(ctx context.Context) (apiclient.Sparse, error) {
+		var out struct {
+			Movie apiclient.Sparse `json:"movie"`
+		}
+		if err := c.base.Do(ctx, resty.MethodGet, path, nil, nil, &out); err != nil {
+			return nil, err
+		}
+		return out.Movie, nil
+	})
 }
 
-func (c *Client) GetByCorrelationID(ctx context.Context, corrID string) (body []byte, status int, contentType string, err error) {
+func (c *Client) GetByCorrelationID(ctx context.Context, corrID string) (*Movie, error) {
+	var out struct {
+		Movie Movie `json:"movie"`
+	}
 	path := fmt.Sprintf("/api/movies/by-correlation/%s", corrID)
-	return c.base.DoRequest(ctx, resty.MethodGet, path, nil, nil)
+	if err := c.base.Do(ctx, resty.MethodGet, path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out.Movie, nil
+}
+
+func (c *Client) GetStats(ctx context.Context, id uint64) (*MovieStats, error) {
+	path := fmt.Sprintf("/api/movies/%d/stats", id)
+	return cache.Fetch(ctx, c.cache, path, []string{statsTag, movieStatsTag(id)}, func(ctx context.Context) (*MovieStats, error) {
+		var out struct {
+			Stats MovieStats `json:"stats"`
+		}
+		if err := c.base.Do(ctx, resty.MethodGet, path, nil, nil, &out); err != nil {
+			return nil, err
+		}
+		return &out.Stats, nil
+	})
 }
 
-func (c *Client) UpdateMovie(ctx context.Context, id uint64, payload []byte, headers map[string][]string) (body []byte, status int, contentType string, err error) {
+// UpdateMovie применяет изменения, только если версия записи совпадает с
+// ifMatch — ETag, полученным клиентом шлюза.
+func (c *Client) UpdateMovie(ctx context.Context, id uint64, input MovieUpdate, ifMatch string) (*Movie, error) {
+	var out struct {
+		Movie Movie `json:"movie"`
+	}
 	path := fmt.Sprintf("/api/movies/%d", id)
-	return c.base.DoRequest(ctx, resty.MethodPatch, path, payload, headers)
+	if err := c.base.Do(ctx, resty.MethodPatch, path, apiclient.Precondition(ifMatch), input, &out); err != nil {
+		return nil, err
+	}
+	return &out.Movie, nil
+}
+
+// RestoreMovie возвращает фильм из корзины вместе с отзывами, удалёнными
+// вместе с ним.
+func (c *Client) RestoreMovie(ctx context.Context, id uint64) (*Movie, error) {
+	var out struct {
+		Movie Movie `json:"movie"`
+	}
+	path := fmt.Sprintf("/api/movies/%d/restore", id)
+	if err := c.base.Do(ctx, resty.MethodPost, path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out.Movie, nil
 }
 
-func (c *Client) DeleteMovie(ctx context.Context, id uint64) (body []byte, status int, contentType string, err error) {
+func (c *Client) DeleteMovie(ctx context.Context, id uint64, ifMatch string) error {
 	path := fmt.Sprintf("/api/movies/%d", id)
-	return c.base.DoRequest(ctx, resty.MethodDelete, path, nil, nil)
+	return c.base.Do(ctx, resty.MethodDelete, path, apiclient.Precondition(ifMatch), nil, nil)
+}
+
+func (c *Client) ListMovies(ctx context.Context, params ListParams) (*MovieList, error) {
+	path := "/api/movies?" + listParamsQuery(params).Encode()
+	return cache.Fetch(ctx, c.cache, path, []string{listTag}, func(ctx context.Context) (*MovieList, error) {
+		var out MovieList
+		if err := c.base.Do(ctx, resty.MethodGet, path, nil, nil, &out); err != nil {
+			return nil, err
+		}
+		return &out, nil
+	})
 }
 
-func (c *Client) GetListMovie(ctx context.Context, title, sort, page, pageSize string, genres []string) (body []byte, status int, contentType string, err error) {
+// ListMoviesSparse читает список с fields и include. Со встроенными
+// связями список зависит от рецензий так же, как агрегаты по оценкам.
+func (c *Client) ListMoviesSparse(ctx context.Context, params ListParams, fs apiclient.Fieldset) (*SparseMovieList, error) {
+	query := listParamsQuery(params)
+	fs.Apply(query)
+	path := "/api/movies?" + query.Encode()
+
+	tags := []string{listTag}
+	if fs.Include != "" {
+		tags = append(tags, ratingsTag)
+	}
+	return cache.Fetch(ctx, c.cache, path, tags, func(ctx context.Context) (*SparseMovieList, error) {
+		var out SparseMovieList
+		if err := c.base.Do(ctx, resty.MethodGet, path, nil, nil, &out); err != nil {
+			return nil, err
+		}
+		return &out, nil
+	})
+}
+
+func listParamsQuery(params ListParams) url.Values {
+	query := listQuery(params.Title, params.Sort, formatInt(params.Page), formatInt(params.PageSize), params.Genres)
+	for key, value := range map[string]string{
+		"filter": params.Filter,
+		"after":  params.After,
+		"before": params.Before,
+		"count":  params.Count,
+	} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	return query
+}
+
+// Suggest дополняет начало названия и подбирает похожие названия.
+// limit == 0 оставляет значение по умолчанию data-service.
+func (c *Client) Suggest(ctx context.Context, prefix string, limit int) (*MovieSuggestions, error) {
+	query := url.Values{}
+	query.Set("prefix", prefix)
+	if limit != 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	path := "/api/movies/suggest?" + query.Encode()
+	return cache.Fetch(ctx, c.cache, path, []string{listTag}, func(ctx context.Context) (*MovieSuggestions, error) {
+		var out MovieSuggestions
+		if err := c.base.Do(ctx, resty.MethodGet, path, nil, nil, &out); err != nil {
+			return nil, err
+		}
+		return &out, nil
+	})
+}
+
+func (c *Client) GetTopRatedMovies(ctx context.Context) ([]MovieRating, error) {
+	path := "/api/movies/top"
+	return cache.Fetch(ctx, c.cache, path, []string{ratingsTag}, func(ctx context.Context) ([]MovieRating, error) {
+		var out struct {
+			TopMovies []MovieRating `json:"top_movies"`
+		}
+		if err := c.base.Do(ctx, resty.MethodGet, path, nil, nil, &out); err != nil {
+			return nil, err
+		}
+		return out.TopMovies, nil
+	})
+}
+
+func (c *Client) GetWithoutReviews(ctx context.Context) ([]MovieRating, error) {
+	path := "/api/movies/without-reviews"
+	return cache.Fetch(ctx, c.cache, path, []string{ratingsTag}, func(ctx context.Context) ([]MovieRating, error) {
+		var out struct {
+			Movies []MovieRating `json:"movies_without_reviews"`
+		}
+		if err := c.base.Do(ctx, resty.MethodGet, path, nil, nil, &out); err != nil {
+			return nil, err
+		}
+		return out.Movies, nil
+	})
+}
+
+func (c *Client) GetControversialMovies(ctx context.Context) ([]MovieVariance, error) {
+	path := "/api/movies/variance"
+	return cache.Fetch(ctx, c.cache, path, []string{ratingsTag}, func(ctx context.Context) ([]MovieVariance, error) {
+		var out struct {
+			Movies []MovieVariance `json:"movies_variance"`
+		}
+		if err := c.base.Do(ctx, resty.MethodGet, path, nil, nil, &out); err != nil {
+			return nil, err
+		}
+		return out.Movies, nil
+	})
+}
+
+func (c *Client) GetAvgRatingByGenre(ctx context.Context) ([]GenreRating, error) {
+	path := "/api/movies/avg-rating"
+	return cache.Fetch(ctx, c.cache, path, []string{ratingsTag}, func(ctx context.Context) ([]GenreRating, error) {
+		var out struct {
+			RatingAverage []GenreRating `json:"rating_average"`
+		}
+		if err := c.base.Do(ctx, resty.MethodGet, path, nil, nil, &out); err != nil {
+			return nil, err
+		}
+		return out.RatingAverage, nil
+	})
+}
+
+// Теги записей кэша. Списки фильмов зависят от самих фильмов, агрегаты по
+// оценкам — и от фильмов, и от рецензий.
+const (
+	listTag    = "movies"
+	ratingsTag = "ratings"
+	statsTag   = "stats"
+)
+
+func movieTag(id uint64) string {
+	return "movie:" + strconv.FormatUint(id, 10)
+}
+
+func movieStatsTag(id uint64) string {
+	return statsTag + ":" + strconv.FormatUint(id, 10)
+}
+
+// InvalidateMovie сбрасывает всё, что относится к фильму id: сам фильм,
+// его статистику, списки и агрегаты.
+func (c *Client) InvalidateMovie(id uint64) {
+	c.cache.Invalidate(movieTag(id), movieStatsTag(id), listTag, ratingsTag)
+}
+
+// InvalidateReviews сбрасывает статистику фильма movieID и агрегаты по
+// оценкам. Если фильм неизвестен (movieID == 0), сбрасывается статистика
+// всех фильмов.
+func (c *Client) InvalidateReviews(movieID uint64) {
+	c.cache.Invalidate(reviewsTags(movieID)...)
+}
+
+// PendingMovies сбрасывает ответы, на которые влияет появление нового
+// фильма, отправленного в Kafka, и не кэширует их, пока консьюмер пишет
+// фильм в базу.
+func (c *Client) PendingMovies() {
+	c.cache.Hold(c.pending, moviesTags()...)
+}
+
+// PendingReviews — InvalidateReviews для отзывов, отправленных в Kafka.
+func (c *Client) PendingReviews(movieID uint64) {
+	c.cache.Hold(c.pending, reviewsTags(movieID)...)
+}
+
+func moviesTags() []string {
+	return []string{listTag, ratingsTag}
+}
+
+func reviewsTags(movieID uint64) []string {
+	if movieID == 0 {
+		return []string{statsTag, ratingsTag}
+	}
+	return []string{movieStatsTag(movieID), ratingsTag}
+}
+
+func formatInt(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+func listQuery(title, sort, page, pageSize string, genres []string) url.Values {
 	query := url.Values{}
 	if title != "" {
 		query.Set("title", title)
@@ -55,27 +316,5 @@ func (c *Client) GetListMovie(ctx context.Context, title, sort, page, pageSize s
 			query.Add("genres", genre)
 		}
 	}
-	path := "/api/movies" + "?" + query.Encode()
-
-	return c.base.DoRequest(ctx, resty.MethodGet, path, nil, nil)
-}
-
-func (c *Client) GetTopRatedMovies(ctx context.Context) (body []byte, status int, contentType string, err error) {
-	path := "/api/movies/top"
-	return c.base.DoRequest(ctx, resty.MethodGet, path, nil, nil)
-}
-
-func (c *Client) GetWithoutReviews(ctx context.Context) (body []byte, status int, contentType string, err error) {
-	path := "/api/movies/without-reviews"
-	return c.base.DoRequest(ctx, resty.MethodGet, path, nil, nil)
-}
-
-func (c *Client) GetControversialMovies(ctx context.Context) (body []byte, status int, contentType string, err error) {
-	path := "/api/movies/variance"
-	return c.base.DoRequest(ctx, resty.MethodGet, path, nil, nil)
-}
-
-func (c *Client) GetAvgRatingByGenre(ctx context.Context) (body []byte, status int, contentType string, err error) {
-	path := "/api/movies/avg-rating"
-	return c.base.DoRequest(ctx, resty.MethodGet, path, nil, nil)
+	return query
 }
@@ -0,0 +1,100 @@
+package movies
+
+import (
+	"github.com/google/uuid"
+	"reviews-movies/api-service/internal/apiclient"
+	"time"
+)
+
+// Movie повторяет JSON-представление фильма в data-service, включая поля
+// gorm.Model без json-тегов.
+type Movie struct {
+	ID            uint       `json:"ID"`
+	CreatedAt     time.Time  `json:"CreatedAt"`
+	UpdatedAt     time.Time  `json:"UpdatedAt"`
+	DeletedAt     *time.Time `json:"DeletedAt"`
+	CorrelationId uuid.UUID  `json:"correlation_id"`
+	Title         string     `json:"title"`
+	Year          int32      `json:"year,omitempty"`
+	Runtime       int32      `json:"runtime,omitempty"`
+	Genres        []string   `json:"genres"`
+	Version       int32      `json:"version"`
+}
+
+type MovieUpdate struct {
+	Title   *string  `json:"title,omitempty"`
+	Year    *int32   `json:"year,omitempty"`
+	Runtime *int32   `json:"runtime,omitempty"`
+	Genres  []string `json:"genres,omitempty"`
+}
+
+// ListParams — параметры списка фильмов. Нулевые значения не передаются,
+// и data-service подставляет свои значения по умолчанию.
+type ListParams struct {
+	Title  string
+	Genres []string
+	// Filter — выражение фильтра data-service, например
+	// "year>=1990 and genres in (Drama, Crime)".
+	Filter   string
+	Sort     string
+	Page     int
+	PageSize int
+	// After и Before — курсоры из metadata предыдущего ответа, Count —
+	// exact, estimated или none.
+	After  string
+	Before string
+	Count  string
+}
+
+type MovieList struct {
+	Movies   []Movie            `json:"movies"`
+	Metadata apiclient.Metadata `json:"metadata"`
+}
+
+// SparseMovieList — список фильмов, запрошенный с fields или include.
+type SparseMovieList struct {
+	Movies   []apiclient.Sparse `json:"movies"`
+	Metadata apiclient.Metadata `json:"metadata"`
+}
+
+type MovieRating struct {
+	ID        uint    `json:"id"`
+	Title     string  `json:"title"`
+	Year      int32   `json:"year"`
+	Runtime   int32   `json:"runtime"`
+	AvgRating float64 `json:"avg_rating"`
+}
+
+type MovieVariance struct {
+	ID       uint    `json:"id"`
+	Title    string  `json:"title"`
+	Year     int32   `json:"year"`
+	Runtime  int32   `json:"runtime"`
+	Variance float64 `json:"variance"`
+}
+
+type GenreRating struct {
+	Genre     string  `json:"genre"`
+	AvgRating float64 `json:"avg_rating"`
+}
+
+type MovieStats struct {
+	MovieID     uint           `json:"movie_id"`
+	ReviewCount int            `json:"review_count"`
+	AvgRating   float64        `json:"avg_rating"`
+	Histogram   map[string]int `json:"histogram"`
+}
+
+type MovieSuggestion struct {
+	ID         uint    `json:"id"`
+	Title      string  `json:"title"`
+	Year       int32   `json:"year"`
+	Similarity float64 `json:"similarity,omitempty"`
+}
+
+// MovieSuggestions — автодополнение названия (Completions) и похожие
+// названия для запроса с опечаткой (DidYouMean).
+type MovieSuggestions struct {
+	Completions []MovieSuggestion `json:"completions"`
+	DidYouMean  []MovieSuggestion `json:"did_you_mean"`
+}
@@ -0,0 +1,65 @@
+package movies
+
+import (
+	"context"
+	"fmt"
+	"resty.dev/v3"
+	"reviews-movies/api-service/internal/apiclient"
+)
+
+// RawClient отдаёт ответы data-service как есть: тело, статус и
+// Content-Type. Нужен там, где ответ проксируется без разбора; такой ответ
+// не кэшируется и не проверяется. Разобрать его можно через apiclient.Decode.
+type RawClient struct {
+	base *apiclient.BaseClient
+}
+
+func NewRawMovieClient(base *apiclient.BaseClient) *RawClient {
+	return &RawClient{base: base}
+}
+
+func (c *RawClient) GetByID(ctx context.Context, id uint64) (body []byte, status int, contentType string, err error) {
+	path := fmt.Sprintf("/api/movies/%d", id)
+	return c.base.DoRequest(ctx, resty.MethodGet, path, nil, nil)
+}
+
+func (c *RawClient) GetByCorrelationID(ctx context.Context, corrID string) (body []byte, status int, contentType string, err error) {
+	path := fmt.Sprintf("/api/movies/by-correlation/%s", corrID)
+	return c.base.DoRequest(ctx, resty.MethodGet, path, nil, nil)
+}
+
+func (c *RawClient) UpdateMovie(ctx context.Context, id uint64, payload []byte, headers map[string][]string) (body []byte, status int, contentType string, err error) {
+	path := fmt.Sprintf("/api/movies/%d", id)
+	return c.base.DoRequest(ctx, resty.MethodPatch, path, payload, headers)
+}
+
+func (c *RawClient) DeleteMovie(ctx context.Context, id uint64) (body []byte, status int, contentType string, err error) {
+	path := fmt.Sprintf("/api/movies/%d", id)
+	return c.base.DoRequest(ctx, resty.MethodDelete, path, nil, nil)
+}
+
+func (c *RawClient) GetListMovie(ctx context.Context, title, sort, page, pageSize string, genres []string) (body []byte, status int, contentType string, err error) {
+	path := "/api/movies?" + listQuery(title, sort, page, pageSize, genres).Encode()
+
+	return c.base.DoRequest(ctx, resty.MethodGet, path, nil, nil)
+}
+
+func (c *RawClient) GetTopRatedMovies(ctx context.Context) (body []byte, status int, contentType string, err error) {
+	path := "/api/movies/top"
+	return c.base.DoRequest(ctx, resty.MethodGet, path, nil, nil)
+}
+
+func (c *RawClient) GetWithoutReviews(ctx context.Context) (body []byte, status int, contentType string, err error) {
+	path := "/api/movies/without-reviews"
+	return c.base.DoRequest(ctx, resty.MethodGet, path, nil, nil)
+}
+
+func (c *RawClient) GetControversialMovies(ctx context.Context) (body []byte, status int, contentType string, err error) {
+	path := "/api/movies/variance"
+	return c.base.DoRequest(ctx, resty.MethodGet, path, nil, nil)
+}
+
+func (c *RawClient) GetAvgRatingByGenre(ctx context.Context) (body []byte, status int, contentType string, err error) {
+	path := "/api/movies/avg-rating"
+	return c.base.DoRequest(ctx, resty.MethodGet, path, nil, nil)
+}
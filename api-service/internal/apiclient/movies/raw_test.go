@@ -0,0 +1,40 @@
+package movies
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reviews-movies/api-service/internal/apiclient"
+	"testing"
+	"time"
+)
+
+func TestRawClientPassesResponseThrough(t *testing.T) {
+	const problemBody = `{"type":"urn:reviews-movies:problem:not_found","status":404,"code":"not_found"}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/movies/42" {
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(problemBody))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	base := apiclient.NewBaseClient(apiclient.StaticDiscovery{srv.URL}, apiclient.Options{
+		Timeout:  time.Second,
+		Balancer: apiclient.BalancerOptions{HealthInterval: time.Hour, DiscoveryInterval: time.Hour},
+	})
+	t.Cleanup(base.Close)
+
+	body, status, contentType, err := NewRawMovieClient(base).GetByID(context.Background(), 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusNotFound || contentType != "application/problem+json" || string(body) != problemBody {
+		t.Errorf("got %d %q %s", status, contentType, body)
+	}
+	if err := apiclient.Decode(body, status, contentType, nil); !errors.Is(err, apiclient.ErrNotFound) {
+		t.Errorf("Decode = %v, want ErrNotFound", err)
+	}
+}
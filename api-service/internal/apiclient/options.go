@@ -0,0 +1,32 @@
+package apiclient
+
+import "time"
+
+type Options struct {
+	Timeout time.Duration
+	Retry   RetryOptions
+	Breaker BreakerOptions
+	// HedgeDelay — через сколько после первого GET отправлять дублирующий
+	// запрос. Ноль отключает хеджирование.
+	HedgeDelay time.Duration
+	Balancer   BalancerOptions
+}
+
+type RetryOptions struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+type BreakerOptions struct {
+	FailureThreshold    int
+	OpenTimeout         time.Duration
+	HalfOpenMaxRequests int
+}
+
+type BalancerOptions struct {
+	// Strategy — RoundRobin или LeastOutstanding.
+	Strategy          string
+	HealthInterval    time.Duration
+	DiscoveryInterval time.Duration
+}
@@ -0,0 +1,90 @@
+package apiclient
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"resty.dev/v3"
+	"reviews-movies/api-service/internal/metrics"
+	"time"
+)
+
+func isIdempotent(method string) bool {
+	switch method {
+	case resty.MethodGet, resty.MethodPut, resty.MethodDelete, resty.MethodHead, resty.MethodOptions:
+		return true
+	default:
+		return false
+	}
+}
+
+func retryableStatus(r result) bool {
+	if r.err != nil {
+		return true
+	}
+	switch r.status {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// backoff — экспоненциальная задержка с full jitter: случайное значение
+// в [0, min(MaxDelay, BaseDelay*2^(attempt-1))).
+func backoff(opts RetryOptions, attempt int) time.Duration {
+	d := opts.BaseDelay << (attempt - 1)
+	if d <= 0 || (opts.MaxDelay > 0 && d > opts.MaxDelay) {
+		d = opts.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// hedge отправляет primary и, если ответа нет через delay, дополнительно
+// secondary. Возвращается первый успешный ответ, второй запрос отменяется.
+func hedge(ctx context.Context, delay time.Duration, endpoint string, primary, secondary func(context.Context) result) result {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan result, 2)
+
+	go func() { results <- primary(ctx) }()
+	inflight := 1
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var last result
+	for inflight > 0 {
+		select {
+		case <-timer.C:
+			metrics.UpstreamHedged.WithLabelValues(endpoint).Inc()
+			go func() { results <- secondary(ctx) }()
+			inflight++
+		case r := <-results:
+			inflight--
+			if !r.failed() {
+				return r
+			}
+			last = r
+		}
+	}
+	return last
+}
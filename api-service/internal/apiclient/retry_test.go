@@ -0,0 +1,201 @@
+package apiclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackoffFullJitter(t *testing.T) {
+	opts := RetryOptions{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+	tests := []struct {
+		attempt int
+		ceiling time.Duration
+	}{
+		{attempt: 1, ceiling: 10 * time.Millisecond},
+		{attempt: 2, ceiling: 20 * time.Millisecond},
+		{attempt: 3, ceiling: 40 * time.Millisecond},
+		{attempt: 4, ceiling: 50 * time.Millisecond},
+		{attempt: 100, ceiling: 50 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		seen := map[time.Duration]bool{}
+		for range 200 {
+			d := backoff(opts, tt.attempt)
+			if d < 0 || d >= tt.ceiling {
+				t.Fatalf("backoff(%d) = %s, want [0, %s)", tt.attempt, d, tt.ceiling)
+			}
+			seen[d] = true
+		}
+		if len(seen) < 10 {
+			t.Errorf("backoff(%d) returned only %d distinct delays", tt.attempt, len(seen))
+		}
+	}
+
+	if d := backoff(RetryOptions{}, 1); d != 0 {
+		t.Errorf("backoff without delays = %s, want 0", d)
+	}
+	if d := backoff(RetryOptions{BaseDelay: time.Hour}, 100); d != 0 {
+		t.Errorf("backoff after overflow without MaxDelay = %s, want 0", d)
+	}
+}
+
+func TestRetryableStatus(t *testing.T) {
+	tests := []struct {
+		res  result
+		want bool
+	}{
+		{res: result{err: errors.New("reset")}, want: true},
+		{res: result{status: http.StatusBadGateway}, want: true},
+		{res: result{status: http.StatusServiceUnavailable}, want: true},
+		{res: result{status: http.StatusGatewayTimeout}, want: true},
+		{res: result{status: http.StatusInternalServerError}, want: false},
+		{res: result{status: http.StatusTooManyRequests}, want: false},
+		{res: result{status: http.StatusNotFound}, want: false},
+		{res: result{status: http.StatusOK}, want: false},
+	}
+	for _, tt := range tests {
+		if got := retryableStatus(tt.res); got != tt.want {
+			t.Errorf("retryableStatus(%d, %v) = %t, want %t", tt.res.status, tt.res.err, got, tt.want)
+		}
+	}
+
+	for method, want := range map[string]bool{
+		http.MethodGet: true, http.MethodPut: true, http.MethodDelete: true, http.MethodHead: true, http.MethodOptions: true,
+		http.MethodPost: false, http.MethodPatch: false,
+	} {
+		if got := isIdempotent(method); got != want {
+			t.Errorf("isIdempotent(%s) = %t, want %t", method, got, want)
+		}
+	}
+}
+
+// flaky отвечает status на первые fail запросов, затем 200.
+func flaky(t *testing.T, status int, fail int32) (*httptest.Server, *atomic.Int32) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == healthCheckPath {
+			return
+		}
+		if hits.Add(1) <= fail {
+			w.WriteHeader(status)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func TestDoRequestRetries(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		status int
+		want   int
+		hits   int32
+	}{
+		{name: "GET on 503", method: http.MethodGet, status: http.StatusServiceUnavailable, want: http.StatusOK, hits: 3},
+		{name: "PUT on 502", method: http.MethodPut, status: http.StatusBadGateway, want: http.StatusOK, hits: 3},
+		{name: "GET on 500 is not retried", method: http.MethodGet, status: http.StatusInternalServerError, want: http.StatusInternalServerError, hits: 1},
+		{name: "POST on 503 is not retried", method: http.MethodPost, status: http.StatusServiceUnavailable, want: http.StatusServiceUnavailable, hits: 1},
+		{name: "PATCH on 504 is not retried", method: http.MethodPatch, status: http.StatusGatewayTimeout, want: http.StatusGatewayTimeout, hits: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, hits := flaky(t, tt.status, 2)
+			c := newTestClient(t, testOptions(), srv.URL)
+
+			_, status, _, err := c.DoRequest(context.Background(), tt.method, "/api/movies/1", nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if status != tt.want {
+				t.Errorf("status = %d, want %d", status, tt.want)
+			}
+			if got := hits.Load(); got != tt.hits {
+				t.Errorf("server hits = %d, want %d", got, tt.hits)
+			}
+		})
+	}
+}
+
+func TestDoRequestStopsAtMaxAttempts(t *testing.T) {
+	srv, hits := flaky(t, http.StatusServiceUnavailable, 100)
+	c := newTestClient(t, testOptions(), srv.URL)
+
+	_, status, _, err := c.DoRequest(context.Background(), http.MethodGet, "/api/movies/1", nil, nil)
+	if err != nil || status != http.StatusServiceUnavailable {
+		t.Fatalf("DoRequest = %d, %v", status, err)
+	}
+	if got := hits.Load(); got != 3 {
+		t.Errorf("server hits = %d, want 3", got)
+	}
+}
+
+// slowFirst держит первый запрос, пока его не отменят или тест не
+// закончится, и сразу отвечает на остальные.
+func slowFirst(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	var hits atomic.Int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == healthCheckPath {
+			return
+		}
+		if hits.Add(1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(release) })
+	return srv, &hits
+}
+
+func TestDoRequestHedgesSlowGet(t *testing.T) {
+	srv, hits := slowFirst(t)
+	opts := testOptions()
+	opts.HedgeDelay = 20 * time.Millisecond
+	c := newTestClient(t, opts, srv.URL)
+
+	start := time.Now()
+	_, status, _, err := c.DoRequest(context.Background(), http.MethodGet, "/api/movies/1", nil, nil)
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("DoRequest = %d, %v", status, err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("hedged GET took %s", elapsed)
+	}
+	if got := hits.Load(); got != 2 {
+		t.Errorf("server hits = %d, want 2", got)
+	}
+}
+
+func TestDoRequestDoesNotHedgeFastGetOrPost(t *testing.T) {
+	srv, hits := flaky(t, http.StatusOK, 0)
+	opts := testOptions()
+	opts.HedgeDelay = 200 * time.Millisecond
+	c := newTestClient(t, opts, srv.URL)
+
+	if _, _, _, err := c.DoRequest(context.Background(), http.MethodGet, "/api/movies/1", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := hits.Load(); got != 1 {
+		t.Errorf("fast GET: server hits = %d, want 1", got)
+	}
+
+	slow, slowHits := slowFirst(t)
+	opts.HedgeDelay = 20 * time.Millisecond
+	opts.Timeout = 300 * time.Millisecond
+	c = newTestClient(t, opts, slow.URL)
+	if _, _, _, err := c.DoRequest(context.Background(), http.MethodPost, "/api/movies", []byte(`{}`), nil); !IsTimeout(err) {
+		t.Errorf("slow POST error = %v, want a timeout", err)
+	}
+	if got := slowHits.Load(); got != 1 {
+		t.Errorf("slow POST: server hits = %d, want 1", got)
+	}
+}
@@ -6,8 +6,13 @@ import (
 	"net/url"
 	"resty.dev/v3"
 	"reviews-movies/api-service/internal/apiclient"
+	"strconv"
 )
 
+// Client разбирает ответы data-service в структуры. Неуспешные ответы
+// возвращаются как *apiclient.StatusError и проверяются через
+// apiclient.ErrNotFound, apiclient.ErrGone, apiclient.ErrConflict,
+// apiclient.ErrValidation и apiclient.ErrPrecondition.
 type Client struct {
 	base *apiclient.BaseClient
 }
@@ -16,27 +21,137 @@ func NewReviewClient(base *apiclient.BaseClient) *Client {
 	return &Client{base: base}
 }
 
-func (c *Client) GetByID(ctx context.Context, id uint64) (body []byte, status int, contentType string, err error) {
+func (c *Client) GetByID(ctx context.Context, id uint64) (*Review, error) {
+	var out struct {
+		Review Review `json:"review"`
+	}
 	path := fmt.Sprintf("/api/reviews/%d", id)
-	return c.base.DoRequest(ctx, resty.MethodGet, path, nil, nil)
+	if err := c.base.Do(ctx, resty.MethodGet, path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out.Review, nil
 }
 
-func (c *Client) GetByCorrelationID(ctx context.Context, corrID string) (body []byte, status int, contentType string, err error) {
+// GetSparseByID читает рецензию только с полями fs.Fields.
+func (c *Client) GetSparseByID(ctx context.Context, id uint64, fs apiclient.Fieldset) (apiclient.Sparse, error) {
+	var out struct {
+		Review apiclient.Sparse `json:"review"`
+	}
+	query := url.Values{}
+	fs.Apply(query)
+	path := fmt.Sprintf("/api/reviews/%d?%s", id, query.Encode())
+	if err := c.base.Do(ctx, resty.MethodGet, path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Review, nil
+}
+
+func (c *Client) GetByCorrelationID(ctx context.Context, corrID string) (*Review, error) {
+	var out struct {
+		Review Review `json:"review"`
+	}
 	path := fmt.Sprintf("/api/reviews/by-correlation/%s", corrID)
-	return c.base.DoRequest(ctx, resty.MethodGet, path, nil, nil)
+	if err := c.base.Do(ctx, resty.MethodGet, path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out.Review, nil
 }
 
-func (c *Client) UpdateReview(ctx context.Context, id uint64, payload []byte, headers map[string][]string) (body []byte, status int, contentType string, err error) {
+// UpdateReview применяет изменения, только если версия записи совпадает с
+// ifMatch — ETag, полученным клиентом шлюза.
+func (c *Client) UpdateReview(ctx context.Context, id uint64, input ReviewUpdate, ifMatch string) (*Review, error) {
+	var out struct {
+		Review Review `json:"review"`
+	}
 	path := fmt.Sprintf("/api/reviews/%d", id)
-	return c.base.DoRequest(ctx, resty.MethodPatch, path, payload, headers)
+	if err := c.base.Do(ctx, resty.MethodPatch, path, apiclient.Precondition(ifMatch), input, &out); err != nil {
+		return nil, err
+	}
+	return &out.Review, nil
+}
+
+// RestoreReview возвращает рецензию из корзины. Пока фильм рецензии
+// удалён, data-service отвечает 409.
+func (c *Client) RestoreReview(ctx context.Context, id uint64) (*Review, error) {
+	var out struct {
+		Review Review `json:"review"`
+	}
+	path := fmt.Sprintf("/api/reviews/%d/restore", id)
+	if err := c.base.Do(ctx, resty.MethodPost, path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out.Review, nil
 }
 
-func (c *Client) DeleteReview(ctx context.Context, id uint64) (body []byte, status int, contentType string, err error) {
+func (c *Client) DeleteReview(ctx context.Context, id uint64, ifMatch string) error {
 	path := fmt.Sprintf("/api/reviews/%d", id)
-	return c.base.DoRequest(ctx, resty.MethodDelete, path, nil, nil)
+	return c.base.Do(ctx, resty.MethodDelete, path, apiclient.Precondition(ifMatch), nil, nil)
+}
+
+func (c *Client) ListReviews(ctx context.Context, params ListParams) (*ReviewList, error) {
+	var out ReviewList
+	if err := c.base.Do(ctx, resty.MethodGet, "/api/reviews?"+listParamsQuery(params).Encode(), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
 }
 
-func (c *Client) GetListReview(ctx context.Context, movieID, author, rating, sort, page, pageSize string) (body []byte, status int, contentType string, err error) {
+// ListReviewsSparse читает список только с полями fs.Fields.
+func (c *Client) ListReviewsSparse(ctx context.Context, params ListParams, fs apiclient.Fieldset) (*SparseReviewList, error) {
+	var out SparseReviewList
+	query := listParamsQuery(params)
+	fs.Apply(query)
+	if err := c.base.Do(ctx, resty.MethodGet, "/api/reviews?"+query.Encode(), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func listParamsQuery(params ListParams) url.Values {
+	var movieID, rating, page, pageSize string
+	if params.MovieID != 0 {
+		movieID = strconv.FormatUint(params.MovieID, 10)
+	}
+	if params.Rating != 0 {
+		rating = formatRating(params.Rating)
+	}
+	if params.Page != 0 {
+		page = strconv.Itoa(params.Page)
+	}
+	if params.PageSize != 0 {
+		pageSize = strconv.Itoa(params.PageSize)
+	}
+
+	query := listQuery(movieID, params.Author, rating, params.Sort, page, pageSize)
+	for key, value := range map[string]string{
+		"author_match": params.AuthorMatch,
+		"created_from": params.CreatedFrom,
+		"created_to":   params.CreatedTo,
+		"q":            params.Query,
+		"lang":         params.Lang,
+		"filter":       params.Filter,
+		"after":        params.After,
+		"before":       params.Before,
+		"count":        params.Count,
+	} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	if params.RatingMin != 0 {
+		query.Set("rating_min", formatRating(params.RatingMin))
+	}
+	if params.RatingMax != 0 {
+		query.Set("rating_max", formatRating(params.RatingMax))
+	}
+	return query
+}
+
+func formatRating(r float64) string {
+	return strconv.FormatFloat(r, 'f', -1, 64)
+}
+
+func listQuery(movieID, author, rating, sort, page, pageSize string) url.Values {
 	query := url.Values{}
 	if movieID != "" {
 		query.Set("movie_id", movieID)
@@ -56,7 +171,5 @@ func (c *Client) GetListReview(ctx context.Context, movieID, author, rating, sor
 	if pageSize != "" {
 		query.Set("page_size", pageSize)
 	}
-	path := "/api/reviews" + "?" + query.Encode()
-
-	return c.base.DoRequest(ctx, resty.MethodGet, path, nil, nil)
+	return query
 }
@@ -0,0 +1,61 @@
+package reviews
+
+import (
+	"github.com/google/uuid"
+	"reviews-movies/api-service/internal/apiclient"
+	"time"
+)
+
+// Review повторяет JSON-представление рецензии в data-service, включая
+// поля gorm.Model без json-тегов.
+type Review struct {
+	ID            uint       `json:"ID"`
+	CreatedAt     time.Time  `json:"CreatedAt"`
+	UpdatedAt     time.Time  `json:"UpdatedAt"`
+	DeletedAt     *time.Time `json:"DeletedAt"`
+	CorrelationId uuid.UUID  `json:"correlation_id"`
+	MovieId       uint       `json:"movie_id"`
+	Rating        float32    `json:"rating"`
+	Comment       string     `json:"comment"`
+	Author        string     `json:"author"`
+	Version       int32      `json:"version"`
+}
+
+type ReviewUpdate struct {
+	Rating  *float32 `json:"rating,omitempty"`
+	Comment *string  `json:"comment,omitempty"`
+}
+
+// ListParams — параметры списка рецензий. Нулевые значения не передаются,
+// и data-service подставляет свои значения по умолчанию.
+type ListParams struct {
+	MovieID     uint64
+	Author      string
+	AuthorMatch string
+	Rating      float64
+	RatingMin   float64
+	RatingMax   float64
+	// CreatedFrom и CreatedTo передаются как есть: RFC 3339 или YYYY-MM-DD.
+	CreatedFrom string
+	CreatedTo   string
+	Query       string
+	Lang        string
+	Filter      string
+	Sort        string
+	Page        int
+	PageSize    int
+	After       string
+	Before      string
+	Count       string
+}
+
+type ReviewList struct {
+	Reviews  []Review           `json:"reviews"`
+	Metadata apiclient.Metadata `json:"metadata"`
+}
+
+// SparseReviewList — список рецензий, запрошенный с fields.
+type SparseReviewList struct {
+	Reviews  []apiclient.Sparse `json:"reviews"`
+	Metadata apiclient.Metadata `json:"metadata"`
+}
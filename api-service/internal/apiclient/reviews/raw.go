@@ -0,0 +1,45 @@
+package reviews
+
+import (
+	"context"
+	"fmt"
+	"resty.dev/v3"
+	"reviews-movies/api-service/internal/apiclient"
+)
+
+// RawClient отдаёт ответы data-service как есть: тело, статус и
+// Content-Type. Нужен там, где ответ проксируется без разбора; такой ответ
+// не проверяется. Разобрать его можно через apiclient.Decode.
+type RawClient struct {
+	base *apiclient.BaseClient
+}
+
+func NewRawReviewClient(base *apiclient.BaseClient) *RawClient {
+	return &RawClient{base: base}
+}
+
+func (c *RawClient) GetByID(ctx context.Context, id uint64) (body []byte, status int, contentType string, err error) {
+	path := fmt.Sprintf("/api/reviews/%d", id)
+	return c.base.DoRequest(ctx, resty.MethodGet, path, nil, nil)
+}
+
+func (c *RawClient) GetByCorrelationID(ctx context.Context, corrID string) (body []byte, status int, contentType string, err error) {
+	path := fmt.Sprintf("/api/reviews/by-correlation/%s", corrID)
+	return c.base.DoRequest(ctx, resty.MethodGet, path, nil, nil)
+}
+
+func (c *RawClient) UpdateReview(ctx context.Context, id uint64, payload []byte, headers map[string][]string) (body []byte, status int, contentType string, err error) {
+	path := fmt.Sprintf("/api/reviews/%d", id)
+	return c.base.DoRequest(ctx, resty.MethodPatch, path, payload, headers)
+}
+
+func (c *RawClient) DeleteReview(ctx context.Context, id uint64) (body []byte, status int, contentType string, err error) {
+	path := fmt.Sprintf("/api/reviews/%d", id)
+	return c.base.DoRequest(ctx, resty.MethodDelete, path, nil, nil)
+}
+
+func (c *RawClient) GetListReview(ctx context.Context, movieID, author, rating, sort, page, pageSize string) (body []byte, status int, contentType string, err error) {
+	path := "/api/reviews?" + listQuery(movieID, author, rating, sort, page, pageSize).Encode()
+
+	return c.base.DoRequest(ctx, resty.MethodGet, path, nil, nil)
+}
@@ -0,0 +1,41 @@
+package search
+
+import (
+	"context"
+	"net/url"
+	"resty.dev/v3"
+	"reviews-movies/api-service/internal/apiclient"
+	"strconv"
+)
+
+// Client — полнотекстовый поиск data-service по фильмам и отзывам.
+type Client struct {
+	base *apiclient.BaseClient
+}
+
+func NewSearchClient(base *apiclient.BaseClient) *Client {
+	return &Client{base: base}
+}
+
+func (c *Client) Search(ctx context.Context, params Params) (*Result, error) {
+	query := url.Values{}
+	query.Set("q", params.Query)
+	if params.Lang != "" {
+		query.Set("lang", params.Lang)
+	}
+	for _, t := range params.Types {
+		query.Add("type", t)
+	}
+	if params.Page != 0 {
+		query.Set("page", strconv.Itoa(params.Page))
+	}
+	if params.PageSize != 0 {
+		query.Set("page_size", strconv.Itoa(params.PageSize))
+	}
+
+	var out Result
+	if err := c.base.Do(ctx, resty.MethodGet, "/api/search?"+query.Encode(), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
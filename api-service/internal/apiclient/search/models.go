@@ -0,0 +1,31 @@
+package search
+
+import (
+	"reviews-movies/api-service/internal/apiclient"
+)
+
+// Hit — найденный фильм или отзыв. Для отзыва Title — название фильма,
+// Snippet — фрагмент комментария с совпадениями в <mark>;
+// остальной текст в нём экранирован как HTML.
+type Hit struct {
+	Type    string  `json:"type"`
+	ID      uint    `json:"id"`
+	MovieID uint    `json:"movie_id"`
+	Title   string  `json:"title"`
+	Snippet string  `json:"snippet"`
+	Rank    float64 `json:"rank"`
+}
+
+// Params — параметры поиска. Нулевые значения не передаются.
+type Params struct {
+	Query    string
+	Lang     string
+	Types    []string
+	Page     int
+	PageSize int
+}
+
+type Result struct {
+	Hits     []Hit              `json:"hits"`
+	Metadata apiclient.Metadata `json:"metadata"`
+}
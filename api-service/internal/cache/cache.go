@@ -0,0 +1,237 @@
+// Package cache — LRU-кэш с TTL для ответов data-service. Одновременные
+// промахи по одному ключу схлопываются в один запрос, а записи снимаются
+// по тегам, когда шлюз видит изменение данных.
+//
+// Кэш свой у каждого процесса шлюза: изменение, прошедшее через одну
+// реплику, снимает записи только в ней, а остальные отдают прежний ответ
+// до истечения TTL. Поэтому TTL задаёт допустимую устарелость данных.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"golang.org/x/sync/singleflight"
+	"reviews-movies/api-service/internal/metrics"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	key     string
+	value   any
+	tags    []string
+	expires time.Time
+}
+
+type Cache struct {
+	name    string
+	size    int
+	ttl     time.Duration
+	flights singleflight.Group
+	now     func() time.Time
+
+	mu     sync.Mutex
+	items  map[string]*list.Element
+	order  *list.List
+	byTag  map[string]map[string]struct{}
+	holds  map[string]time.Time
+	gen    uint64
+	hits   uint64
+	misses uint64
+}
+
+// New создаёт кэш на size записей. При size <= 0 или ttl <= 0 кэш
+// выключен: Fetch всегда вызывает загрузку, но промахи всё равно схлопываются.
+func New(name string, size int, ttl time.Duration) *Cache {
+	return &Cache{
+		name:  name,
+		size:  size,
+		ttl:   ttl,
+		now:   time.Now,
+		items: make(map[string]*list.Element),
+		order: list.New(),
+		byTag: make(map[string]map[string]struct{}),
+		holds: make(map[string]time.Time),
+	}
+}
+
+func (c *Cache) enabled() bool {
+	return c != nil && c.size > 0 && c.ttl > 0
+}
+
+// Fetch возвращает значение из кэша или загружает его через load. Ошибки
+// не кэшируются. Загрузка идёт без отмены контекста вызывающего: её
+// результат нужен всем, кто ждёт тот же ключ.
+func Fetch[T any](ctx context.Context, c *Cache, key string, tags []string, load func(ctx context.Context) (T, error)) (T, error) {
+	if c == nil {
+		return load(ctx)
+	}
+
+	if value, ok := c.get(key); ok {
+		return value.(T), nil
+	}
+
+	gen := c.generation()
+	value, err, _ := c.flights.Do(key+"#"+strconv.FormatUint(gen, 10), func() (any, error) {
+		value, err := load(context.WithoutCancel(ctx))
+		if err != nil {
+			return nil, err
+		}
+		c.set(key, value, tags, gen)
+		return value, nil
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return value.(T), nil
+}
+
+// Invalidate удаляет все записи с любым из тегов. Загрузки, начатые до
+// вызова, свой результат в кэш уже не положат.
+func (c *Cache) Invalidate(tags ...string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.invalidate(tags)
+}
+
+// Hold — Invalidate для изменения, которое data-service применит позже,
+// например после чтения из Kafka. Кроме снятия записей, ответы с этими
+// тегами window не кэшируются: иначе чтение до записи в базу закэшировало
+// бы старые данные на весь TTL.
+func (c *Cache) Hold(window time.Duration, tags ...string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.invalidate(tags)
+	until := c.now().Add(window)
+	for _, tag := range tags {
+		if until.After(c.holds[tag]) {
+			c.holds[tag] = until
+		}
+	}
+}
+
+func (c *Cache) invalidate(tags []string) {
+	c.gen++
+	for _, tag := range tags {
+		for key := range c.byTag[tag] {
+			if el, ok := c.items[key]; ok {
+				c.remove(el)
+			}
+		}
+	}
+}
+
+// held сообщает, что один из тегов ещё под Hold. Истёкшие удерживания
+// удаляются.
+func (c *Cache) held(tags []string) bool {
+	now := c.now()
+	for _, tag := range tags {
+		until, ok := c.holds[tag]
+		if !ok {
+			continue
+		}
+		if now.Before(until) {
+			return true
+		}
+		delete(c.holds, tag)
+	}
+	return false
+}
+
+func (c *Cache) generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
+func (c *Cache) get(key string) (any, bool) {
+	if !c.enabled() {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if ok && c.now().After(el.Value.(*entry).expires) {
+		c.remove(el)
+		ok = false
+	}
+
+	if ok {
+		c.hits++
+		c.order.MoveToFront(el)
+	} else {
+		c.misses++
+	}
+	c.observe(ok)
+
+	if !ok {
+		return nil, false
+	}
+	return el.Value.(*entry).value, true
+}
+
+func (c *Cache) set(key string, value any, tags []string, gen uint64) {
+	if !c.enabled() {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if gen != c.gen || c.held(tags) {
+		return
+	}
+
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+
+	el := c.order.PushFront(&entry{key: key, value: value, tags: tags, expires: c.now().Add(c.ttl)})
+	c.items[key] = el
+	for _, tag := range tags {
+		if c.byTag[tag] == nil {
+			c.byTag[tag] = make(map[string]struct{})
+		}
+		c.byTag[tag][key] = struct{}{}
+	}
+
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+	metrics.CacheEntries.WithLabelValues(c.name).Set(float64(c.order.Len()))
+}
+
+func (c *Cache) remove(el *list.Element) {
+	e := el.Value.(*entry)
+	c.order.Remove(el)
+	delete(c.items, e.key)
+	for _, tag := range e.tags {
+		delete(c.byTag[tag], e.key)
+		if len(c.byTag[tag]) == 0 {
+			delete(c.byTag, tag)
+		}
+	}
+	metrics.CacheEntries.WithLabelValues(c.name).Set(float64(c.order.Len()))
+}
+
+func (c *Cache) observe(hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	metrics.CacheRequests.WithLabelValues(c.name, result).Inc()
+	metrics.CacheHitRatio.WithLabelValues(c.name).Set(float64(c.hits) / float64(c.hits+c.misses))
+}
@@ -0,0 +1,342 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// clock — управляемое время для TTL и Hold.
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestCache(size int, ttl time.Duration) (*Cache, *clock) {
+	clk := &clock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	c := New("test", size, ttl)
+	c.now = clk.Now
+	return c, clk
+}
+
+// loader считает загрузки по ключам и возвращает номер загрузки.
+type loader struct {
+	mu    sync.Mutex
+	loads map[string]int
+}
+
+func (l *loader) fetch(t *testing.T, c *Cache, key string, tags ...string) int {
+	t.Helper()
+	v, err := Fetch(context.Background(), c, key, tags, func(ctx context.Context) (int, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.loads == nil {
+			l.loads = make(map[string]int)
+		}
+		l.loads[key]++
+		return l.loads[key], nil
+	})
+	if err != nil {
+		t.Fatalf("Fetch(%s): %v", key, err)
+	}
+	return v
+}
+
+func TestFetch(t *testing.T) {
+	const ttl = time.Minute
+
+	tests := []struct {
+		name  string
+		size  int
+		ttl   time.Duration
+		steps func(t *testing.T, c *Cache, clk *clock, l *loader)
+		loads map[string]int
+	}{
+		{
+			name: "hit",
+			size: 2, ttl: ttl,
+			steps: func(t *testing.T, c *Cache, clk *clock, l *loader) {
+				l.fetch(t, c, "a")
+				if v := l.fetch(t, c, "a"); v != 1 {
+					t.Errorf("second fetch = %d, want cached 1", v)
+				}
+			},
+			loads: map[string]int{"a": 1},
+		},
+		{
+			name: "lru eviction",
+			size: 2, ttl: ttl,
+			steps: func(t *testing.T, c *Cache, clk *clock, l *loader) {
+				l.fetch(t, c, "a")
+				l.fetch(t, c, "b")
+				l.fetch(t, c, "a") // a становится самой свежей
+				l.fetch(t, c, "c") // вытесняет b
+				l.fetch(t, c, "a")
+				l.fetch(t, c, "b")
+			},
+			loads: map[string]int{"a": 1, "b": 2, "c": 1},
+		},
+		{
+			name: "ttl expiry",
+			size: 2, ttl: ttl,
+			steps: func(t *testing.T, c *Cache, clk *clock, l *loader) {
+				l.fetch(t, c, "a")
+				clk.Advance(ttl)
+				l.fetch(t, c, "a")
+				clk.Advance(time.Nanosecond)
+				if v := l.fetch(t, c, "a"); v != 2 {
+					t.Errorf("fetch after ttl = %d, want reload 2", v)
+				}
+			},
+			loads: map[string]int{"a": 2},
+		},
+		{
+			name: "invalidate by tag",
+			size: 4, ttl: ttl,
+			steps: func(t *testing.T, c *Cache, clk *clock, l *loader) {
+				l.fetch(t, c, "movie:1", "movie:1")
+				l.fetch(t, c, "list", "movies")
+				l.fetch(t, c, "movie:2", "movie:2")
+				c.Invalidate("movie:1", "movies")
+				l.fetch(t, c, "movie:1", "movie:1")
+				l.fetch(t, c, "list", "movies")
+				l.fetch(t, c, "movie:2", "movie:2")
+			},
+			loads: map[string]int{"movie:1": 2, "list": 2, "movie:2": 1},
+		},
+		{
+			name: "hold window",
+			size: 2, ttl: ttl,
+			steps: func(t *testing.T, c *Cache, clk *clock, l *loader) {
+				l.fetch(t, c, "a", "movies")
+				c.Hold(10*time.Second, "movies")
+				l.fetch(t, c, "a", "movies") // запись снята через Hold
+				l.fetch(t, c, "a", "movies") // не закэширована в окне
+				l.fetch(t, c, "b", "reviews")
+				l.fetch(t, c, "b", "reviews") // другой тег кэшируется
+				clk.Advance(10 * time.Second)
+				l.fetch(t, c, "a", "movies")
+				l.fetch(t, c, "a", "movies")
+			},
+			loads: map[string]int{"a": 4, "b": 1},
+		},
+		{
+			name: "hold keeps the longest window",
+			size: 2, ttl: ttl,
+			steps: func(t *testing.T, c *Cache, clk *clock, l *loader) {
+				c.Hold(10*time.Second, "movies")
+				c.Hold(time.Second, "movies")
+				clk.Advance(5 * time.Second)
+				l.fetch(t, c, "a", "movies")
+				l.fetch(t, c, "a", "movies")
+			},
+			loads: map[string]int{"a": 2},
+		},
+		{
+			name: "disabled",
+			size: 0, ttl: ttl,
+			steps: func(t *testing.T, c *Cache, clk *clock, l *loader) {
+				l.fetch(t, c, "a")
+				l.fetch(t, c, "a")
+			},
+			loads: map[string]int{"a": 2},
+		},
+		{
+			name: "zero ttl",
+			size: 2, ttl: 0,
+			steps: func(t *testing.T, c *Cache, clk *clock, l *loader) {
+				l.fetch(t, c, "a")
+				l.fetch(t, c, "a")
+			},
+			loads: map[string]int{"a": 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, clk := newTestCache(tt.size, tt.ttl)
+			l := &loader{}
+			tt.steps(t, c, clk, l)
+
+			for key, want := range tt.loads {
+				if got := l.loads[key]; got != want {
+					t.Errorf("loads[%s] = %d, want %d", key, got, want)
+				}
+			}
+			if len(c.items) > max(tt.size, 0) || len(c.items) != c.order.Len() {
+				t.Errorf("items = %d, order = %d, size = %d", len(c.items), c.order.Len(), tt.size)
+			}
+		})
+	}
+}
+
+func TestFetchErrorNotCached(t *testing.T) {
+	c, _ := newTestCache(2, time.Minute)
+	errLoad := errors.New("upstream down")
+
+	calls := 0
+	load := func(ctx context.Context) (int, error) {
+		calls++
+		if calls == 1 {
+			return 0, errLoad
+		}
+		return calls, nil
+	}
+
+	if _, err := Fetch(context.Background(), c, "a", nil, load); !errors.Is(err, errLoad) {
+		t.Fatalf("err = %v, want %v", err, errLoad)
+	}
+	if v, err := Fetch(context.Background(), c, "a", nil, load); err != nil || v != 2 {
+		t.Fatalf("Fetch = %d, %v, want 2, nil", v, err)
+	}
+}
+
+func TestFetchNilCache(t *testing.T) {
+	var c *Cache
+	c.Invalidate("movies")
+	c.Hold(time.Second, "movies")
+
+	l := &loader{}
+	l.fetch(t, c, "a")
+	l.fetch(t, c, "a")
+	if l.loads["a"] != 2 {
+		t.Errorf("loads = %d, want 2", l.loads["a"])
+	}
+}
+
+// blockingLoad возвращает загрузку, которая сообщает о старте в started
+// и ждёт release.
+func blockingLoad(loads *atomic.Int32, started chan<- struct{}, release <-chan struct{}) func(context.Context) (int, error) {
+	return func(ctx context.Context) (int, error) {
+		n := loads.Add(1)
+		started <- struct{}{}
+		<-release
+		return int(n), nil
+	}
+}
+
+func TestFetchCollapsesConcurrentMisses(t *testing.T) {
+	c, _ := newTestCache(2, time.Minute)
+
+	var loads atomic.Int32
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	load := blockingLoad(&loads, started, release)
+
+	const callers = 10
+	results := make(chan int, callers)
+	var wg sync.WaitGroup
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := Fetch(context.Background(), c, "a", nil, load)
+			if err != nil {
+				t.Error(err)
+			}
+			results <- v
+		}()
+	}
+
+	<-started
+	// Остальные вызовы успевают встать в ожидание той же загрузки.
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	if n := loads.Load(); n != 1 {
+		t.Errorf("loads = %d, want 1", n)
+	}
+	for v := range results {
+		if v != 1 {
+			t.Errorf("result = %d, want 1", v)
+		}
+	}
+}
+
+func TestFetchCancelledCallerKeepsLoad(t *testing.T) {
+	c, _ := newTestCache(2, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	v, err := Fetch(ctx, c, "a", nil, func(ctx context.Context) (int, error) {
+		return 1, ctx.Err()
+	})
+	if err != nil || v != 1 {
+		t.Errorf("Fetch = %d, %v, want the load to run without the caller's cancellation", v, err)
+	}
+}
+
+func TestInvalidateDropsInFlightLoad(t *testing.T) {
+	tests := []struct {
+		name       string
+		invalidate func(c *Cache)
+	}{
+		{name: "invalidate", invalidate: func(c *Cache) { c.Invalidate("movies") }},
+		{name: "hold", invalidate: func(c *Cache) { c.Hold(time.Nanosecond, "movies") }},
+		{name: "unrelated tag", invalidate: func(c *Cache) { c.Invalidate("reviews") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, clk := newTestCache(2, time.Minute)
+
+			var loads atomic.Int32
+			started := make(chan struct{}, 2)
+			release := make(chan struct{})
+			load := blockingLoad(&loads, started, release)
+
+			stale := make(chan int, 1)
+			go func() {
+				v, _ := Fetch(context.Background(), c, "a", []string{"movies"}, load)
+				stale <- v
+			}()
+			<-started
+
+			tt.invalidate(c)
+			clk.Advance(time.Second)
+
+			// Новая загрузка после снятия не присоединяется к старой.
+			fresh := make(chan int, 1)
+			go func() {
+				v, _ := Fetch(context.Background(), c, "a", []string{"movies"}, load)
+				fresh <- v
+			}()
+			<-started
+
+			close(release)
+			old, cur := <-stale, <-fresh
+			if old == cur {
+				t.Fatalf("both fetches got load %d, want separate loads", old)
+			}
+
+			// Закэширована только загрузка, начатая после снятия.
+			v, err := Fetch(context.Background(), c, "a", []string{"movies"}, func(ctx context.Context) (int, error) {
+				return 0, errors.New("unexpected load")
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v != cur {
+				t.Errorf("cached = %d, want the fresh load %d", v, cur)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+// Package data описывает входные сообщения о создании фильмов и рецензий.
+// Проверка делегируется пакету rules, который генерируется из data-service,
+// поэтому шлюз и сервис отклоняют записи по одним и тем же правилам.
+package data
+
+import (
+	"github.com/google/uuid"
+	"reviews-movies/api-service/internal/rules"
+	"reviews-movies/api-service/internal/validator"
+)
+
+type MovieInput struct {
+	Title         string    `json:"title"`
+	Year          int32     `json:"year"`
+	Runtime       int32     `json:"runtime"`
+	Genres        []string  `json:"genres"`
+	CorrelationId uuid.UUID `json:"correlation_id" binding:"-"`
+}
+
+type ReviewInput struct {
+	CorrelationId uuid.UUID `json:"correlation_id" binding:"-"`
+	MovieId       uint      `json:"movie_id"`
+	Rating        float32   `json:"rating"`
+	Comment       string    `json:"comment"`
+	Author        string    `json:"author"`
+}
+
+func ValidateMovie(v *validator.Validator, movie *MovieInput) {
+	rules.ValidateMovie(v, rules.Movie{
+		Title:   movie.Title,
+		Year:    movie.Year,
+		Runtime: movie.Runtime,
+		Genres:  movie.Genres,
+	})
+}
+
+func ValidateReview(v *validator.Validator, review *ReviewInput) {
+	rules.ValidateReview(v, rules.Review{
+		CorrelationId: review.CorrelationId,
+		MovieId:       review.MovieId,
+		Rating:        review.Rating,
+		Comment:       review.Comment,
+		Author:        review.Author,
+	})
+}
@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"reviews-movies/api-service/internal/data"
+	"reviews-movies/api-service/internal/kafka"
+	"reviews-movies/api-service/internal/problem"
+	"reviews-movies/api-service/internal/validator"
+	"strconv"
+	"time"
+)
+
+// BatchResult — итог по одному элементу пакета: correlation_id принятого
+// сообщения либо ошибки проверки или публикации.
+type BatchResult struct {
+	Index         int               `json:"index"`
+	CorrelationId *uuid.UUID        `json:"correlation_id,omitempty"`
+	Errors        map[string]string `json:"errors,omitempty"`
+}
+
+type batchResponse struct {
+	Accepted int           `json:"accepted"`
+	Rejected int           `json:"rejected"`
+	Results  []BatchResult `json:"results"`
+}
+
+func (h *Handler) CreateMoviesBatchHandler(c *gin.Context) {
+	items, ok := h.readBatch(c)
+	if !ok {
+		return
+	}
+
+	results := make([]BatchResult, len(items))
+	var (
+		inputs   []data.MovieInput
+		messages []kafka.Message
+		indexes  []int
+	)
+	for i, raw := range items {
+		results[i].Index = i
+
+		var input data.MovieInput
+		if err := json.Unmarshal(raw, &input); err != nil {
+			results[i].Errors = map[string]string{"item": "must be a valid movie object"}
+			continue
+		}
+		input.CorrelationId = uuid.New()
+
+		v := validator.New()
+		if data.ValidateMovie(v, &input); !v.Valid() {
+			results[i].Errors = v.Errors
+			continue
+		}
+
+		msg, err := json.Marshal(input)
+		if err != nil {
+			h.serverError(c, err, "failed to serialize message")
+			return
+		}
+		inputs = append(inputs, input)
+		messages = append(messages, kafka.Message{Key: input.CorrelationId.String(), Value: msg})
+		indexes = append(indexes, i)
+	}
+
+	errs := h.producer.ProduceBatch(c.Request.Context(), h.cfg.Kafka.Topics.Movie, messages, time.Now())
+	for j, err := range errs {
+		i := indexes[j]
+		if err != nil {
+			h.logger.ErrorContext(c.Request.Context(), "failed to produce movie", "index", i, "error", err)
+			results[i].Errors = map[string]string{"item": "failed to publish, please retry"}
+			continue
+		}
+		results[i].CorrelationId = &inputs[j].CorrelationId
+	}
+	if len(messages) > 0 {
+		h.moviesClient.PendingMovies()
+	}
+
+	c.JSON(http.StatusOK, summarize(results))
+}
+
+func (h *Handler) CreateReviewsBatchHandler(c *gin.Context) {
+	items, ok := h.readBatch(c)
+	if !ok {
+		return
+	}
+
+	results := make([]BatchResult, len(items))
+	var (
+		inputs   []data.ReviewInput
+		messages []kafka.Message
+		indexes  []int
+	)
+	for i, raw := range items {
+		results[i].Index = i
+
+		var input data.ReviewInput
+		if err := json.Unmarshal(raw, &input); err != nil {
+			results[i].Errors = map[string]string{"item": "must be a valid review object"}
+			continue
+		}
+		input.CorrelationId = uuid.New()
+
+		v := validator.New()
+		if data.ValidateReview(v, &input); !v.Valid() {
+			results[i].Errors = v.Errors
+			continue
+		}
+
+		msg, err := json.Marshal(input)
+		if err != nil {
+			h.serverError(c, err, "failed to serialize message")
+			return
+		}
+		inputs = append(inputs, input)
+		messages = append(messages, kafka.Message{Key: strconv.Itoa(int(input.MovieId)), Value: msg})
+		indexes = append(indexes, i)
+	}
+
+	errs := h.producer.ProduceBatch(c.Request.Context(), h.cfg.Kafka.Topics.Review, messages, time.Now())
+	for j, err := range errs {
+		i := indexes[j]
+		if err != nil {
+			h.logger.ErrorContext(c.Request.Context(), "failed to produce review", "index", i, "error", err)
+			results[i].Errors = map[string]string{"item": "failed to publish, please retry"}
+			continue
+		}
+		results[i].CorrelationId = &inputs[j].CorrelationId
+		h.moviesClient.PendingReviews(uint64(inputs[j].MovieId))
+	}
+
+	c.JSON(http.StatusOK, summarize(results))
+}
+
+// readBatch разбирает тело {"items": [...]}. Элементы остаются сырыми,
+// чтобы ошибка в одном из них не отклоняла весь пакет.
+func (h *Handler) readBatch(c *gin.Context) ([]json.RawMessage, bool) {
+	var input struct {
+		Items []json.RawMessage `json:"items"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		problem.BadRequest(c, "invalid input data")
+		return nil, false
+	}
+
+	v := validator.New()
+	v.Check(len(input.Items) > 0, "items", "must contain at least one item")
+	v.Check(len(input.Items) <= h.cfg.Batch.MaxItems, "items", "must not contain more than "+strconv.Itoa(h.cfg.Batch.MaxItems)+" items")
+	if !v.Valid() {
+		problem.Validation(c, v.Errors)
+		return nil, false
+	}
+	return input.Items, true
+}
+
+func summarize(results []BatchResult) batchResponse {
+	resp := batchResponse{Results: results}
+	for _, r := range results {
+		if r.CorrelationId != nil {
+			resp.Accepted++
+		} else {
+			resp.Rejected++
+		}
+	}
+	return resp
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reviews-movies/api-service/internal/idempotency"
+	"reviews-movies/api-service/internal/problem"
+	"strings"
+	"testing"
+)
+
+// Элементы в тестах не проходят проверку, поэтому до Kafka дело не доходит.
+const rejectedBatch = `{"items": [{"title": ""}, 1]}`
+
+func TestBatchRoutes(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+	}{
+		{name: "movies", method: http.MethodPost, path: "/api/movies:batch", status: http.StatusOK},
+		{name: "reviews", method: http.MethodPost, path: "/api/reviews:batch", status: http.StatusOK},
+		{name: "unknown verb", method: http.MethodPost, path: "/api/movies:import", status: http.StatusNotFound},
+		{name: "suffix without colon", method: http.MethodPost, path: "/api/moviesbatch", status: http.StatusNotFound},
+		{name: "verb without colon", method: http.MethodPost, path: "/api/movies:", status: http.StatusNotFound},
+		{name: "trailing slash", method: http.MethodPost, path: "/api/movies:batch/", status: http.StatusNotFound},
+		{name: "wrong method", method: http.MethodGet, path: "/api/movies:batch", status: http.StatusNotFound},
+		{name: "other collection", method: http.MethodPost, path: "/api/imports:batch", status: http.StatusNotFound},
+	}
+
+	router := newTestRouter(t, dataService())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(rejectedBatch))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.status == http.StatusNotFound {
+				if ct := rec.Header().Get("Content-Type"); ct != problem.ContentType {
+					t.Errorf("Content-Type = %q, want %q", ct, problem.ContentType)
+				}
+				return
+			}
+
+			var resp batchResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if resp.Accepted != 0 || resp.Rejected != 2 || len(resp.Results) != 2 {
+				t.Errorf("response = %+v, want two rejected items", resp)
+			}
+			if resp.Results[1].Errors["item"] == "" {
+				t.Errorf("non-object item errors = %v", resp.Results[1].Errors)
+			}
+		})
+	}
+}
+
+func TestBatchIdempotencyKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		status   int
+		replayed bool
+	}{
+		{name: "first", path: "/api/movies:batch", body: rejectedBatch, status: http.StatusOK},
+		{name: "replay", path: "/api/movies:batch", body: rejectedBatch, status: http.StatusOK, replayed: true},
+		{name: "different body", path: "/api/movies:batch", body: `{"items": [{}]}`, status: http.StatusUnprocessableEntity},
+		{name: "other route", path: "/api/reviews:batch", body: rejectedBatch, status: http.StatusUnprocessableEntity},
+	}
+
+	router := newTestRouter(t, dataService())
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(idempotency.Header, "batch-1")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Fatalf("%s: status = %d, want %d; body %s", tt.name, rec.Code, tt.status, rec.Body)
+		}
+		if got := rec.Header().Get(idempotency.ReplayedHeader) == "true"; got != tt.replayed {
+			t.Errorf("%s: replayed = %v, want %v", tt.name, got, tt.replayed)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"reviews-movies/api-service/internal/apiclient/movies"
+	"reviews-movies/api-service/internal/apiclient/reviews"
+	"reviews-movies/api-service/internal/problem"
+	"strconv"
+	"sync"
+)
+
+const (
+	defaultDetailsReviews = 5
+	maxDetailsReviews     = 100
+)
+
+// MovieDetails — страница фильма целиком. Если рецензии или статистику
+// получить не удалось, соответствующее поле равно null, Partial выставлен,
+// а причина лежит в Errors под именем части ответа.
+type MovieDetails struct {
+	Movie   *movies.Movie               `json:"movie"`
+	Reviews []reviews.Review            `json:"reviews"`
+	Stats   *movies.MovieStats          `json:"stats"`
+	Partial bool                        `json:"partial,omitempty"`
+	Errors  map[string]*problem.Problem `json:"errors,omitempty"`
+}
+
+// GetMovieDetailsHandler параллельно запрашивает фильм, последние рецензии
+// и статистику оценок. Без фильма ответ не имеет смысла, поэтому его ошибка
+// отдаётся клиенту целиком, а остальные запросы отменяются.
+func (h *Handler) GetMovieDetailsHandler(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil || id <= 0 {
+		problem.BadRequest(c, "invalid id")
+		return
+	}
+
+	limit := defaultDetailsReviews
+	if value := c.Query("reviews_limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > maxDetailsReviews {
+			problem.BadRequest(c, "reviews_limit must be between 1 and "+strconv.Itoa(maxDetailsReviews))
+			return
+		}
+	}
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	var (
+		wg         sync.WaitGroup
+		details    MovieDetails
+		movieErr   error
+		reviewsErr error
+		statsErr   error
+	)
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		details.Movie, movieErr = h.moviesClient.GetByID(ctx, id)
+		if movieErr != nil {
+			cancel()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		var list *reviews.ReviewList
+		list, reviewsErr = h.reviewsClient.ListReviews(ctx, reviews.ListParams{
+			MovieID:  id,
+			Sort:     "-id",
+			PageSize: limit,
+		})
+		if reviewsErr == nil {
+			details.Reviews = list.Reviews
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		details.Stats, statsErr = h.moviesClient.GetStats(ctx, id)
+	}()
+	wg.Wait()
+
+	if movieErr != nil {
+		h.upstreamError(c, movieErr)
+		return
+	}
+
+	for part, err := range map[string]error{"reviews": reviewsErr, "stats": statsErr} {
+		if err == nil {
+			continue
+		}
+		if details.Errors == nil {
+			details.Errors = make(map[string]*problem.Problem)
+		}
+		details.Errors[part] = h.upstreamProblem(ctx, err)
+		details.Partial = true
+	}
+
+	c.JSON(http.StatusOK, details)
+}
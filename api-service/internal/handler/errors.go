@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"reviews-movies/api-service/internal/apiclient"
+	"reviews-movies/api-service/internal/problem"
+	"strconv"
+)
+
+// upstreamError переводит ошибку обращения к data-service в problem-ответ.
+func (h *Handler) upstreamError(c *gin.Context, err error) {
+	problem.Write(c, h.upstreamProblem(c.Request.Context(), err))
+}
+
+// upstreamProblem строит problem для ошибки обращения к data-service.
+// Отказы 4xx отдаются клиенту как есть, сбои самого data-service и сети
+// заменяются ответами шлюза: 503, 504 или 502.
+func (h *Handler) upstreamProblem(ctx context.Context, err error) *problem.Problem {
+	var statusErr *apiclient.StatusError
+	if errors.As(err, &statusErr) && statusErr.Status < http.StatusInternalServerError {
+		return clientProblem(statusErr)
+	}
+
+	h.logger.ErrorContext(ctx, "data-service request failed", "error", err)
+
+	switch {
+	case apiclient.IsUnavailable(err), statusErr != nil && statusErr.Status == http.StatusServiceUnavailable:
+		return problem.New(http.StatusServiceUnavailable, problem.CodeUpstreamUnavailable, "data-service is temporarily unavailable")
+	case apiclient.IsTimeout(err), statusErr != nil && statusErr.Status == http.StatusGatewayTimeout:
+		return problem.New(http.StatusGatewayTimeout, problem.CodeUpstreamTimeout, "data-service did not respond in time")
+	case apiclient.IsConnectionError(err):
+		return problem.New(http.StatusBadGateway, problem.CodeBadGateway, "could not connect to data-service")
+	case statusErr != nil:
+		return problem.New(http.StatusBadGateway, problem.CodeBadGateway, "data-service failed to process the request")
+	default:
+		return problem.New(http.StatusBadGateway, problem.CodeBadGateway, "invalid response from data-service")
+	}
+}
+
+// clientProblem возвращает problem-ответ data-service для повторной отдачи
+// клиенту. Instance и request_id Write проставит заново.
+func clientProblem(statusErr *apiclient.StatusError) *problem.Problem {
+	if statusErr.Problem != nil {
+		p := *statusErr.Problem
+		p.Instance = ""
+		return &p
+	}
+
+	switch statusErr.Status {
+	case http.StatusNotFound:
+		return problem.New(statusErr.Status, problem.CodeNotFound, "the requested resource could not be found")
+	case http.StatusGone:
+		return problem.New(statusErr.Status, problem.CodeGone, "the record has been deleted")
+	case http.StatusConflict:
+		return problem.New(statusErr.Status, problem.CodeEditConflict, "unable to update the record due to an edit conflict, please try again")
+	case http.StatusPreconditionFailed:
+		return problem.New(statusErr.Status, problem.CodePreconditionFailed, "the record has been modified, fetch it again and retry with the new ETag")
+	case http.StatusPreconditionRequired:
+		return problem.New(statusErr.Status, problem.CodePreconditionNeeded, "this request must be conditional, send If-Match with the ETag of the record")
+	default:
+		return problem.New(statusErr.Status, problem.CodeInvalidRequest, "data-service rejected the request")
+	}
+}
+
+// queryInt разбирает необязательный целочисленный параметр запроса. При
+// ошибке ответ уже отправлен и возвращается false.
+func queryInt(c *gin.Context, name string) (int, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return 0, true
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		problem.BadRequest(c, "invalid "+name)
+		return 0, false
+	}
+	return n, true
+}
+
+// queryFieldset читает fields и include; проверяет их data-service.
+func queryFieldset(c *gin.Context) apiclient.Fieldset {
+	return apiclient.Fieldset{Fields: c.Query("fields"), Include: c.Query("include")}
+}
+
+func (h *Handler) produceError(c *gin.Context, err error) {
+	h.logger.ErrorContext(c.Request.Context(), "failed to produce message", "error", err)
+	problem.Write(c, problem.New(http.StatusServiceUnavailable, problem.CodeUpstreamUnavailable, "message broker is temporarily unavailable"))
+}
+
+func (h *Handler) serverError(c *gin.Context, err error, detail string) {
+	h.logger.ErrorContext(c.Request.Context(), detail, "error", err)
+	problem.Internal(c, detail)
+}
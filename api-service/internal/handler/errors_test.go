@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reviews-movies/api-service/config"
+	"reviews-movies/api-service/internal/apiclient"
+	"reviews-movies/api-service/internal/problem"
+	"testing"
+)
+
+// newTestRouter собирает роутер шлюза, у которого data-service — upstream.
+// Kafka в тестах не нужна: продюсер не создаётся.
+func newTestRouter(t *testing.T, upstream http.Handler) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	srv := httptest.NewServer(upstream)
+	t.Cleanup(srv.Close)
+
+	t.Setenv("DATA_SERVICE_HOST", srv.URL)
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewHandler(nil, slog.New(slog.NewTextHandler(io.Discard, nil)), cfg)
+	t.Cleanup(h.Close)
+	return h.Routes()
+}
+
+// dataService отвечает так же, как data-service: фильм 42 не существует,
+// фильм 5 и отзыв 7 в корзине, отзыв 7 удалён вместе с фильмом 3.
+func dataService() http.Handler {
+	writeProblem := func(w http.ResponseWriter, p *problem.Problem) {
+		w.Header().Set("Content-Type", problem.ContentType)
+		w.WriteHeader(p.Status)
+		json.NewEncoder(w).Encode(p)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("GET /api/movies/42", func(w http.ResponseWriter, r *http.Request) {
+		p := problem.New(http.StatusNotFound, problem.CodeNotFound, "movie not found")
+		p.Instance = r.URL.Path
+		writeProblem(w, p)
+	})
+	mux.HandleFunc("GET /api/movies/5", func(w http.ResponseWriter, r *http.Request) {
+		p := problem.New(http.StatusGone, problem.CodeGone, "the record has been deleted")
+		p.Instance = r.URL.Path
+		p.Restore = "/api/movies/5/restore"
+		writeProblem(w, p)
+	})
+	mux.HandleFunc("GET /api/reviews/7", func(w http.ResponseWriter, r *http.Request) {
+		p := problem.New(http.StatusGone, problem.CodeGone, "the record has been deleted")
+		p.Instance = r.URL.Path
+		p.Restore = "/api/movies/3/restore"
+		writeProblem(w, p)
+	})
+	mux.HandleFunc("GET /api/reviews/8", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	})
+	return mux
+}
+
+func TestUpstreamMissingRecords(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		status  int
+		code    string
+		restore string
+	}{
+		{name: "unknown movie", path: "/api/movies/42", status: http.StatusNotFound, code: problem.CodeNotFound},
+		{name: "deleted movie", path: "/api/movies/5", status: http.StatusGone, code: problem.CodeGone, restore: "/api/movies/5/restore"},
+		{name: "deleted movie sparse", path: "/api/movies/5?fields=title", status: http.StatusGone, code: problem.CodeGone, restore: "/api/movies/5/restore"},
+		{name: "review deleted with its movie", path: "/api/reviews/7", status: http.StatusGone, code: problem.CodeGone, restore: "/api/movies/3/restore"},
+		{name: "gone without problem body", path: "/api/reviews/8", status: http.StatusGone, code: problem.CodeGone},
+	}
+
+	router := newTestRouter(t, dataService())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, tt.status, rec.Body)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != problem.ContentType {
+				t.Errorf("Content-Type = %q, want %q", ct, problem.ContentType)
+			}
+
+			var p problem.Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if p.Status != tt.status || p.Code != tt.code {
+				t.Errorf("status, code = %d, %q, want %d, %q", p.Status, p.Code, tt.status, tt.code)
+			}
+			if p.Type != "urn:reviews-movies:problem:"+tt.code || p.Title == "" || p.Detail == "" {
+				t.Errorf("incomplete problem: %+v", p)
+			}
+			if p.Instance != req.URL.Path {
+				t.Errorf("instance = %q, want %q", p.Instance, req.URL.Path)
+			}
+			if p.Restore != tt.restore {
+				t.Errorf("restore = %q, want %q", p.Restore, tt.restore)
+			}
+		})
+	}
+}
+
+func TestClientProblemKeepsRestore(t *testing.T) {
+	upstream := problem.New(http.StatusGone, problem.CodeGone, "the record has been deleted")
+	upstream.Instance = "/api/movies/5"
+	upstream.RequestID = "upstream-request"
+	upstream.Restore = "/api/movies/5/restore"
+
+	p := clientProblem(&apiclient.StatusError{Status: http.StatusGone, Problem: upstream})
+
+	if p.Code != problem.CodeGone || p.Status != http.StatusGone {
+		t.Errorf("status, code = %d, %q", p.Status, p.Code)
+	}
+	if p.Restore != upstream.Restore {
+		t.Errorf("restore = %q, want %q", p.Restore, upstream.Restore)
+	}
+	if p.Instance != "" {
+		t.Errorf("instance = %q, want it reset for the gateway path", p.Instance)
+	}
+	if upstream.Instance == "" {
+		t.Error("clientProblem modified the upstream problem")
+	}
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"hash/fnv"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// etag строит ETag из версии записи так же, как data-service, поэтому
+// шлюз может отвечать 304 по закэшированной записи без обращения к нему.
+func etag(version int32) string {
+	return `"` + strconv.FormatInt(int64(version), 10) + `"`
+}
+
+// fieldsETag строит ETag выборочного представления так же, как
+// data-service: к версии добавляется FNV-1a от отсортированного списка
+// полей без пустых элементов и повторов.
+func fieldsETag(version int32, fields string) string {
+	if fields == "" {
+		return etag(version)
+	}
+	var list []string
+	for _, field := range strings.Split(fields, ",") {
+		if field = strings.TrimSpace(field); field != "" && !slices.Contains(list, field) {
+			list = append(list, field)
+		}
+	}
+	if len(list) == 0 {
+		return etag(version)
+	}
+	slices.Sort(list)
+	h := fnv.New32a()
+	h.Write([]byte(strings.Join(list, ",")))
+	return fmt.Sprintf(`"%d-%08x"`, version, h.Sum32())
+}
+
+// notModified выставляет ETag tag и отвечает 304, если If-None-Match
+// совпал с ним.
+func notModified(c *gin.Context, tag string) bool {
+	c.Header("ETag", tag)
+
+	for _, candidate := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == tag {
+			c.AbortWithStatus(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,25 @@
+package handler
+
+import "testing"
+
+// data-service строит тот же ETag в internal/handler/etag.go: значения в
+// тестах обоих сервисов должны совпадать.
+func TestFieldsETag(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields string
+		want   string
+	}{
+		{name: "full", want: `"3"`},
+		{name: "sparse", fields: "title,year", want: `"3-0775d28a"`},
+		{name: "sparse reordered", fields: " year,title,year,", want: `"3-0775d28a"`},
+		{name: "empty list", fields: ",", want: `"3"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fieldsETag(3, tt.fields); got != tt.want {
+				t.Errorf("fieldsETag = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,49 +1,139 @@
 package handler
 
 import (
+	"fmt"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"log/slog"
 	"reviews-movies/api-service/config"
 	"reviews-movies/api-service/internal/apiclient"
 	"reviews-movies/api-service/internal/apiclient/movies"
 	"reviews-movies/api-service/internal/apiclient/reviews"
+	"reviews-movies/api-service/internal/apiclient/search"
+	"reviews-movies/api-service/internal/cache"
+	"reviews-movies/api-service/internal/health"
+	"reviews-movies/api-service/internal/idempotency"
+	"reviews-movies/api-service/internal/importer"
 	"reviews-movies/api-service/internal/kafka"
+	"reviews-movies/api-service/internal/metrics"
+	"reviews-movies/api-service/internal/problem"
+	"reviews-movies/api-service/internal/requestid"
+	"sync"
 )
 
 type Handler struct {
 	logger   *slog.Logger
 	cfg      *config.Config
 	producer *kafka.Producer
+	health   *health.Checker
+	idem     *idempotency.Store
+	upstream *apiclient.BaseClient
+
+	imports   *importer.JobStore
+	importer  *importer.Importer
+	importsWG sync.WaitGroup
 
 	moviesClient  *movies.Client
 	reviewsClient *reviews.Client
+	searchClient  *search.Client
 }
 
 func NewHandler(producer *kafka.Producer, logger *slog.Logger, cfg *config.Config) *Handler {
-	restyCli := apiclient.NewBaseClient(cfg.Services.DataServiceHost, 5)
+	var discovery apiclient.Discovery = apiclient.StaticDiscovery(cfg.Services.DataServiceHosts)
+	if cfg.Services.Discovery == "dns" && len(cfg.Services.DataServiceHosts) > 0 {
+		discovery = apiclient.DNSDiscovery{Template: cfg.Services.DataServiceHosts[0]}
+	}
 
-	moviesCli := movies.NewMovieClient(restyCli)
+	restyCli := apiclient.NewBaseClient(discovery, apiclient.Options{
+		Timeout: cfg.Upstream.Timeout,
+		Retry: apiclient.RetryOptions{
+			MaxAttempts: cfg.Upstream.RetryMaxAttempts,
+			BaseDelay:   cfg.Upstream.RetryBaseDelay,
+			MaxDelay:    cfg.Upstream.RetryMaxDelay,
+		},
+		Breaker: apiclient.BreakerOptions{
+			FailureThreshold:    cfg.Upstream.BreakerFailures,
+			OpenTimeout:         cfg.Upstream.BreakerOpenTimeout,
+			HalfOpenMaxRequests: cfg.Upstream.BreakerHalfOpenRequests,
+		},
+		HedgeDelay: cfg.Upstream.HedgeDelay,
+		Balancer: apiclient.BalancerOptions{
+			Strategy:          cfg.Services.LBStrategy,
+			HealthInterval:    cfg.Services.HealthInterval,
+			DiscoveryInterval: cfg.Services.DiscoveryInterval,
+		},
+	})
+
+	moviesCli := movies.NewMovieClient(restyCli, cache.New("movies", cfg.Cache.Size, cfg.Cache.TTL), cfg.Cache.PendingWindow)
 	reviewsCli := reviews.NewReviewClient(restyCli)
 
+	checker := health.New(cfg.Health.CacheTTL)
+	checker.Add("kafka", producer.CheckMetadata)
+	checker.Add("data_service", restyCli.Ping)
+
 	return &Handler{
 		producer:      producer,
+		health:        checker,
+		idem:          idempotency.NewStore(cfg.Idempotency.TTL),
+		imports:       importer.NewJobStore(cfg.Import.MaxJobs),
+		importer:      importer.New(producer, cfg.Kafka.Topics.Movie, logger),
+		upstream:      restyCli,
 		logger:        logger,
 		cfg:           cfg,
 		moviesClient:  moviesCli,
 		reviewsClient: reviewsCli,
+		searchClient:  search.NewSearchClient(restyCli),
 	}
 }
 
 func (h *Handler) Routes() *gin.Engine {
 	router := gin.New()
+	router.Use(otelgin.Middleware("api-service"))
+	router.Use(requestid.Middleware())
+	router.Use(gin.LoggerWithFormatter(func(p gin.LogFormatterParams) string {
+		h.logger.InfoContext(p.Request.Context(), "request",
+			"method", p.Method, "path", p.Path,
+			"status", fmt.Sprint(p.StatusCode), "latency", p.Latency.String(),
+		)
+		return ""
+	}))
+	router.Use(gin.CustomRecovery(func(c *gin.Context, recovered any) {
+		h.serverError(c, fmt.Errorf("panic: %v", recovered), "the server encountered a problem and could not process the request")
+	}))
+	// Пользовательские методы коллекций вида POST /api/movies:batch gin 1.10
+	// зарегистрировать не может: ':' в пути всегда начинает параметр. Поэтому
+	// они выбираются по точному методу и пути до ответа 404.
+	actions := map[string]gin.HandlerFunc{
+		"POST /api/movies:batch":  idempotency.Handler(h.idem, h.CreateMoviesBatchHandler),
+		"POST /api/reviews:batch": idempotency.Handler(h.idem, h.CreateReviewsBatchHandler),
+	}
+	router.NoRoute(func(c *gin.Context) {
+		if action, ok := actions[c.Request.Method+" "+c.Request.URL.Path]; ok {
+			c.Set(metrics.RouteKey, c.Request.URL.Path)
+			action(c)
+			return
+		}
+		problem.NotFound(c, "the requested resource could not be found")
+	})
+	router.Use(metrics.Middleware())
+
+	router.GET("/metrics", metrics.Handler())
+	router.GET("/healthz", health.LivenessHandler)
+	router.GET("/readyz", h.health.ReadinessHandler)
+
 	api := router.Group("/api")
 	{
+		api.GET("/search", h.SearchHandler)
+
 		movies := api.Group("/movies")
 		{
 			movies.GET("/:id", h.GetMovieByIdHandler)
-			movies.POST("/", h.CreateMovieHandler)
+			movies.GET("/:id/details", h.GetMovieDetailsHandler)
+			movies.GET("/suggest", h.SuggestMoviesHandler)
+			movies.POST("/", idempotency.Middleware(h.idem), h.CreateMovieHandler)
 			movies.PATCH("/:id", h.UpdateMovieHandler)
 			movies.DELETE("/:id", h.DeleteMovieHandler)
+			movies.POST("/:id/restore", h.RestoreMovieHandler)
 			movies.GET("/", h.ListMovieHandler)
 			movies.GET("/by-correlation/:correlation_id", h.GetMovieByCorrelation)
 			movies.GET("/top/", h.GetTopRatedMoviesHandler)
@@ -51,12 +141,19 @@ func (h *Handler) Routes() *gin.Engine {
 			movies.GET("/variance", h.GetControversialMoviesHandler)
 			movies.GET("/avg-rating", h.GetAvgRatingByGenreHandler)
 		}
+		imports := api.Group("/imports")
+		{
+			imports.POST("/", h.CreateImportHandler)
+			imports.GET("/:id", h.GetImportHandler)
+			imports.GET("/:id/errors", h.GetImportErrorsHandler)
+		}
 		reviews := api.Group("/reviews")
 		{
-			reviews.POST("/", h.CreateReviewHandler)
+			reviews.POST("/", idempotency.Middleware(h.idem), h.CreateReviewHandler)
 			reviews.GET("/:id", h.GetReviewByIdHandler)
 			reviews.PATCH("/:id", h.UpdateReviewHandler)
 			reviews.DELETE("/:id", h.DeleteReviewHandler)
+			reviews.POST("/:id/restore", h.RestoreReviewHandler)
 			reviews.GET("/", h.ListReviewHandler)
 			reviews.GET("/by-correlation/:correlation_id", h.GetReviewByCorrelation)
 		}
@@ -64,3 +161,8 @@ func (h *Handler) Routes() *gin.Engine {
 
 	return router
 }
+
+func (h *Handler) Close() {
+	h.importsWG.Wait()
+	h.upstream.Close()
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reviews-movies/api-service/internal/importer"
+	"reviews-movies/api-service/internal/problem"
+	"strings"
+)
+
+// CreateImportHandler принимает каталог фильмов файлом multipart (поле
+// file) или телом запроса и запускает импорт в фоне. Формат задаётся
+// параметром format либо определяется по имени файла или Content-Type.
+func (h *Handler) CreateImportHandler(c *gin.Context) {
+	mapping, err := importer.ParseMapping(c.Query("mapping"))
+	if err != nil {
+		problem.BadRequest(c, err.Error())
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.cfg.Import.MaxBytes)
+
+	var (
+		src      io.Reader = c.Request.Body
+		filename string
+	)
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, header, err := c.Request.FormFile("file")
+		if err != nil {
+			h.importBodyError(c, err)
+			return
+		}
+		defer file.Close()
+		src, filename = file, header.Filename
+	}
+
+	format, err := importFormat(c.Query("format"), filename, c.ContentType())
+	if err != nil {
+		problem.BadRequest(c, err.Error())
+		return
+	}
+
+	// Тело запроса закроется вместе с ответом, а импорт идёт в фоне,
+	// поэтому источник сохраняется во временный файл.
+	tmp, err := os.CreateTemp("", "import-*."+string(format))
+	if err != nil {
+		h.serverError(c, err, "failed to store the uploaded catalog")
+		return
+	}
+	size, err := io.Copy(tmp, src)
+	if err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		h.importBodyError(c, err)
+		return
+	}
+
+	job := h.imports.Create(format, size)
+	opts := importer.Options{
+		Format:          format,
+		Mapping:         mapping,
+		GenreSeparators: c.Query("genre_separators"),
+	}
+	ctx := context.WithoutCancel(c.Request.Context())
+
+	h.importsWG.Add(1)
+	go func() {
+		defer h.importsWG.Done()
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+
+		if err := h.importer.Run(ctx, job, tmp, opts); err != nil {
+			h.logger.ErrorContext(ctx, "import failed", "job", job.ID(), "error", err)
+		}
+		h.moviesClient.PendingMovies()
+	}()
+
+	c.Header("Location", "/api/imports/"+job.ID())
+	c.JSON(http.StatusAccepted, gin.H{"job": job.View()})
+}
+
+func (h *Handler) GetImportHandler(c *gin.Context) {
+	job, ok := h.imports.Get(c.Param("id"))
+	if !ok {
+		problem.NotFound(c, "import job not found")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"job": job.View()})
+}
+
+// GetImportErrorsHandler отдаёт отчёт об ошибках строк в CSV.
+func (h *Handler) GetImportErrorsHandler(c *gin.Context) {
+	job, ok := h.imports.Get(c.Param("id"))
+	if !ok {
+		problem.NotFound(c, "import job not found")
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="import-`+job.ID()+`-errors.csv"`)
+	c.Status(http.StatusOK)
+	if err := job.WriteErrorReport(c.Writer); err != nil {
+		h.logger.ErrorContext(c.Request.Context(), "failed to write import error report", "job", job.ID(), "error", err)
+	}
+}
+
+func (h *Handler) importBodyError(c *gin.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		problem.Write(c, problem.New(http.StatusRequestEntityTooLarge, problem.CodeInvalidRequest, "the uploaded catalog is too large"))
+		return
+	}
+	problem.BadRequest(c, "unable to read the uploaded catalog")
+}
+
+func importFormat(param, filename, contentType string) (importer.Format, error) {
+	if param != "" {
+		return importer.ParseFormat(param)
+	}
+	if ext := strings.TrimPrefix(filepath.Ext(filename), "."); ext != "" {
+		return importer.ParseFormat(ext)
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		switch mediaType {
+		case "text/csv":
+			return importer.CSV, nil
+		case "application/x-ndjson", "application/jsonl":
+			return importer.JSONL, nil
+		}
+	}
+	return importer.ParseFormat(param)
+}
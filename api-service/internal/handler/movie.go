@@ -4,40 +4,37 @@ import (
 	"encoding/json"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"io"
 	"net/http"
+	"reviews-movies/api-service/internal/apiclient/movies"
+	"reviews-movies/api-service/internal/data"
+	"reviews-movies/api-service/internal/problem"
 	"strconv"
 	"time"
 )
 
 func (h *Handler) CreateMovieHandler(c *gin.Context) {
-	var input struct {
-		Title         string    `json:"title"`
-		Year          int32     `json:"year"`
-		Runtime       int32     `json:"runtime"`
-		Genres        []string  `json:"genres"`
-		CorrelationId uuid.UUID `json:"correlation_id" binding:"-"`
-	}
+	var input data.MovieInput
 
 	input.CorrelationId = uuid.New()
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+		problem.BadRequest(c, "invalid input data")
 		return
 	}
 
 	msgBytes, err := json.Marshal(input)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to serialize message"})
+		h.serverError(c, err, "failed to serialize message")
 		return
 	}
-	h.logger.Debug(input.Title, nil)
+	h.logger.DebugContext(c.Request.Context(), "produce movie", "title", input.Title)
 
-	err = h.producer.Produce(string(msgBytes), h.cfg.Kafka.Topics.Movie, input.CorrelationId.String(), time.Now())
+	err = h.producer.Produce(c.Request.Context(), string(msgBytes), h.cfg.Kafka.Topics.Movie, input.CorrelationId.String(), time.Now())
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		h.produceError(c, err)
 		return
 	}
+	h.moviesClient.PendingMovies()
 
 	c.JSON(http.StatusOK, gin.H{"status": "ok", "correlation_id": input.CorrelationId})
 }
@@ -45,118 +42,199 @@ func (h *Handler) CreateMovieHandler(c *gin.Context) {
 func (h *Handler) GetMovieByCorrelation(c *gin.Context) {
 	corrID, err := uuid.Parse(c.Param("correlation_id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid correlation_id"})
+		problem.BadRequest(c, "invalid correlation_id")
 		return
 	}
-	body, status, contentType, err := h.moviesClient.GetByCorrelationID(c, corrID.String())
+	movie, err := h.moviesClient.GetByCorrelationID(c.Request.Context(), corrID.String())
 	if err != nil {
-		h.logger.Error("ERROR: ", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "¯\\_(ツ)_/¯"})
+		h.upstreamError(c, err)
+		return
+	}
+	if notModified(c, etag(movie.Version)) {
 		return
 	}
-	c.Data(status, contentType, body)
+	c.JSON(http.StatusOK, gin.H{"movie": movie})
 }
 
 func (h *Handler) GetMovieByIdHandler(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil || id <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		problem.BadRequest(c, "invalid id")
+		return
+	}
+
+	if fs := queryFieldset(c); fs.Sparse() {
+		movie, err := h.moviesClient.GetSparseByID(c.Request.Context(), id, fs)
+		if err != nil {
+			h.upstreamError(c, err)
+			return
+		}
+		// Встроенные связи меняются без изменения версии фильма.
+		if fs.Include == "" && notModified(c, fieldsETag(movie.Version(), fs.Fields)) {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"movie": movie})
 		return
 	}
 
-	body, status, contentType, err := h.moviesClient.GetByID(c, id)
+	movie, err := h.moviesClient.GetByID(c.Request.Context(), id)
 	if err != nil {
-		h.logger.Error("ERROR: ", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "¯\\_(ツ)_/¯"})
+		h.upstreamError(c, err)
+		return
+	}
+	if notModified(c, etag(movie.Version)) {
 		return
 	}
-	c.Data(status, contentType, body)
+	c.JSON(http.StatusOK, gin.H{"movie": movie})
 }
 
 func (h *Handler) UpdateMovieHandler(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil || id <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		problem.BadRequest(c, "invalid id")
 		return
 	}
-	payload, _ := io.ReadAll(c.Request.Body)
 
-	body, status, contentType, err := h.moviesClient.UpdateMovie(c, id, payload, nil)
+	var input movies.MovieUpdate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		problem.BadRequest(c, "invalid input data")
+		return
+	}
 
-	c.Data(status, contentType, body)
+	movie, err := h.moviesClient.UpdateMovie(c.Request.Context(), id, input, c.GetHeader("If-Match"))
+	// Кэш сбрасывается и при ошибке: изменение могло примениться, даже если
+	// ответ до шлюза не дошёл.
+	h.moviesClient.InvalidateMovie(id)
+	if err != nil {
+		h.upstreamError(c, err)
+		return
+	}
+	c.Header("ETag", etag(movie.Version))
+	c.JSON(http.StatusOK, gin.H{"movie": movie})
 }
 
 func (h *Handler) DeleteMovieHandler(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil || id <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		problem.BadRequest(c, "invalid id")
+		return
+	}
+
+	err = h.moviesClient.DeleteMovie(c.Request.Context(), id, c.GetHeader("If-Match"))
+	h.moviesClient.InvalidateMovie(id)
+	if err != nil {
+		h.upstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Operation delete successfully completed"})
+}
+
+func (h *Handler) RestoreMovieHandler(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil || id <= 0 {
+		problem.BadRequest(c, "invalid id")
 		return
 	}
 
-	body, status, contentType, err := h.moviesClient.DeleteMovie(c, id)
+	movie, err := h.moviesClient.RestoreMovie(c.Request.Context(), id)
+	// С фильмом возвращаются и его рецензии, поэтому сбрасывается и
+	// статистика.
+	h.moviesClient.InvalidateMovie(id)
 	if err != nil {
-		h.logger.Error("ERROR: ", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "¯\\_(ツ)_/¯"})
+		h.upstreamError(c, err)
 		return
 	}
-	c.Data(status, contentType, body)
+	c.Header("ETag", etag(movie.Version))
+	c.JSON(http.StatusOK, gin.H{"movie": movie})
 }
 
 func (h *Handler) ListMovieHandler(c *gin.Context) {
-	title := c.Query("title")
-	genres := c.QueryArray("genres")
-	sort := c.Query("sort")
-	page := c.Query("page")
-	pageSize := c.Query("page_size")
+	params := movies.ListParams{
+		Title:  c.Query("title"),
+		Genres: c.QueryArray("genres"),
+		Filter: c.Query("filter"),
+		Sort:   c.Query("sort"),
+		After:  c.Query("after"),
+		Before: c.Query("before"),
+		Count:  c.Query("count"),
+	}
+
+	var ok bool
+	if params.Page, ok = queryInt(c, "page"); !ok {
+		return
+	}
+	if params.PageSize, ok = queryInt(c, "page_size"); !ok {
+		return
+	}
+
+	if fs := queryFieldset(c); fs.Sparse() {
+		list, err := h.moviesClient.ListMoviesSparse(c.Request.Context(), params, fs)
+		if err != nil {
+			h.upstreamError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, list)
+		return
+	}
+
+	list, err := h.moviesClient.ListMovies(c.Request.Context(), params)
+	if err != nil {
+		h.upstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+func (h *Handler) SuggestMoviesHandler(c *gin.Context) {
+	limit, ok := queryInt(c, "limit")
+	if !ok {
+		return
+	}
 
-	body, status, contentType, err := h.moviesClient.GetListMovie(c, title, sort, page, pageSize, genres)
+	res, err := h.moviesClient.Suggest(c.Request.Context(), c.Query("prefix"), limit)
 	if err != nil {
-		h.logger.Error("ERROR: ", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "¯\\_(ツ)_/¯"})
+		h.upstreamError(c, err)
 		return
 	}
-	c.Data(status, contentType, body)
+	c.JSON(http.StatusOK, res)
 }
 
 func (h *Handler) GetTopRatedMoviesHandler(c *gin.Context) {
-	body, status, contentType, err := h.moviesClient.GetTopRatedMovies(c)
+	res, err := h.moviesClient.GetTopRatedMovies(c.Request.Context())
 	if err != nil {
-		h.logger.Error("ERROR: ", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "¯\\_(ツ)_/¯"})
+		h.upstreamError(c, err)
 		return
 	}
-	c.Data(status, contentType, body)
+	c.JSON(http.StatusOK, gin.H{"top_movies": res})
 }
 
 func (h *Handler) GetWithoutReviewsHandler(c *gin.Context) {
-	body, status, contentType, err := h.moviesClient.GetWithoutReviews(c)
+	res, err := h.moviesClient.GetWithoutReviews(c.Request.Context())
 	if err != nil {
-		h.logger.Error("ERROR: ", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "¯\\_(ツ)_/¯"})
+		h.upstreamError(c, err)
 		return
 	}
-	c.Data(status, contentType, body)
+	c.JSON(http.StatusOK, gin.H{"movies_without_reviews": res})
 }
 
 func (h *Handler) GetControversialMoviesHandler(c *gin.Context) {
-	body, status, contentType, err := h.moviesClient.GetControversialMovies(c)
+	res, err := h.moviesClient.GetControversialMovies(c.Request.Context())
 	if err != nil {
-		h.logger.Error("ERROR: ", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "¯\\_(ツ)_/¯"})
+		h.upstreamError(c, err)
 		return
 	}
-	c.Data(status, contentType, body)
+	c.JSON(http.StatusOK, gin.H{"movies_variance": res})
 }
 
 func (h *Handler) GetAvgRatingByGenreHandler(c *gin.Context) {
-	body, status, contentType, err := h.moviesClient.GetAvgRatingByGenre(c)
+	res, err := h.moviesClient.GetAvgRatingByGenre(c.Request.Context())
 	if err != nil {
-		h.logger.Error("ERROR: ", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "¯\\_(ツ)_/¯"})
+		h.upstreamError(c, err)
 		return
 	}
-	c.Data(status, contentType, body)
+	c.JSON(http.StatusOK, gin.H{"rating_average": res})
 }
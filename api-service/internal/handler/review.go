@@ -4,42 +4,39 @@ import (
 	"encoding/json"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"io"
 	"net/http"
+	"reviews-movies/api-service/internal/apiclient/reviews"
+	"reviews-movies/api-service/internal/data"
+	"reviews-movies/api-service/internal/problem"
 	"strconv"
 	"time"
 )
 
 func (h *Handler) CreateReviewHandler(c *gin.Context) {
-	var input struct {
-		CorrelationId uuid.UUID `json:"correlation_id" binding:"-"`
-		MovieId       uint      `json:"movie_id"`
-		Rating        float32   `json:"rating"`
-		Comment       string    `json:"comment"`
-		Author        string    `json:"author"`
-	}
+	var input data.ReviewInput
 
 	input.CorrelationId = uuid.New()
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+		problem.BadRequest(c, "invalid input data")
 		return
 	}
 
 	msgBytes, err := json.Marshal(input)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to serialize message"})
+		h.serverError(c, err, "failed to serialize message")
 		return
 	}
-	h.logger.Debug(input.CorrelationId.String(), nil)
+	h.logger.DebugContext(c.Request.Context(), "produce review", "correlation_id", input.CorrelationId.String())
 
 	key := strconv.Itoa(int(input.MovieId))
 
-	err = h.producer.Produce(string(msgBytes), h.cfg.Kafka.Topics.Review, key, time.Now())
+	err = h.producer.Produce(c.Request.Context(), string(msgBytes), h.cfg.Kafka.Topics.Review, key, time.Now())
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		h.produceError(c, err)
 		return
 	}
+	h.moviesClient.PendingReviews(uint64(input.MovieId))
 
 	c.JSON(http.StatusOK, gin.H{"status": "ok", "correlation_id": input.CorrelationId})
 }
@@ -48,84 +45,178 @@ func (h *Handler) GetReviewByIdHandler(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil || id <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		problem.BadRequest(c, "invalid id")
 		return
 	}
 
-	body, status, contentType, err := h.reviewsClient.GetByID(c, id)
+	if fs := queryFieldset(c); fs.Sparse() {
+		review, err := h.reviewsClient.GetSparseByID(c.Request.Context(), id, fs)
+		if err != nil {
+			h.upstreamError(c, err)
+			return
+		}
+		if notModified(c, fieldsETag(review.Version(), fs.Fields)) {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"review": review})
+		return
+	}
+
+	review, err := h.reviewsClient.GetByID(c.Request.Context(), id)
 	if err != nil {
-		h.logger.Error("ERROR: ", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "¯\\_(ツ)_/¯"})
+		h.upstreamError(c, err)
 		return
 	}
-	c.Data(status, contentType, body)
+	if notModified(c, etag(review.Version)) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"review": review})
 }
 
 func (h *Handler) GetReviewByCorrelation(c *gin.Context) {
 	corrID, err := uuid.Parse(c.Param("correlation_id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid correlation_id"})
+		problem.BadRequest(c, "invalid correlation_id")
 		return
 	}
 
-	body, status, contentType, err := h.reviewsClient.GetByCorrelationID(c, corrID.String())
+	review, err := h.reviewsClient.GetByCorrelationID(c.Request.Context(), corrID.String())
 	if err != nil {
-		h.logger.Error("ERROR: ", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "¯\\_(ツ)_/¯"})
+		h.upstreamError(c, err)
+		return
+	}
+	if notModified(c, etag(review.Version)) {
 		return
 	}
-	c.Data(status, contentType, body)
+	c.JSON(http.StatusOK, gin.H{"review": review})
 }
 
 func (h *Handler) UpdateReviewHandler(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil || id <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		problem.BadRequest(c, "invalid id")
 		return
 	}
 
-	payload, _ := io.ReadAll(c.Request.Body)
+	var input reviews.ReviewUpdate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		problem.BadRequest(c, "invalid input data")
+		return
+	}
 
-	body, status, contentType, err := h.reviewsClient.UpdateReview(c, id, payload, nil)
+	review, err := h.reviewsClient.UpdateReview(c.Request.Context(), id, input, c.GetHeader("If-Match"))
 	if err != nil {
-		h.logger.Error("ERROR: ", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "¯\\_(ツ)_/¯"})
+		h.moviesClient.InvalidateReviews(0)
+		h.upstreamError(c, err)
 		return
 	}
-	c.Data(status, contentType, body)
+	h.moviesClient.InvalidateReviews(uint64(review.MovieId))
+	c.Header("ETag", etag(review.Version))
+	c.JSON(http.StatusOK, gin.H{"review": review})
 }
 
 func (h *Handler) DeleteReviewHandler(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil || id <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		problem.BadRequest(c, "invalid id")
 		return
 	}
 
-	body, status, contentType, err := h.reviewsClient.DeleteReview(c, id)
+	err = h.reviewsClient.DeleteReview(c.Request.Context(), id, c.GetHeader("If-Match"))
+	h.moviesClient.InvalidateReviews(0)
 	if err != nil {
-		h.logger.Error("ERROR: ", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "¯\\_(ツ)_/¯"})
+		h.upstreamError(c, err)
 		return
 	}
-	c.Data(status, contentType, body)
+	c.JSON(http.StatusOK, gin.H{"message": "Operation delete successfully completed"})
+}
+
+func (h *Handler) RestoreReviewHandler(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil || id <= 0 {
+		problem.BadRequest(c, "invalid id")
+		return
+	}
+
+	review, err := h.reviewsClient.RestoreReview(c.Request.Context(), id)
+	if err != nil {
+		h.moviesClient.InvalidateReviews(0)
+		h.upstreamError(c, err)
+		return
+	}
+	h.moviesClient.InvalidateReviews(uint64(review.MovieId))
+	c.Header("ETag", etag(review.Version))
+	c.JSON(http.StatusOK, gin.H{"review": review})
 }
 
 func (h *Handler) ListReviewHandler(c *gin.Context) {
-	movieID := c.Query("movie_id")
-	author := c.Query("author")
-	rating := c.Query("rating")
-	page := c.Query("page")
-	pageSize := c.Query("page_size")
-	sort := c.Query("sort")
-
-	body, status, contentType, err := h.reviewsClient.GetListReview(c, movieID, author, rating, sort, page, pageSize)
+	params := reviews.ListParams{
+		Author:      c.Query("author"),
+		AuthorMatch: c.Query("author_match"),
+		CreatedFrom: c.Query("created_from"),
+		CreatedTo:   c.Query("created_to"),
+		Query:       c.Query("q"),
+		Lang:        c.Query("lang"),
+		Filter:      c.Query("filter"),
+		Sort:        c.Query("sort"),
+		After:       c.Query("after"),
+		Before:      c.Query("before"),
+		Count:       c.Query("count"),
+	}
+
+	if movieID := c.Query("movie_id"); movieID != "" {
+		id, err := strconv.ParseUint(movieID, 10, 32)
+		if err != nil {
+			problem.BadRequest(c, "invalid movie_id")
+			return
+		}
+		params.MovieID = id
+	}
+	for _, p := range []struct {
+		name string
+		dst  *float64
+	}{
+		{"rating", &params.Rating},
+		{"rating_min", &params.RatingMin},
+		{"rating_max", &params.RatingMax},
+	} {
+		value := c.Query(p.name)
+		if value == "" {
+			continue
+		}
+		r, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			problem.BadRequest(c, "invalid "+p.name)
+			return
+		}
+		*p.dst = r
+	}
+
+	var ok bool
+	if params.Page, ok = queryInt(c, "page"); !ok {
+		return
+	}
+	if params.PageSize, ok = queryInt(c, "page_size"); !ok {
+		return
+	}
+
+	if fs := queryFieldset(c); fs.Sparse() {
+		list, err := h.reviewsClient.ListReviewsSparse(c.Request.Context(), params, fs)
+		if err != nil {
+			h.upstreamError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, list)
+		return
+	}
+
+	list, err := h.reviewsClient.ListReviews(c.Request.Context(), params)
 	if err != nil {
-		h.logger.Error("ERROR: ", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "¯\\_(ツ)_/¯"})
+		h.upstreamError(c, err)
 		return
 	}
-	c.Data(status, contentType, body)
+	c.JSON(http.StatusOK, list)
 }
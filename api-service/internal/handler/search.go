@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"reviews-movies/api-service/internal/apiclient/search"
+)
+
+func (h *Handler) SearchHandler(c *gin.Context) {
+	params := search.Params{
+		Query: c.Query("q"),
+		Lang:  c.Query("lang"),
+		Types: c.QueryArray("type"),
+	}
+
+	var ok bool
+	if params.Page, ok = queryInt(c, "page"); !ok {
+		return
+	}
+	if params.PageSize, ok = queryInt(c, "page_size"); !ok {
+		return
+	}
+
+	res, err := h.searchClient.Search(c.Request.Context(), params)
+	if err != nil {
+		h.upstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,186 @@
+// Package idempotency реализует заголовок Idempotency-Key для POST-запросов
+// шлюза: повтор с тем же ключом и телом получает сохранённый ответ вместо
+// повторной публикации сообщения.
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"reviews-movies/api-service/internal/metrics"
+	"reviews-movies/api-service/internal/problem"
+	"sync"
+	"time"
+)
+
+const (
+	Header         = "Idempotency-Key"
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxKeyLength  = 255
+	sweepInterval = time.Minute
+)
+
+type record struct {
+	hash        string
+	done        bool
+	status      int
+	contentType string
+	body        []byte
+	expires     time.Time
+}
+
+// Store хранит ключи и ответы в памяти процесса в течение ttl.
+type Store struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	records   map[string]*record
+	lastSweep time.Time
+}
+
+func NewStore(ttl time.Duration) *Store {
+	return &Store{
+		ttl:     ttl,
+		records: make(map[string]*record),
+	}
+}
+
+// begin резервирует ключ. Если ключ уже есть, возвращает его запись и false.
+func (s *Store) begin(key, hash string) (record, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) > sweepInterval {
+		for k, r := range s.records {
+			if r.done && now.After(r.expires) {
+				delete(s.records, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	if r, ok := s.records[key]; ok && (!r.done || now.Before(r.expires)) {
+		return *r, false
+	}
+
+	s.records[key] = &record{hash: hash}
+	return record{}, true
+}
+
+func (s *Store) complete(key string, status int, contentType string, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[key]
+	if !ok {
+		return
+	}
+	r.done = true
+	r.status = status
+	r.contentType = contentType
+	r.body = body
+	r.expires = time.Now().Add(s.ttl)
+}
+
+// release снимает резервирование, чтобы клиент мог повторить запрос.
+func (s *Store) release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+}
+
+type capturingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *capturingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *capturingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Middleware применяет Idempotency-Key к маршруту. Запросы без заголовка
+// проходят как есть. Ответ сохраняется, если он не 5xx: после сбоя клиент
+// должен иметь возможность повторить запрос с тем же ключом.
+func Middleware(store *Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		store.serve(c, c.Next)
+	}
+}
+
+// Handler применяет Idempotency-Key к одному обработчику. Он нужен для
+// маршрутов, которые выбираются вне дерева gin, где c.Next не дойдёт до
+// обработчика.
+func Handler(store *Store, h gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		store.serve(c, func() { h(c) })
+	}
+}
+
+func (s *Store) serve(c *gin.Context, next func()) {
+	key := c.GetHeader(Header)
+	if key == "" {
+		next()
+		return
+	}
+	if len(key) > maxKeyLength {
+		problem.BadRequest(c, "Idempotency-Key must not be longer than 255 characters")
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		problem.BadRequest(c, "unable to read request body")
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	sum := sha256.New()
+	sum.Write([]byte(c.Request.Method + " " + metrics.Route(c) + "\n"))
+	sum.Write(body)
+	hash := hex.EncodeToString(sum.Sum(nil))
+
+	if prev, ok := s.begin(key, hash); !ok {
+		switch {
+		case prev.hash != hash:
+			problem.Write(c, problem.New(http.StatusUnprocessableEntity, problem.CodeIdempotencyKeyReused,
+				"Idempotency-Key has already been used with a different request"))
+		case !prev.done:
+			problem.Write(c, problem.New(http.StatusConflict, problem.CodeRequestInProgress,
+				"a request with this Idempotency-Key is still being processed"))
+		default:
+			metrics.IdempotentReplays.WithLabelValues(metrics.Route(c)).Inc()
+			c.Header(ReplayedHeader, "true")
+			c.Data(prev.status, prev.contentType, prev.body)
+			c.Abort()
+		}
+		return
+	}
+
+	w := &capturingWriter{ResponseWriter: c.Writer}
+	c.Writer = w
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			s.release(key)
+			panic(recovered)
+		}
+	}()
+	next()
+
+	if w.Status() >= http.StatusInternalServerError {
+		s.release(key)
+		return
+	}
+	s.complete(key, w.Status(), w.Header().Get("Content-Type"), w.body.Bytes())
+}
@@ -0,0 +1,233 @@
+package idempotency
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reviews-movies/api-service/internal/problem"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestRouter регистрирует обработчик под Middleware и под Handler:
+// POST /items и POST /other отвечают 201 с телом запроса и номером вызова,
+// POST /fail — 500.
+func newTestRouter(t *testing.T, store *Store, calls *atomic.Int32, block <-chan struct{}) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	create := func(c *gin.Context) {
+		n := calls.Add(1)
+		if block != nil {
+			<-block
+		}
+		body, _ := io.ReadAll(c.Request.Body)
+		c.JSON(http.StatusCreated, gin.H{"call": n, "body": string(body)})
+	}
+
+	router := gin.New()
+	router.POST("/items", Middleware(store), create)
+	router.POST("/other", Middleware(store), create)
+	router.POST("/fail", Middleware(store), func(c *gin.Context) {
+		calls.Add(1)
+		problem.Internal(c, "boom")
+	})
+	router.NoRoute(func(c *gin.Context) {
+		if c.Request.URL.Path == "/wrapped" {
+			Handler(store, create)(c)
+		}
+	})
+	return router
+}
+
+func post(router http.Handler, path, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(Header, key)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	type request struct {
+		path, key, body string
+		status          int
+		code            string
+		replayed        bool
+	}
+
+	tests := []struct {
+		name     string
+		requests []request
+		calls    int32
+	}{
+		{
+			name: "no key",
+			requests: []request{
+				{path: "/items", body: `{"a":1}`, status: http.StatusCreated},
+				{path: "/items", body: `{"a":1}`, status: http.StatusCreated},
+			},
+			calls: 2,
+		},
+		{
+			name: "replay",
+			requests: []request{
+				{path: "/items", key: "k1", body: `{"a":1}`, status: http.StatusCreated},
+				{path: "/items", key: "k1", body: `{"a":1}`, status: http.StatusCreated, replayed: true},
+				{path: "/items", key: "k1", body: `{"a":1}`, status: http.StatusCreated, replayed: true},
+			},
+			calls: 1,
+		},
+		{
+			name: "replay through Handler",
+			requests: []request{
+				{path: "/wrapped", key: "k1", body: `{"a":1}`, status: http.StatusCreated},
+				{path: "/wrapped", key: "k1", body: `{"a":1}`, status: http.StatusCreated, replayed: true},
+			},
+			calls: 1,
+		},
+		{
+			name: "different body",
+			requests: []request{
+				{path: "/items", key: "k1", body: `{"a":1}`, status: http.StatusCreated},
+				{path: "/items", key: "k1", body: `{"a":2}`, status: http.StatusUnprocessableEntity, code: problem.CodeIdempotencyKeyReused},
+			},
+			calls: 1,
+		},
+		{
+			name: "different route",
+			requests: []request{
+				{path: "/items", key: "k1", body: `{"a":1}`, status: http.StatusCreated},
+				{path: "/other", key: "k1", body: `{"a":1}`, status: http.StatusUnprocessableEntity, code: problem.CodeIdempotencyKeyReused},
+			},
+			calls: 1,
+		},
+		{
+			name: "different keys",
+			requests: []request{
+				{path: "/items", key: "k1", body: `{"a":1}`, status: http.StatusCreated},
+				{path: "/items", key: "k2", body: `{"a":1}`, status: http.StatusCreated},
+			},
+			calls: 2,
+		},
+		{
+			name: "server error is not stored",
+			requests: []request{
+				{path: "/fail", key: "k1", body: `{}`, status: http.StatusInternalServerError},
+				{path: "/fail", key: "k1", body: `{}`, status: http.StatusInternalServerError},
+			},
+			calls: 2,
+		},
+		{
+			name: "key too long",
+			requests: []request{
+				{path: "/items", key: strings.Repeat("k", maxKeyLength+1), body: `{}`, status: http.StatusBadRequest, code: problem.CodeInvalidRequest},
+			},
+			calls: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			router := newTestRouter(t, NewStore(time.Minute), &calls, nil)
+
+			var first string
+			for i, r := range tt.requests {
+				rec := post(router, r.path, r.key, r.body)
+				if rec.Code != r.status {
+					t.Fatalf("request %d: status = %d, want %d; body %s", i, rec.Code, r.status, rec.Body)
+				}
+				if got := rec.Header().Get(ReplayedHeader) == "true"; got != r.replayed {
+					t.Errorf("request %d: replayed = %v, want %v", i, got, r.replayed)
+				}
+				if r.code != "" {
+					var p problem.Problem
+					if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil || p.Code != r.code {
+						t.Errorf("request %d: problem code = %q (%v), want %q", i, p.Code, err, r.code)
+					}
+				}
+				if i == 0 {
+					first = rec.Body.String()
+				} else if r.replayed && rec.Body.String() != first {
+					t.Errorf("request %d: replayed body = %s, want %s", i, rec.Body, first)
+				}
+			}
+			if n := calls.Load(); n != tt.calls {
+				t.Errorf("handler calls = %d, want %d", n, tt.calls)
+			}
+		})
+	}
+}
+
+func TestIdempotencyKeyConcurrentDuplicates(t *testing.T) {
+	var calls atomic.Int32
+	block := make(chan struct{})
+	router := newTestRouter(t, NewStore(time.Minute), &calls, block)
+
+	first := make(chan *httptest.ResponseRecorder, 1)
+	go func() {
+		first <- post(router, "/items", "k1", `{"a":1}`)
+	}()
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Пока первый запрос выполняется, дубликаты получают 409.
+	const duplicates = 5
+	var wg sync.WaitGroup
+	for range duplicates {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := post(router, "/items", "k1", `{"a":1}`)
+			var p problem.Problem
+			json.Unmarshal(rec.Body.Bytes(), &p)
+			if rec.Code != http.StatusConflict || p.Code != problem.CodeRequestInProgress {
+				t.Errorf("duplicate: status = %d, code = %q, want 409 %s", rec.Code, p.Code, problem.CodeRequestInProgress)
+			}
+		}()
+	}
+	wg.Wait()
+
+	close(block)
+	if rec := <-first; rec.Code != http.StatusCreated {
+		t.Fatalf("first: status = %d, want 201", rec.Code)
+	}
+
+	rec := post(router, "/items", "k1", `{"a":1}`)
+	if rec.Code != http.StatusCreated || rec.Header().Get(ReplayedHeader) != "true" {
+		t.Errorf("after completion: status = %d, replayed = %q, want a replay", rec.Code, rec.Header().Get(ReplayedHeader))
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("handler calls = %d, want 1", n)
+	}
+}
+
+func TestIdempotencyKeyExpires(t *testing.T) {
+	var calls atomic.Int32
+	store := NewStore(time.Minute)
+	router := newTestRouter(t, store, &calls, nil)
+
+	post(router, "/items", "k1", `{"a":1}`)
+
+	store.mu.Lock()
+	store.records["k1"].expires = time.Now().Add(-time.Second)
+	store.mu.Unlock()
+
+	rec := post(router, "/items", "k1", `{"a":2}`)
+	if rec.Code != http.StatusCreated || rec.Header().Get(ReplayedHeader) != "" {
+		t.Errorf("status = %d, replayed = %q, want a fresh request", rec.Code, rec.Header().Get(ReplayedHeader))
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("handler calls = %d, want 2", n)
+	}
+}
@@ -0,0 +1,197 @@
+// Package importer загружает каталоги фильмов из CSV и JSONL: сопоставляет
+// колонки с полями, нормализует жанры, проверяет строки по правилам
+// data.ValidateMovie и публикует верные в Kafka пачками.
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"io"
+	"log/slog"
+	"reviews-movies/api-service/internal/data"
+	"reviews-movies/api-service/internal/kafka"
+	"reviews-movies/api-service/internal/metrics"
+	"reviews-movies/api-service/internal/validator"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultBatchSize        = 500
+	DefaultGenreSeparators  = "|,;"
+	progressUpdateThreshold = 64 * 1024
+)
+
+// Publisher — часть kafka.Producer, нужная импорту.
+type Publisher interface {
+	ProduceBatch(ctx context.Context, topic string, messages []kafka.Message, tn time.Time) []error
+}
+
+type Options struct {
+	Format          Format
+	Mapping         Mapping
+	GenreSeparators string
+}
+
+type Importer struct {
+	publisher Publisher
+	topic     string
+	batchSize int
+	logger    *slog.Logger
+}
+
+func New(publisher Publisher, topic string, logger *slog.Logger) *Importer {
+	return &Importer{
+		publisher: publisher,
+		topic:     topic,
+		batchSize: defaultBatchSize,
+		logger:    logger,
+	}
+}
+
+type pendingRow struct {
+	line  int
+	input data.MovieInput
+}
+
+// Run читает источник до конца и заполняет job. Ошибки отдельных строк
+// попадают в отчёт задания; Run возвращает ошибку, только если источник
+// не удалось дочитать.
+func (im *Importer) Run(ctx context.Context, job *Job, r io.Reader, opts Options) error {
+	if opts.GenreSeparators == "" {
+		opts.GenreSeparators = DefaultGenreSeparators
+	}
+
+	job.setStatus(StatusRunning)
+
+	counter := &countingReader{r: r}
+	src, err := newSource(opts.Format, counter)
+	if err != nil {
+		job.fail(err)
+		return err
+	}
+
+	var (
+		batch    []pendingRow
+		lastRead int64
+	)
+	for {
+		if err := ctx.Err(); err != nil {
+			job.fail(err)
+			return err
+		}
+
+		rec, err := src.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			job.fail(err)
+			return err
+		}
+
+		if read := counter.n.Load(); read-lastRead >= progressUpdateThreshold {
+			job.progress(read)
+			lastRead = read
+		}
+
+		if rec.err != nil {
+			im.reject(job, rec.line, map[string]string{"row": rec.err.Error()})
+			continue
+		}
+
+		input, errs := convert(rec.values, opts)
+		if errs != nil {
+			im.reject(job, rec.line, errs)
+			continue
+		}
+
+		batch = append(batch, pendingRow{line: rec.line, input: input})
+		if len(batch) >= im.batchSize {
+			im.publish(ctx, job, batch)
+			batch = batch[:0]
+		}
+	}
+	im.publish(ctx, job, batch)
+
+	job.complete()
+	return nil
+}
+
+func (im *Importer) publish(ctx context.Context, job *Job, rows []pendingRow) {
+	if len(rows) == 0 {
+		return
+	}
+
+	var (
+		messages = make([]kafka.Message, 0, len(rows))
+		sent     = make([]pendingRow, 0, len(rows))
+	)
+	for _, row := range rows {
+		value, err := json.Marshal(row.input)
+		if err != nil {
+			im.reject(job, row.line, map[string]string{"row": "failed to serialize"})
+			continue
+		}
+		messages = append(messages, kafka.Message{Key: row.input.CorrelationId.String(), Value: value})
+		sent = append(sent, row)
+	}
+
+	accepted := 0
+	for i, err := range im.publisher.ProduceBatch(ctx, im.topic, messages, time.Now()) {
+		if err != nil {
+			im.logger.ErrorContext(ctx, "failed to publish imported movie", "job", job.ID(), "row", sent[i].line, "error", err)
+			im.reject(job, sent[i].line, map[string]string{"row": "failed to publish, please retry"})
+			continue
+		}
+		accepted++
+	}
+	job.accept(accepted)
+	metrics.ImportRows.WithLabelValues("accepted").Add(float64(accepted))
+}
+
+func (im *Importer) reject(job *Job, line int, errs map[string]string) {
+	job.reject(line, errs)
+	metrics.ImportRows.WithLabelValues("rejected").Inc()
+}
+
+// convert собирает MovieInput из значений строки и проверяет его. Ошибки
+// разбора чисел и правил ValidateMovie возвращаются вместе.
+func convert(values map[string]any, opts Options) (data.MovieInput, map[string]string) {
+	v := validator.New()
+
+	input := data.MovieInput{
+		Title:         stringValue(values[opts.Mapping.column("title")]),
+		Genres:        NormalizeGenres(listValue(values[opts.Mapping.column("genres")], opts.GenreSeparators)),
+		CorrelationId: uuid.New(),
+	}
+	if s := stringValue(values[opts.Mapping.column("year")]); s != "" {
+		year, err := strconv.ParseInt(s, 10, 32)
+		v.Check(err == nil, "year", "must be an integer")
+		input.Year = int32(year)
+	}
+	if s := stringValue(values[opts.Mapping.column("runtime")]); s != "" {
+		runtime, err := strconv.ParseInt(s, 10, 32)
+		v.Check(err == nil, "runtime", "must be an integer")
+		input.Runtime = int32(runtime)
+	}
+
+	if data.ValidateMovie(v, &input); !v.Valid() {
+		return input, v.Errors
+	}
+	return input, nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
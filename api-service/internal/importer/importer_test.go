@@ -0,0 +1,287 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"io"
+	"log/slog"
+	"reflect"
+	"reviews-movies/api-service/internal/data"
+	"reviews-movies/api-service/internal/kafka"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakePublisher запоминает опубликованные фильмы и отклоняет те, чьё
+// название есть в fail.
+type fakePublisher struct {
+	fail    []string
+	batches int
+	movies  []data.MovieInput
+}
+
+func (p *fakePublisher) ProduceBatch(ctx context.Context, topic string, messages []kafka.Message, tn time.Time) []error {
+	p.batches++
+	errs := make([]error, len(messages))
+	for i, m := range messages {
+		var movie data.MovieInput
+		if err := json.Unmarshal(m.Value, &movie); err != nil {
+			errs[i] = err
+			continue
+		}
+		if m.Key != movie.CorrelationId.String() {
+			errs[i] = errors.New("message key is not the correlation id")
+			continue
+		}
+		if slices.Contains(p.fail, movie.Title) {
+			errs[i] = errors.New("broker unavailable")
+			continue
+		}
+		p.movies = append(p.movies, movie)
+	}
+	return errs
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   Format
+		mapping  Mapping
+		input    string
+		fail     []string
+		accepted []data.MovieInput
+		errors   []RowError
+	}{
+		{
+			name:   "csv",
+			format: CSV,
+			input: "\ufefftitle,year,runtime,genres\n" +
+				"Heat,1995,170,crime|drama\n" +
+				"Alien,1979,117,science fiction; horror\n",
+			accepted: []data.MovieInput{
+				{Title: "Heat", Year: 1995, Runtime: 170, Genres: []string{"Crime", "Drama"}},
+				{Title: "Alien", Year: 1979, Runtime: 117, Genres: []string{"Science Fiction", "Horror"}},
+			},
+		},
+		{
+			name:    "csv mapping",
+			format:  CSV,
+			mapping: Mapping{"title": "Name", "year": "Released"},
+			input: "Name,Released,runtime,genres\n" +
+				"Heat,1995,170,Crime\n",
+			accepted: []data.MovieInput{
+				{Title: "Heat", Year: 1995, Runtime: 170, Genres: []string{"Crime"}},
+			},
+		},
+		{
+			name:   "csv malformed rows",
+			format: CSV,
+			input: "title,year,runtime,genres\n" +
+				"\"Heat\"x,1995,170,Crime\n" +
+				"Alien,nineteen,117,Horror\n" +
+				",1979,117,Horror\n" +
+				"Short\n" +
+				"Up,2009,96,Animation|animation|Family\n",
+			accepted: []data.MovieInput{
+				{Title: "Up", Year: 2009, Runtime: 96, Genres: []string{"Animation", "Family"}},
+			},
+			errors: []RowError{
+				{Row: 2, Errors: map[string]string{"row": `extraneous or missing " in quoted-field`}},
+				{Row: 3, Errors: map[string]string{"year": "must be an integer"}},
+				{Row: 4, Errors: map[string]string{"title": "must be provided"}},
+				{Row: 5, Errors: map[string]string{"year": "must be provided", "runtime": "must be provided", "genres": "must be provided"}},
+			},
+		},
+		{
+			name:   "jsonl",
+			format: JSONL,
+			input: `{"title": "Heat", "year": 1995, "runtime": 170, "genres": ["crime", "drama"]}` + "\n" +
+				"\n" +
+				`{"title": "Alien", "year": "1979", "runtime": 117, "genres": "Horror|Science Fiction"}` + "\n",
+			accepted: []data.MovieInput{
+				{Title: "Heat", Year: 1995, Runtime: 170, Genres: []string{"Crime", "Drama"}},
+				{Title: "Alien", Year: 1979, Runtime: 117, Genres: []string{"Horror", "Science Fiction"}},
+			},
+		},
+		{
+			name:   "jsonl malformed rows",
+			format: JSONL,
+			input: `{"title": "Heat", "year": 1995` + "\n" +
+				`["Heat", 1995]` + "\n" +
+				`{"title": "Alien", "year": 1979.5, "runtime": 117, "genres": ["Horror"]}` + "\n" +
+				`{"title": "Up", "year": 2009, "runtime": -1, "genres": ["Family"]}` + "\n" +
+				`{"title": "Heat", "year": 1995, "runtime": 170, "genres": ["Crime"]}` + "\n",
+			accepted: []data.MovieInput{
+				{Title: "Heat", Year: 1995, Runtime: 170, Genres: []string{"Crime"}},
+			},
+			errors: []RowError{
+				{Row: 1, Errors: map[string]string{"row": "line is not a JSON object"}},
+				{Row: 2, Errors: map[string]string{"row": "line is not a JSON object"}},
+				{Row: 3, Errors: map[string]string{"year": "must be an integer"}},
+				{Row: 4, Errors: map[string]string{"runtime": "must be a positive integer"}},
+			},
+		},
+		{
+			name:   "publish failure",
+			format: JSONL,
+			input: `{"title": "Heat", "year": 1995, "runtime": 170, "genres": ["Crime"]}` + "\n" +
+				`{"title": "Alien", "year": 1979, "runtime": 117, "genres": ["Horror"]}` + "\n",
+			fail: []string{"Heat"},
+			accepted: []data.MovieInput{
+				{Title: "Alien", Year: 1979, Runtime: 117, Genres: []string{"Horror"}},
+			},
+			errors: []RowError{
+				{Row: 1, Errors: map[string]string{"row": "failed to publish, please retry"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pub := &fakePublisher{fail: tt.fail}
+			im := New(pub, "movies", slog.New(slog.NewTextHandler(io.Discard, nil)))
+			job := NewJobStore(10).Create(tt.format, int64(len(tt.input)))
+
+			err := im.Run(context.Background(), job, strings.NewReader(tt.input), Options{Format: tt.format, Mapping: tt.mapping})
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+
+			for i := range pub.movies {
+				pub.movies[i].CorrelationId = uuid.Nil
+			}
+			if !reflect.DeepEqual(pub.movies, tt.accepted) {
+				t.Errorf("published = %+v, want %+v", pub.movies, tt.accepted)
+			}
+
+			view := job.View()
+			if view.Status != StatusCompleted || view.Progress != 100 {
+				t.Errorf("status, progress = %s, %v", view.Status, view.Progress)
+			}
+			if view.Accepted != len(tt.accepted) || view.Rejected != len(tt.errors) || view.Processed != view.Accepted+view.Rejected {
+				t.Errorf("processed, accepted, rejected = %d, %d, %d", view.Processed, view.Accepted, view.Rejected)
+			}
+			if !reflect.DeepEqual(job.errors, tt.errors) {
+				t.Errorf("errors = %v, want %v", job.errors, tt.errors)
+			}
+		})
+	}
+}
+
+func TestRunBatches(t *testing.T) {
+	var input strings.Builder
+	input.WriteString("title,year,runtime,genres\n")
+	for range 5 {
+		input.WriteString("Heat,1995,170,Crime\n")
+	}
+
+	pub := &fakePublisher{}
+	im := New(pub, "movies", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	im.batchSize = 2
+	job := NewJobStore(10).Create(CSV, 0)
+
+	if err := im.Run(context.Background(), job, strings.NewReader(input.String()), Options{Format: CSV}); err != nil {
+		t.Fatal(err)
+	}
+	if pub.batches != 3 || len(pub.movies) != 5 {
+		t.Errorf("batches, movies = %d, %d, want 3, 5", pub.batches, len(pub.movies))
+	}
+}
+
+func TestRunFails(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		format  Format
+		input   string
+		failure string
+	}{
+		{name: "empty csv", ctx: context.Background(), format: CSV, input: "", failure: "read csv header: EOF"},
+		{name: "unknown format", ctx: context.Background(), format: "xml", input: "<movies/>", failure: `unsupported format "xml"`},
+		{name: "cancelled", ctx: ctx, format: JSONL, input: "{}\n", failure: context.Canceled.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pub := &fakePublisher{}
+			im := New(pub, "movies", slog.New(slog.NewTextHandler(io.Discard, nil)))
+			job := NewJobStore(10).Create(tt.format, 0)
+
+			if err := im.Run(tt.ctx, job, strings.NewReader(tt.input), Options{Format: tt.format}); err == nil {
+				t.Fatal("Run succeeded")
+			}
+			view := job.View()
+			if view.Status != StatusFailed || view.Failure != tt.failure || view.FinishedAt == nil {
+				t.Errorf("view = %+v, want failed with %q", view, tt.failure)
+			}
+			if pub.batches != 0 {
+				t.Errorf("published %d batches", pub.batches)
+			}
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Format
+		err  bool
+	}{
+		{in: "csv", want: CSV},
+		{in: "CSV", want: CSV},
+		{in: "jsonl", want: JSONL},
+		{in: "ndjson", want: JSONL},
+		{in: "json", err: true},
+		{in: "", err: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.in)
+		if got != tt.want || (err != nil) != tt.err {
+			t.Errorf("ParseFormat(%q) = %q, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestParseMapping(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Mapping
+		err  bool
+	}{
+		{in: "", want: Mapping{}},
+		{in: "title=Name, year = Released", want: Mapping{"title": "Name", "year": "Released"}},
+		{in: "title", err: true},
+		{in: "title=", err: true},
+		{in: "rating=Score", err: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMapping(tt.in)
+		if !reflect.DeepEqual(got, tt.want) || (err != nil) != tt.err {
+			t.Errorf("ParseMapping(%q) = %v, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestNormalizeGenres(t *testing.T) {
+	tests := []struct {
+		in   []string
+		want []string
+	}{
+		{in: nil, want: []string{}},
+		{in: []string{"  science   FICTION ", "Drama"}, want: []string{"Science Fiction", "Drama"}},
+		{in: []string{"sci-fi", "Sci-Fi", "", " "}, want: []string{"Sci-Fi"}},
+		{in: []string{"film-noir"}, want: []string{"Film-Noir"}},
+	}
+	for _, tt := range tests {
+		if got := NormalizeGenres(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("NormalizeGenres(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package importer
+
+import (
+	"encoding/csv"
+	"github.com/google/uuid"
+	"io"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed"
+)
+
+// RowError — ошибки одной строки источника по именам полей.
+type RowError struct {
+	Row    int               `json:"row"`
+	Errors map[string]string `json:"errors"`
+}
+
+// Job — запись об импорте. Поля меняются из горутины импорта, поэтому
+// читать их снаружи нужно через View и WriteErrorReport.
+type Job struct {
+	mu sync.Mutex
+
+	id         string
+	format     Format
+	status     Status
+	size       int64
+	read       int64
+	processed  int
+	accepted   int
+	rejected   int
+	errors     []RowError
+	failure    string
+	createdAt  time.Time
+	finishedAt time.Time
+}
+
+type JobView struct {
+	ID         string     `json:"id"`
+	Format     Format     `json:"format"`
+	Status     Status     `json:"status"`
+	Progress   float64    `json:"progress"`
+	Processed  int        `json:"processed"`
+	Accepted   int        `json:"accepted"`
+	Rejected   int        `json:"rejected"`
+	Failure    string     `json:"failure,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+func (j *Job) ID() string {
+	return j.id
+}
+
+func (j *Job) View() JobView {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	v := JobView{
+		ID:        j.id,
+		Format:    j.format,
+		Status:    j.status,
+		Processed: j.processed,
+		Accepted:  j.accepted,
+		Rejected:  j.rejected,
+		Failure:   j.failure,
+		CreatedAt: j.createdAt,
+	}
+	switch {
+	case j.status == StatusCompleted:
+		v.Progress = 100
+	case j.size > 0:
+		v.Progress = float64(j.read*1000/j.size) / 10
+	}
+	if !j.finishedAt.IsZero() {
+		finishedAt := j.finishedAt
+		v.FinishedAt = &finishedAt
+	}
+	return v
+}
+
+// WriteErrorReport пишет ошибки строк в CSV: одна строка отчёта на каждое
+// неверное поле.
+func (j *Job) WriteErrorReport(w io.Writer) error {
+	j.mu.Lock()
+	rowErrors := slices.Clone(j.errors)
+	j.mu.Unlock()
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"row", "field", "message"}); err != nil {
+		return err
+	}
+	for _, re := range rowErrors {
+		fieldNames := make([]string, 0, len(re.Errors))
+		for field := range re.Errors {
+			fieldNames = append(fieldNames, field)
+		}
+		slices.Sort(fieldNames)
+		for _, field := range fieldNames {
+			if err := cw.Write([]string{strconv.Itoa(re.Row), field, re.Errors[field]}); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (j *Job) setStatus(status Status) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status = status
+}
+
+func (j *Job) fail(err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status = StatusFailed
+	j.failure = err.Error()
+	j.finishedAt = time.Now()
+}
+
+func (j *Job) complete() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status = StatusCompleted
+	j.finishedAt = time.Now()
+}
+
+func (j *Job) progress(read int64) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.read = read
+}
+
+func (j *Job) reject(row int, errs map[string]string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.processed++
+	j.rejected++
+	j.errors = append(j.errors, RowError{Row: row, Errors: errs})
+}
+
+func (j *Job) accept(n int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.processed += n
+	j.accepted += n
+}
+
+// JobStore хранит задания импорта в памяти процесса. Когда заданий больше
+// limit, удаляются самые старые завершённые.
+type JobStore struct {
+	limit int
+
+	mu    sync.Mutex
+	jobs  map[string]*Job
+	order []string
+}
+
+func NewJobStore(limit int) *JobStore {
+	return &JobStore{limit: limit, jobs: make(map[string]*Job)}
+}
+
+// Create регистрирует задание; size — размер источника в байтах для
+// расчёта прогресса, 0 если неизвестен.
+func (s *JobStore) Create(format Format, size int64) *Job {
+	job := &Job{
+		id:        uuid.NewString(),
+		format:    format,
+		status:    StatusPending,
+		size:      size,
+		createdAt: time.Now(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobs[job.id] = job
+	s.order = append(s.order, job.id)
+
+	for i := 0; len(s.order) > s.limit && i < len(s.order); {
+		old := s.jobs[s.order[i]]
+		if status := old.View().Status; status == StatusPending || status == StatusRunning {
+			i++
+			continue
+		}
+		delete(s.jobs, old.id)
+		s.order = slices.Delete(s.order, i, i+1)
+	}
+	return job
+}
+
+func (s *JobStore) Get(id string) (*Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	return job, ok
+}
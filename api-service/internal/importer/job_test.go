@@ -0,0 +1,110 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestJobStoreEvictsFinishedJobs(t *testing.T) {
+	store := NewJobStore(2)
+
+	running := store.Create(CSV, 0)
+	running.setStatus(StatusRunning)
+	completed := store.Create(CSV, 0)
+	completed.complete()
+	failed := store.Create(JSONL, 0)
+	failed.fail(errors.New("boom"))
+	latest := store.Create(JSONL, 0)
+
+	tests := []struct {
+		name string
+		job  *Job
+		kept bool
+	}{
+		{name: "running", job: running, kept: true},
+		{name: "completed", job: completed, kept: false},
+		{name: "failed", job: failed, kept: false},
+		{name: "latest", job: latest, kept: true},
+	}
+	for _, tt := range tests {
+		got, ok := store.Get(tt.job.ID())
+		if ok != tt.kept || (ok && got != tt.job) {
+			t.Errorf("%s: kept = %v, want %v", tt.name, ok, tt.kept)
+		}
+	}
+	if len(store.order) != 2 {
+		t.Errorf("order = %v, want 2 jobs", store.order)
+	}
+}
+
+func TestJobStoreKeepsUnfinishedJobsOverLimit(t *testing.T) {
+	store := NewJobStore(1)
+	first := store.Create(CSV, 0)
+	second := store.Create(CSV, 0)
+
+	for _, job := range []*Job{first, second} {
+		if _, ok := store.Get(job.ID()); !ok {
+			t.Errorf("pending job %s was evicted", job.ID())
+		}
+	}
+}
+
+func TestJobViewProgress(t *testing.T) {
+	tests := []struct {
+		name     string
+		size     int64
+		read     int64
+		status   Status
+		progress float64
+	}{
+		{name: "unknown size", size: 0, read: 500, status: StatusRunning, progress: 0},
+		{name: "partial", size: 3000, read: 1000, status: StatusRunning, progress: 33.3},
+		{name: "completed", size: 3000, read: 1000, status: StatusCompleted, progress: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := NewJobStore(10).Create(CSV, tt.size)
+			job.progress(tt.read)
+			job.setStatus(tt.status)
+
+			if got := job.View().Progress; got != tt.progress {
+				t.Errorf("progress = %v, want %v", got, tt.progress)
+			}
+		})
+	}
+}
+
+func TestJobCounters(t *testing.T) {
+	job := NewJobStore(10).Create(CSV, 0)
+	job.accept(3)
+	job.reject(5, map[string]string{"year": "must be provided"})
+	job.accept(1)
+
+	v := job.View()
+	if v.Processed != 5 || v.Accepted != 4 || v.Rejected != 1 {
+		t.Errorf("processed, accepted, rejected = %d, %d, %d, want 5, 4, 1", v.Processed, v.Accepted, v.Rejected)
+	}
+	if v.Status != StatusPending || v.FinishedAt != nil {
+		t.Errorf("status = %s, finished_at = %v", v.Status, v.FinishedAt)
+	}
+}
+
+func TestWriteErrorReport(t *testing.T) {
+	job := NewJobStore(10).Create(CSV, 0)
+	job.reject(3, map[string]string{"year": "must be an integer", "genres": "must be provided"})
+	job.reject(7, map[string]string{"row": `extraneous or missing " in quoted-field`})
+
+	var out strings.Builder
+	if err := job.WriteErrorReport(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "row,field,message\n" +
+		"3,genres,must be provided\n" +
+		"3,year,must be an integer\n" +
+		"7,row,\"extraneous or missing \"\" in quoted-field\"\n"
+	if out.String() != want {
+		t.Errorf("report =\n%s\nwant\n%s", out.String(), want)
+	}
+}
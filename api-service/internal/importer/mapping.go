@@ -0,0 +1,67 @@
+package importer
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Поля фильма, которые можно сопоставить с колонками источника.
+var fields = []string{"title", "year", "runtime", "genres"}
+
+// Mapping сопоставляет поле фильма с колонкой CSV или ключом JSONL.
+// Несопоставленные поля берутся из колонки с тем же именем.
+type Mapping map[string]string
+
+// ParseMapping разбирает строку вида "title=Name,year=Released".
+func ParseMapping(s string) (Mapping, error) {
+	m := Mapping{}
+	if strings.TrimSpace(s) == "" {
+		return m, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		field, column, ok := strings.Cut(pair, "=")
+		field, column = strings.TrimSpace(field), strings.TrimSpace(column)
+		if !ok || column == "" {
+			return nil, fmt.Errorf("invalid mapping %q, expected field=column", pair)
+		}
+		if !slices.Contains(fields, field) {
+			return nil, fmt.Errorf("unknown field %q, expected one of %s", field, strings.Join(fields, ", "))
+		}
+		m[field] = column
+	}
+	return m, nil
+}
+
+func (m Mapping) column(field string) string {
+	if column, ok := m[field]; ok {
+		return column
+	}
+	return field
+}
+
+// NormalizeGenres приводит жанры к виду "Science Fiction": убирает лишние
+// пробелы, выравнивает регистр и удаляет пустые значения и повторы.
+func NormalizeGenres(genres []string) []string {
+	out := make([]string, 0, len(genres))
+	for _, g := range genres {
+		g = strings.Join(strings.Fields(g), " ")
+		if g == "" {
+			continue
+		}
+
+		runes := []rune(strings.ToLower(g))
+		for i := range runes {
+			if i == 0 || runes[i-1] == ' ' || runes[i-1] == '-' {
+				runes[i] = unicode.ToUpper(runes[i])
+			}
+		}
+		g = string(runes)
+
+		if !slices.Contains(out, g) {
+			out = append(out, g)
+		}
+	}
+	return out
+}
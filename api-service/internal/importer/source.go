@@ -0,0 +1,148 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type Format string
+
+const (
+	CSV   Format = "csv"
+	JSONL Format = "jsonl"
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "csv":
+		return CSV, nil
+	case "jsonl", "ndjson":
+		return JSONL, nil
+	}
+	return "", fmt.Errorf("unsupported format %q, expected csv or jsonl", s)
+}
+
+// record — одна строка источника: номер строки в файле и значения по
+// имени колонки.
+type record struct {
+	line   int
+	values map[string]any
+	err    error
+}
+
+// source читает записи по одной. Ошибка в отдельной строке возвращается в
+// record.err, ошибка чтения самого потока — вторым значением.
+type source interface {
+	next() (record, error)
+}
+
+func newSource(format Format, r io.Reader) (source, error) {
+	switch format {
+	case CSV:
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		reader.ReuseRecord = true
+
+		header, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("read csv header: %w", err)
+		}
+		columns := make([]string, len(header))
+		for i, h := range header {
+			columns[i] = strings.TrimSpace(strings.TrimPrefix(h, "\ufeff"))
+		}
+		return &csvSource{reader: reader, columns: columns}, nil
+	case JSONL:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		return &jsonlSource{scanner: scanner}, nil
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+type csvSource struct {
+	reader  *csv.Reader
+	columns []string
+}
+
+func (s *csvSource) next() (record, error) {
+	row, err := s.reader.Read()
+
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return record{line: parseErr.StartLine, err: parseErr.Err}, nil
+	}
+	if err != nil {
+		return record{}, err
+	}
+	line, _ := s.reader.FieldPos(0)
+
+	values := make(map[string]any, len(s.columns))
+	for i, column := range s.columns {
+		if i < len(row) {
+			values[column] = row[i]
+		}
+	}
+	return record{line: line, values: values}, nil
+}
+
+type jsonlSource struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (s *jsonlSource) next() (record, error) {
+	for s.scanner.Scan() {
+		s.line++
+		text := strings.TrimSpace(s.scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var values map[string]any
+		if err := json.Unmarshal([]byte(text), &values); err != nil {
+			return record{line: s.line, err: errors.New("line is not a JSON object")}, nil
+		}
+		return record{line: s.line, values: values}, nil
+	}
+	if err := s.scanner.Err(); err != nil {
+		return record{}, err
+	}
+	return record{}, io.EOF
+}
+
+func stringValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func listValue(v any, separators string) []string {
+	switch v := v.(type) {
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			out = append(out, stringValue(item))
+		}
+		return out
+	case nil:
+		return nil
+	default:
+		return strings.FieldsFunc(stringValue(v), func(r rune) bool {
+			return strings.ContainsRune(separators, r)
+		})
+	}
+}
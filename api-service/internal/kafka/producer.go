@@ -1,19 +1,30 @@
 package kafka
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"reviews-movies/api-service/internal/metrics"
+	"reviews-movies/api-service/internal/requestid"
+	"strconv"
 	"strings"
 	"time"
 )
 
 const (
-	FLUSH_TIMEOUT = 5000 // ms
+	FLUSH_TIMEOUT    = 5000 // ms
+	METADATA_TIMEOUT = 2000 // ms
 )
 
 var errUnknownType = errors.New("unknown event type")
 
+var tracer = otel.Tracer("reviews-movies/api-service/internal/kafka")
+
 type Producer struct {
 	producer *kafka.Producer
 }
@@ -30,16 +41,119 @@ func NewProducer(address []string) (*Producer, error) {
 	return &Producer{producer: p}, nil
 }
 
-func (p *Producer) Produce(message, topic, key string, tn time.Time) error {
+func (p *Producer) Produce(ctx context.Context, message, topic, key string, tn time.Time) error {
+	ctx, span := tracer.Start(ctx, "publish "+topic,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingOperationTypePublish,
+			semconv.MessagingDestinationName(topic),
+			semconv.MessagingKafkaMessageKey(key),
+		),
+	)
+	defer span.End()
+
+	kafkaMsg := newMessage(ctx, topic, key, []byte(message), tn)
+
+	start := time.Now()
+	err := p.deliver(kafkaMsg)
+	metrics.KafkaProduceDuration.WithLabelValues(topic).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.KafkaProduceErrors.WithLabelValues(topic).Inc()
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	span.SetAttributes(
+		semconv.MessagingDestinationPartitionID(strconv.Itoa(int(kafkaMsg.TopicPartition.Partition))),
+		semconv.MessagingKafkaMessageOffset(int(kafkaMsg.TopicPartition.Offset)),
+	)
+	return nil
+}
+
+// Message — одно сообщение пакетной публикации.
+type Message struct {
+	Key   string
+	Value []byte
+}
+
+// ProduceBatch ставит все сообщения в очередь разом и ждёт подтверждения
+// каждого. Возвращает ошибки доставки по индексам messages; nil в слайсе
+// означает, что сообщение принято брокером.
+func (p *Producer) ProduceBatch(ctx context.Context, topic string, messages []Message, tn time.Time) []error {
+	ctx, span := tracer.Start(ctx, "publish "+topic,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingOperationTypePublish,
+			semconv.MessagingDestinationName(topic),
+			semconv.MessagingBatchMessageCount(len(messages)),
+		),
+	)
+	defer span.End()
+
+	errs := make([]error, len(messages))
+	events := make(chan kafka.Event, len(messages))
+
+	start := time.Now()
+	pending := 0
+	for i, m := range messages {
+		kafkaMsg := newMessage(ctx, topic, m.Key, m.Value, tn)
+		kafkaMsg.Opaque = i
+		if err := p.producer.Produce(kafkaMsg, events); err != nil {
+			errs[i] = err
+			continue
+		}
+		pending++
+	}
+
+	for ; pending > 0; pending-- {
+		ev, ok := (<-events).(*kafka.Message)
+		if !ok {
+			continue
+		}
+		if ev.TopicPartition.Error != nil {
+			errs[ev.Opaque.(int)] = ev.TopicPartition.Error
+		}
+	}
+	metrics.KafkaProduceDuration.WithLabelValues(topic).Observe(time.Since(start).Seconds())
+
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		metrics.KafkaProduceErrors.WithLabelValues(topic).Add(float64(failed))
+		span.SetStatus(codes.Error, fmt.Sprintf("%d of %d messages failed", failed, len(messages)))
+	}
+	return errs
+}
+
+// newMessage собирает сообщение с X-Request-ID и контекстом трассировки
+// в заголовках.
+func newMessage(ctx context.Context, topic, key string, value []byte, tn time.Time) *kafka.Message {
 	kafkaMsg := &kafka.Message{
 		TopicPartition: kafka.TopicPartition{
 			Topic:     &topic,
 			Partition: kafka.PartitionAny,
 		},
-		Value:     []byte(message),
+		Value:     value,
 		Key:       []byte(key),
 		Timestamp: tn,
 	}
+	if id := requestid.FromContext(ctx); id != "" {
+		kafkaMsg.Headers = append(kafkaMsg.Headers, kafka.Header{
+			Key:   requestid.Header,
+			Value: []byte(id),
+		})
+	}
+	otel.GetTextMapPropagator().Inject(ctx, headerCarrier{msg: kafkaMsg})
+	return kafkaMsg
+}
+
+func (p *Producer) deliver(kafkaMsg *kafka.Message) error {
 	kafkaChan := make(chan kafka.Event)
 	if err := p.producer.Produce(kafkaMsg, kafkaChan); err != nil {
 		return err
@@ -51,6 +165,7 @@ func (p *Producer) Produce(message, topic, key string, tn time.Time) error {
 		if ev.TopicPartition.Error != nil {
 			return ev.TopicPartition.Error
 		}
+		kafkaMsg.TopicPartition = ev.TopicPartition
 		return nil
 	case *kafka.Error:
 		return ev
@@ -59,6 +174,23 @@ func (p *Producer) Produce(message, topic, key string, tn time.Time) error {
 	}
 }
 
+// CheckMetadata запрашивает у брокеров метаданные кластера и проверяет,
+// что хотя бы один брокер доступен.
+func (p *Producer) CheckMetadata(ctx context.Context) error {
+	timeout := METADATA_TIMEOUT
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = int(time.Until(deadline).Milliseconds())
+	}
+	md, err := p.producer.GetMetadata(nil, false, timeout)
+	if err != nil {
+		return err
+	}
+	if len(md.Brokers) == 0 {
+		return errors.New("no brokers available")
+	}
+	return nil
+}
+
 func (p *Producer) Close() {
 	p.producer.Flush(FLUSH_TIMEOUT)
 	p.producer.Close()
@@ -1,8 +1,10 @@
 package logger
 
 import (
+	"context"
 	"io"
 	"log/slog"
+	"reviews-movies/api-service/internal/requestid"
 )
 
 var Logger *slog.Logger
@@ -20,7 +22,27 @@ func InitLogger(env string, w io.Writer) *slog.Logger {
 		handler = slog.NewJSONHandler(w, opts)
 	}
 
-	Logger = slog.New(handler)
+	Logger = slog.New(&contextHandler{Handler: handler})
 
 	return Logger
 }
+
+// contextHandler дописывает в каждую запись request_id из контекста.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := requestid.FromContext(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -5,12 +5,16 @@ import (
 	"data-service/internal/config"
 	"data-service/internal/data"
 	"data-service/internal/handler"
+	"data-service/internal/health"
 	"data-service/internal/kafka"
 	logger2 "data-service/internal/logger"
 	"data-service/internal/models"
+	"data-service/internal/tracing"
+	"data-service/internal/trash"
 	"data-service/pkg/database"
 	"errors"
 	"fmt"
+	"log"
 	"log/slog"
 	"net/http"
 	"os"
@@ -25,35 +29,57 @@ type Application struct {
 }
 
 func main() {
-	cfg, _ := config.LoadConfig()
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
 
 	logger := logger2.InitLogger(cfg.Env, os.Stdout)
 
 	logger.Debug(fmt.Sprintf("Config: %+v", *cfg))
-	db, err := database.OpenDB(cfg)
+
+	shutdownTracing, err := tracing.Init("data-service", cfg.Tracing.Output)
+	if err != nil {
+		logger.Info(err.Error())
+		os.Exit(1)
+	}
+	defer shutdownTracing(context.Background())
+
+	db, err := database.OpenDB(cfg, logger)
 	if err != nil {
-		logger.Info(err.Error(), nil)
+		logger.Info(err.Error())
 	}
 	if err := db.AutoMigrate(&data.Movie{}, &data.Review{}); err != nil {
-		logger.Info(err.Error(), nil)
+		logger.Info(err.Error())
 	}
 	defer func() {
 		conn, _ := db.DB()
 		conn.Close()
 	}()
-	logger.Info("database connection pool established", nil)
+	logger.Info("database connection pool established")
 
 	app := &Application{config: cfg, logger: logger}
-	appModels := models.NewModels(db)
-	ginHandler := handler.NewHandler(appModels, logger)
+	appModels := models.NewModels(db, models.SearchOptions{
+		Languages:        cfg.Search.Languages,
+		FuzzyThreshold:   cfg.Search.FuzzyThreshold,
+		SuggestThreshold: cfg.Search.SuggestThreshold,
+		SuggestMax:       cfg.Search.SuggestMax,
+	}, cfg.Trash.Retention)
+	// Фильтры списков и поиск опираются на search_vector: без него сервис
+	// отвечал бы 500 на каждый такой запрос.
+	if err := appModels.Search.EnsureSchema(context.Background()); err != nil {
+		logger.Error("search schema", "error", err)
+		os.Exit(1)
+	}
 
 	movieConsumer, err := kafka.NewConsumer(
 		cfg.Kafka.Address,
 		cfg.Kafka.Topics.Movie,
 		cfg.Kafka.ConsumerGroup.Movie,
+		logger,
 	)
 	if err != nil {
-		logger.Info(err.Error(), nil)
+		logger.Info(err.Error())
 		os.Exit(1)
 	}
 
@@ -61,23 +87,45 @@ func main() {
 		cfg.Kafka.Address,
 		cfg.Kafka.Topics.Review,
 		cfg.Kafka.ConsumerGroup.Review,
+		logger,
 	)
 	if err != nil {
-		logger.Info(err.Error(), nil)
+		logger.Info(err.Error())
 		os.Exit(1)
 	}
 
+	checker := health.New(cfg.Health.CacheTTL)
+	checker.Add("postgres", database.Ping(db))
+	checker.Add("kafka_movies_group", movieConsumer.CheckMembership)
+	checker.Add("kafka_reviews_group", reviewConsumer.CheckMembership)
+	checker.Add("kafka_movies_lag", movieConsumer.CheckLag(cfg.Health.MaxLag))
+	checker.Add("kafka_reviews_lag", reviewConsumer.CheckLag(cfg.Health.MaxLag))
+
+	ginHandler := handler.NewHandler(appModels, logger, checker, string(cfg.Admin.Token))
+
 	go movieConsumer.StartWithFunc(ginHandler.HandleMovieMessage)
 	go reviewConsumer.StartWithFunc(ginHandler.HandleReviewMessage)
+	movieConsumer.WatchLag(cfg.Health.LagInterval)
+	reviewConsumer.WatchLag(cfg.Health.LagInterval)
+
+	purgeCtx, stopPurger := context.WithCancel(context.Background())
+	purger := &trash.Purger{
+		Trash:     appModels.Trash,
+		Retention: cfg.Trash.Retention,
+		Interval:  cfg.Trash.PurgeInterval,
+		Logger:    logger,
+	}
+	go purger.Run(purgeCtx)
 
 	if err := app.serve(ginHandler.Routes()); err != nil {
-		logger.Info(err.Error(), nil)
+		logger.Info(err.Error())
 	}
+	stopPurger()
 	if err := movieConsumer.Stop(); err != nil {
-		app.logger.Info(err.Error(), nil)
+		app.logger.Info(err.Error())
 	}
 	if err := reviewConsumer.Stop(); err != nil {
-		app.logger.Info(err.Error(), nil)
+		app.logger.Info(err.Error())
 	}
 }
 
@@ -97,17 +145,14 @@ func (app *Application) serve(router http.Handler) error {
 		quit := make(chan os.Signal, 1)
 		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 		s := <-quit
-		app.logger.Info("caught signal, shutting down", map[string]string{"signal": s.String()})
+		app.logger.Info("caught signal, shutting down", "signal", s.String())
 
 		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 		defer cancel()
 		shutdownErr <- srv.Shutdown(ctx)
 	}()
 
-	app.logger.Info("starting Gin server", map[string]string{
-		"env":  app.config.Env,
-		"addr": srv.Addr,
-	})
+	app.logger.Info("starting Gin server", "env", app.config.Env, "addr", srv.Addr)
 
 	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return err
@@ -117,7 +162,7 @@ func (app *Application) serve(router http.Handler) error {
 		return err
 	}
 
-	app.logger.Info("server stopped", map[string]string{"addr": srv.Addr})
+	app.logger.Info("server stopped", "addr", srv.Addr)
 	return nil
 }
 
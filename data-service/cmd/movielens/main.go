@@ -0,0 +1,101 @@
+// Команда movielens заполняет сервис данными из выгрузки MovieLens
+// (movies.csv, ratings.csv, tags.csv): фильмы становятся data.Movie,
+// оценки — отзывами. Загрузка идёт напрямую в Postgres или через Kafka.
+//
+//	movielens -dir ./ml-latest-small -mode kafka -ratings-limit 100000
+package main
+
+import (
+	"context"
+	"data-service/internal/config"
+	"data-service/internal/data"
+	"data-service/internal/kafka"
+	logger2 "data-service/internal/logger"
+	"data-service/internal/movielens"
+	"data-service/pkg/database"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func main() {
+	var (
+		dir            = flag.String("dir", ".", "directory with movies.csv, ratings.csv and tags.csv")
+		mode           = flag.String("mode", "direct", "load mode: direct (Postgres) or kafka")
+		moviesLimit    = flag.Int("movies-limit", 0, "maximum number of movies to load, 0 for all")
+		ratingsLimit   = flag.Int("ratings-limit", 0, "maximum number of ratings to load, 0 for all")
+		batchSize      = flag.Int("batch", 1000, "records per insert or produce batch")
+		runtime        = flag.Int("runtime", 90, "runtime in minutes assigned to every movie")
+		resolveTimeout = flag.Duration("resolve-timeout", 2*time.Minute, "how long to wait for movies created through kafka")
+	)
+	flag.Parse()
+
+	if *mode != "direct" && *mode != "kafka" {
+		log.Fatalf("unknown mode %q, want direct or kafka", *mode)
+	}
+	if *batchSize <= 0 || *runtime <= 0 {
+		log.Fatal("batch and runtime must be positive")
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+	logger := logger2.InitLogger(cfg.Env, os.Stdout)
+
+	db, err := database.OpenDB(cfg, logger)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+	defer func() {
+		conn, _ := db.DB()
+		conn.Close()
+	}()
+
+	opts := movielens.Options{
+		Dir:          *dir,
+		Runtime:      int32(*runtime),
+		MoviesLimit:  *moviesLimit,
+		RatingsLimit: *ratingsLimit,
+		BatchSize:    *batchSize,
+	}
+
+	var sink movielens.Sink
+	switch *mode {
+	case "direct":
+		if err := db.AutoMigrate(&data.Movie{}, &data.Review{}); err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		sink = movielens.NewDirectSink(db)
+	case "kafka":
+		producer, err := kafka.NewProducer(cfg.Kafka.Address)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		defer producer.Close()
+		sink = movielens.NewKafkaSink(producer, cfg.Kafka.Topics.Movie, cfg.Kafka.Topics.Review)
+		opts.ResolveTimeout = *resolveTimeout
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	start := time.Now()
+	stats, err := movielens.New(db, sink, logger).Run(ctx, opts)
+	logger.Info("movielens load finished",
+		"mode", *mode,
+		"movies", stats.Movies, "movies_skipped", stats.MoviesSkipped,
+		"reviews", stats.Reviews, "reviews_skipped", stats.ReviewsSkipped,
+		"elapsed", time.Since(start).String(),
+	)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+}
@@ -2,8 +2,11 @@ package config
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -23,6 +26,58 @@ type Config struct {
 			Review string
 		}
 	}
+	Tracing struct {
+		Output string
+	}
+	Health struct {
+		CacheTTL time.Duration
+		MaxLag   int64
+		// LagInterval — как часто пересчитывается отставание потребителей.
+		LagInterval time.Duration
+	}
+	Search struct {
+		// Languages — конфигурации текстового поиска Postgres, по которым
+		// индексируются названия и комментарии. Первая используется
+		// по умолчанию.
+		Languages []string
+		// FuzzyThreshold — порог word_similarity для фильтра по названию,
+		// SuggestThreshold — для списка "возможно, вы искали".
+		FuzzyThreshold   float64
+		SuggestThreshold float64
+		SuggestMax       int
+	}
+	Trash struct {
+		// Retention — сколько удалённая запись хранится в корзине,
+		// PurgeInterval — как часто корзина очищается; 0 выключает очистку.
+		Retention     time.Duration
+		PurgeInterval time.Duration
+	}
+	Admin struct {
+		// Token — bearer-токен административных маршрутов; пустой
+		// выключает их.
+		Token Secret
+	}
+}
+
+// Secret — значение, которое не должно попадать в логи: при печати через
+// fmt и slog вместо него выводится заглушка.
+type Secret string
+
+const redacted = "[REDACTED]"
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+func (s Secret) GoString() string {
+	return strconv.Quote(s.String())
+}
+
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.String())
 }
 
 func LoadConfig() (*Config, error) {
@@ -57,6 +112,64 @@ func LoadConfig() (*Config, error) {
 	cfg.Kafka.ConsumerGroup.Movie = getEnv("MOVIES_CONSUMER_GROUP", "movies-group")
 	cfg.Kafka.ConsumerGroup.Review = getEnv("REVIEWS_CONSUMER_GROUP", "reviews-group")
 
+	cfg.Tracing.Output = getEnv("TRACING_OUTPUT", "")
+
+	cfg.Health.CacheTTL, err = time.ParseDuration(getEnv("HEALTH_CACHE_TTL", "5s"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Health.MaxLag, err = strconv.ParseInt(getEnv("READY_MAX_CONSUMER_LAG", "10000"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Health.LagInterval, err = time.ParseDuration(getEnv("KAFKA_LAG_INTERVAL", "15s"))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Health.LagInterval <= 0 {
+		return nil, fmt.Errorf("KAFKA_LAG_INTERVAL must be positive")
+	}
+
+	for _, lang := range strings.Split(getEnv("SEARCH_LANGUAGES", "english,russian"), ",") {
+		if lang = strings.TrimSpace(lang); lang != "" {
+			cfg.Search.Languages = append(cfg.Search.Languages, lang)
+		}
+	}
+	if len(cfg.Search.Languages) == 0 {
+		return nil, fmt.Errorf("SEARCH_LANGUAGES must list at least one text search configuration")
+	}
+	cfg.Search.FuzzyThreshold, err = strconv.ParseFloat(getEnv("SEARCH_FUZZY_THRESHOLD", "0.5"), 64)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Search.SuggestThreshold, err = strconv.ParseFloat(getEnv("SEARCH_SUGGEST_THRESHOLD", "0.3"), 64)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Search.SuggestMax, err = strconv.Atoi(getEnv("SEARCH_SUGGEST_MAX_RESULTS", "20"))
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Trash.Retention, err = time.ParseDuration(getEnv("TRASH_RETENTION", "720h"))
+	if err != nil {
+		return nil, err
+	}
+	// Очистка удаляет всё, что старше Retention: при нуле первый же проход
+	// стёр бы корзину целиком.
+	if cfg.Trash.Retention <= 0 {
+		return nil, fmt.Errorf("TRASH_RETENTION must be positive")
+	}
+	cfg.Trash.PurgeInterval, err = time.ParseDuration(getEnv("TRASH_PURGE_INTERVAL", "1h"))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Trash.PurgeInterval < 0 {
+		return nil, fmt.Errorf("TRASH_PURGE_INTERVAL must not be negative")
+	}
+
+	cfg.Admin.Token = Secret(os.Getenv("ADMIN_TOKEN"))
+
 	return cfg, nil
 
 }
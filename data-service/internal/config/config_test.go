@@ -0,0 +1,47 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSecretRedacted(t *testing.T) {
+	const token = "s3cr3t-admin-token"
+
+	var cfg Config
+	cfg.Admin.Token = Secret(token)
+
+	var logged bytes.Buffer
+	slog.New(slog.NewTextHandler(&logged, nil)).Info("config", "token", cfg.Admin.Token)
+
+	tests := []struct {
+		name string
+		out  string
+	}{
+		{name: "%v", out: fmt.Sprintf("%v", cfg)},
+		{name: "%+v", out: fmt.Sprintf("%+v", cfg)},
+		{name: "%#v", out: fmt.Sprintf("%#v", cfg)},
+		{name: "%s", out: fmt.Sprintf("%s", cfg.Admin.Token)},
+		{name: "slog", out: logged.String()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if strings.Contains(tt.out, token) {
+				t.Errorf("token leaked: %s", tt.out)
+			}
+			if !strings.Contains(tt.out, redacted) {
+				t.Errorf("no placeholder in %s", tt.out)
+			}
+		})
+	}
+
+	if got := string(cfg.Admin.Token); got != token {
+		t.Errorf("string(Token) = %q, want %q", got, token)
+	}
+	if got := Secret("").String(); got != "" {
+		t.Errorf("empty secret = %q, want it empty so a disabled token stays visible", got)
+	}
+}
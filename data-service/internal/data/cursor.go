@@ -0,0 +1,132 @@
+package data
+
+import (
+	"data-service/internal/validator"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	CountExact     = "exact"
+	CountEstimated = "estimated"
+	CountNone      = "none"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// Cursor — позиция в списке: значение колонки сортировки и id строки.
+// Клиенту отдаётся непрозрачной строкой; Sort защищает от применения
+// курсора к списку с другой сортировкой.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+func (c Cursor) Encode() string {
+	js, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+func DecodeCursor(token string) (Cursor, error) {
+	var c Cursor
+	js, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, errInvalidCursor
+	}
+	if err := json.Unmarshal(js, &c); err != nil || c.ID == 0 {
+		return c, errInvalidCursor
+	}
+	return c, nil
+}
+
+// Keyset сообщает, что страница задана курсором, а не номером.
+func (f Filters) Keyset() bool {
+	return f.After != "" || f.Before != ""
+}
+
+// Backward — страница перед курсором Before: строки выбираются в обратном
+// порядке и разворачиваются перед ответом.
+func (f Filters) Backward() bool {
+	return f.Before != ""
+}
+
+// KeysetOrder — порядок для постраничной выборки. id идёт в том же
+// направлении, что и колонка сортировки, иначе пара (колонка, id) не
+// сравнивается как кортеж.
+func (f Filters) KeysetOrder() string {
+	dir := f.SortDirection()
+	if f.Backward() {
+		dir = map[string]string{"ASC": "DESC", "DESC": "ASC"}[dir]
+	}
+	col := f.SortColumn()
+	if col == "id" {
+		return "id " + dir
+	}
+	return col + " " + dir + ", id " + dir
+}
+
+// KeysetClause возвращает условие "после курсора" или nil без курсора.
+// Курсор проверяет ValidateFilters; если проверку пропустили, испорченный
+// курсор даёт ошибку.
+func (f Filters) KeysetClause() (clause.Expression, error) {
+	if !f.Keyset() {
+		return nil, nil
+	}
+	token := f.After
+	if f.Backward() {
+		token = f.Before
+	}
+	c, err := DecodeCursor(token)
+	if err != nil {
+		return nil, err
+	}
+
+	op := ">"
+	if (f.SortDirection() == "DESC") != f.Backward() {
+		op = "<"
+	}
+	col := f.SortColumn()
+	if col == "id" {
+		return clause.Expr{SQL: "id " + op + " ?", Vars: []any{c.ID}}, nil
+	}
+	return clause.Expr{SQL: "(" + col + ", id) " + op + " (?, ?)", Vars: []any{c.Value, c.ID}}, nil
+}
+
+func validateCursor(v *validator.Validator, f Filters) {
+	v.Check(f.After == "" || f.Before == "", "before", "must not be combined with after")
+	for key, token := range map[string]string{"after": f.After, "before": f.Before} {
+		if token == "" {
+			continue
+		}
+		c, err := DecodeCursor(token)
+		if err != nil {
+			v.AddError(key, "invalid cursor")
+			continue
+		}
+		v.Check(c.Sort == f.Sort, key, "cursor was issued for a different sort order")
+	}
+	v.Check(!f.Keyset() || f.Page == 1, "page", "must not be combined with after or before")
+}
+
+// PageMetadata собирает метаданные страницы. total учитывается, только если
+// Count не CountNone; next и prev — курсоры соседних страниц или "".
+func PageMetadata(f Filters, total int, next, prev string) Metadata {
+	var m Metadata
+	switch {
+	case f.Count == CountNone:
+		m = Metadata{PageSize: f.PageSize}
+	case f.Keyset():
+		m = Metadata{PageSize: f.PageSize, TotalRecords: total}
+	default:
+		m = CalculateMetadata(total, f.Page, f.PageSize)
+	}
+	if !f.Keyset() {
+		m.CurrentPage, m.PageSize = f.Page, f.PageSize
+	}
+	m.TotalEstimated = f.Count == CountEstimated && m.TotalRecords > 0
+	m.NextCursor, m.PrevCursor = next, prev
+	return m
+}
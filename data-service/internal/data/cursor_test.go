@@ -0,0 +1,132 @@
+package data
+
+import (
+	"data-service/internal/validator"
+	"errors"
+	"gorm.io/gorm/clause"
+	"reflect"
+	"testing"
+)
+
+var testSortSafelist = []string{"id", "rating", "-id", "-rating"}
+
+func TestKeyset(t *testing.T) {
+	tests := []struct {
+		name   string
+		sort   string
+		after  Cursor
+		before Cursor
+		order  string
+		sql    string
+		vars   []any
+	}{
+		{name: "id after", sort: "id", after: Cursor{Sort: "id", ID: 7}, order: "id ASC", sql: "id > ?", vars: []any{uint(7)}},
+		{name: "id before", sort: "id", before: Cursor{Sort: "id", ID: 7}, order: "id DESC", sql: "id < ?", vars: []any{uint(7)}},
+		{name: "-id after", sort: "-id", after: Cursor{Sort: "-id", ID: 7}, order: "id DESC", sql: "id < ?", vars: []any{uint(7)}},
+		{name: "-id before", sort: "-id", before: Cursor{Sort: "-id", ID: 7}, order: "id ASC", sql: "id > ?", vars: []any{uint(7)}},
+		{name: "rating after", sort: "rating", after: Cursor{Sort: "rating", Value: "3.5", ID: 7}, order: "rating ASC, id ASC", sql: "(rating, id) > (?, ?)", vars: []any{"3.5", uint(7)}},
+		{name: "rating before", sort: "rating", before: Cursor{Sort: "rating", Value: "3.5", ID: 7}, order: "rating DESC, id DESC", sql: "(rating, id) < (?, ?)", vars: []any{"3.5", uint(7)}},
+		{name: "-rating after", sort: "-rating", after: Cursor{Sort: "-rating", Value: "3.5", ID: 7}, order: "rating DESC, id DESC", sql: "(rating, id) < (?, ?)", vars: []any{"3.5", uint(7)}},
+		{name: "-rating before", sort: "-rating", before: Cursor{Sort: "-rating", Value: "3.5", ID: 7}, order: "rating ASC, id ASC", sql: "(rating, id) > (?, ?)", vars: []any{"3.5", uint(7)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := Filters{Page: 1, PageSize: 20, Sort: tt.sort, SortSafelist: testSortSafelist}
+			if tt.after.ID != 0 {
+				f.After = tt.after.Encode()
+			}
+			if tt.before.ID != 0 {
+				f.Before = tt.before.Encode()
+			}
+
+			v := validator.New()
+			if ValidateFilters(v, f); !v.Valid() {
+				t.Fatalf("ValidateFilters: %v", v.Errors)
+			}
+			if got := f.KeysetOrder(); got != tt.order {
+				t.Errorf("KeysetOrder = %q, want %q", got, tt.order)
+			}
+			expr, err := f.KeysetClause()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := expr.(clause.Expr)
+			if got.SQL != tt.sql || !reflect.DeepEqual(got.Vars, tt.vars) {
+				t.Errorf("KeysetClause = %q %#v, want %q %#v", got.SQL, got.Vars, tt.sql, tt.vars)
+			}
+		})
+	}
+}
+
+func TestKeysetWithoutCursor(t *testing.T) {
+	f := Filters{Page: 3, PageSize: 20, Sort: "-rating", SortSafelist: testSortSafelist}
+	if got := f.KeysetOrder(); got != "rating DESC, id DESC" {
+		t.Errorf("KeysetOrder = %q", got)
+	}
+	if expr, err := f.KeysetClause(); expr != nil || err != nil {
+		t.Errorf("KeysetClause = %v, %v, want nil, nil", expr, err)
+	}
+}
+
+func TestKeysetClauseBrokenCursor(t *testing.T) {
+	f := Filters{Page: 1, PageSize: 20, Sort: "id", SortSafelist: testSortSafelist, After: "%%%"}
+	if _, err := f.KeysetClause(); !errors.Is(err, errInvalidCursor) {
+		t.Errorf("KeysetClause error = %v, want %v", err, errInvalidCursor)
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	c := Cursor{Sort: "-created_at", Value: "2026-01-02T03:04:05.123456Z", ID: 42}
+	got, err := DecodeCursor(c.Encode())
+	if err != nil || got != c {
+		t.Errorf("DecodeCursor(Encode()) = %+v, %v, want %+v", got, err, c)
+	}
+	for _, token := range []string{"", "%%%", Cursor{Sort: "id"}.Encode()} {
+		if _, err := DecodeCursor(token); !errors.Is(err, errInvalidCursor) {
+			t.Errorf("DecodeCursor(%q) error = %v", token, err)
+		}
+	}
+}
+
+func TestValidateFiltersPaging(t *testing.T) {
+	cursor := Cursor{Sort: "id", ID: 1}.Encode()
+	tests := []struct {
+		name   string
+		f      Filters
+		errors map[string]string
+	}{
+		{name: "first page", f: Filters{Page: 1, PageSize: 20}},
+		{name: "deep offset page", f: Filters{Page: 600, PageSize: 20}},
+		{name: "last allowed page", f: Filters{Page: 10_000_000, PageSize: 100}},
+		{name: "page too large", f: Filters{Page: 10_000_001, PageSize: 100}, errors: map[string]string{"page": "must be a maximum of 10 million"}},
+		{name: "zero page", f: Filters{Page: 0, PageSize: 20}, errors: map[string]string{"page": "must be greater than zero"}},
+		{name: "page size too large", f: Filters{Page: 1, PageSize: 101}, errors: map[string]string{"page_size": "must be a maximum of 100"}},
+		{name: "bad count", f: Filters{Page: 1, PageSize: 20, Count: "all"}, errors: map[string]string{"count": "must be exact, estimated or none"}},
+		{name: "after cursor", f: Filters{Page: 1, PageSize: 20, After: cursor}},
+		{name: "after and before", f: Filters{Page: 1, PageSize: 20, After: cursor, Before: cursor}, errors: map[string]string{"before": "must not be combined with after"}},
+		{name: "cursor with page", f: Filters{Page: 2, PageSize: 20, After: cursor}, errors: map[string]string{"page": "must not be combined with after or before"}},
+		{name: "broken cursor", f: Filters{Page: 1, PageSize: 20, After: "%%%"}, errors: map[string]string{"after": "invalid cursor"}},
+		{name: "cursor for another sort", f: Filters{Page: 1, PageSize: 20, Sort: "-id", Before: cursor}, errors: map[string]string{"before": "cursor was issued for a different sort order"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.f.Sort == "" {
+				tt.f.Sort = "id"
+			}
+			tt.f.SortSafelist = testSortSafelist
+
+			v := validator.New()
+			ValidateFilters(v, tt.f)
+			if len(v.Errors) != len(tt.errors) {
+				t.Fatalf("errors = %v, want %v", v.Errors, tt.errors)
+			}
+			for key, msg := range tt.errors {
+				if v.Errors[key] != msg {
+					t.Errorf("errors[%q] = %q, want %q", key, v.Errors[key], msg)
+				}
+			}
+		})
+	}
+}
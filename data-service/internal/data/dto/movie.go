@@ -0,0 +1,43 @@
+package dto
+
+import (
+	"data-service/internal/data"
+	"github.com/google/uuid"
+	"time"
+)
+
+// Movie — представление фильма в /v2. В отличие от data.Movie не
+// раскрывает поля gorm.Model и пишет все ключи в snake_case.
+type Movie struct {
+	ID            uint      `json:"id"`
+	CorrelationID uuid.UUID `json:"correlation_id"`
+	Title         string    `json:"title"`
+	Year          int32     `json:"year"`
+	Runtime       int32     `json:"runtime"`
+	Genres        []string  `json:"genres"`
+	Version       int64     `json:"version"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func NewMovie(m *data.Movie) Movie {
+	return Movie{
+		ID:            m.ID,
+		CorrelationID: m.CorrelationId,
+		Title:         m.Title,
+		Year:          m.Year,
+		Runtime:       m.Runtime,
+		Genres:        m.Genres,
+		Version:       m.Version.Int64,
+		CreatedAt:     m.CreatedAt,
+		UpdatedAt:     m.UpdatedAt,
+	}
+}
+
+func NewMovies(movies []*data.Movie) []Movie {
+	out := make([]Movie, len(movies))
+	for i, m := range movies {
+		out[i] = NewMovie(m)
+	}
+	return out
+}
@@ -0,0 +1,10 @@
+package dto
+
+// MovieStats — сводка оценок фильма. Histogram содержит все шаги шкалы
+// от "0.5" до "5.0", в том числе пустые.
+type MovieStats struct {
+	MovieID     uint           `json:"movie_id"`
+	ReviewCount int            `json:"review_count"`
+	AvgRating   float64        `json:"avg_rating"`
+	Histogram   map[string]int `json:"histogram"`
+}
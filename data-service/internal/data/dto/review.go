@@ -0,0 +1,42 @@
+package dto
+
+import (
+	"data-service/internal/data"
+	"github.com/google/uuid"
+	"time"
+)
+
+// Review — представление отзыва в /v2.
+type Review struct {
+	ID            uint      `json:"id"`
+	CorrelationID uuid.UUID `json:"correlation_id"`
+	MovieID       uint      `json:"movie_id"`
+	Rating        float32   `json:"rating"`
+	Comment       string    `json:"comment"`
+	Author        string    `json:"author"`
+	Version       int64     `json:"version"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func NewReview(r *data.Review) Review {
+	return Review{
+		ID:            r.ID,
+		CorrelationID: r.CorrelationId,
+		MovieID:       r.MovieId,
+		Rating:        r.Rating,
+		Comment:       r.Comment,
+		Author:        r.Author,
+		Version:       r.Version.Int64,
+		CreatedAt:     r.CreatedAt,
+		UpdatedAt:     r.UpdatedAt,
+	}
+}
+
+func NewReviews(reviews []*data.Review) []Review {
+	out := make([]Review, len(reviews))
+	for i, r := range reviews {
+		out[i] = NewReview(r)
+	}
+	return out
+}
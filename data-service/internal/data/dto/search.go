@@ -0,0 +1,25 @@
+package dto
+
+// SearchHit — найденный фильм или отзыв. Для отзыва Title — название
+// фильма, а Snippet — фрагмент комментария с совпадениями в <mark>;
+// остальной текст в нём экранирован как HTML.
+type SearchHit struct {
+	Type    string  `json:"type"`
+	ID      uint    `json:"id"`
+	MovieID uint    `json:"movie_id"`
+	Title   string  `json:"title"`
+	Snippet string  `json:"snippet"`
+	Rank    float64 `json:"rank"`
+}
+
+type MovieSuggestion struct {
+	ID         uint    `json:"id"`
+	Title      string  `json:"title"`
+	Year       int32   `json:"year"`
+	Similarity float64 `json:"similarity,omitempty"`
+}
+
+type MovieSuggestions struct {
+	Completions []MovieSuggestion `json:"completions"`
+	DidYouMean  []MovieSuggestion `json:"did_you_mean"`
+}
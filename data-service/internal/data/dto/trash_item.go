@@ -0,0 +1,16 @@
+package dto
+
+import "time"
+
+// TrashItem — запись в корзине. Для отзыва Title — название фильма;
+// WithMovie отмечает отзывы, удалённые вместе с фильмом: они
+// восстанавливаются вместе с ним.
+type TrashItem struct {
+	Type      string    `json:"type"`
+	ID        uint      `json:"id"`
+	MovieID   uint      `json:"movie_id"`
+	Title     string    `json:"title"`
+	DeletedAt time.Time `json:"deleted_at"`
+	WithMovie bool      `json:"with_movie"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
@@ -0,0 +1,92 @@
+package data
+
+import (
+	"data-service/internal/validator"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Fieldset — параметры fields и include: какие поля записи вернуть и какие
+// связанные данные встроить в ответ. Имена полей совпадают с колонками
+// таблицы, поэтому выборка сужается прямо в SQL.
+type Fieldset struct {
+	Fields          []string
+	FieldSafelist   []string
+	Include         []string
+	IncludeSafelist []string
+}
+
+// ParseList разбирает список через запятую ("id,title,year"), пропуская
+// пустые элементы и повторы.
+func ParseList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" && !slices.Contains(items, item) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func ValidateFieldset(v *validator.Validator, fs Fieldset) {
+	for _, field := range fs.Fields {
+		if !validator.PermittedValue(field, fs.FieldSafelist...) {
+			v.AddError("fields", fmt.Sprintf("unknown field %q, allowed: %s", field, strings.Join(fs.FieldSafelist, ", ")))
+			break
+		}
+	}
+
+	if len(fs.Include) > 0 && len(fs.IncludeSafelist) == 0 {
+		v.AddError("include", "is not supported for this resource")
+		return
+	}
+	for _, name := range fs.Include {
+		if !validator.PermittedValue(name, fs.IncludeSafelist...) {
+			v.AddError("include", fmt.Sprintf("unknown relation %q, allowed: %s", name, strings.Join(fs.IncludeSafelist, ", ")))
+			break
+		}
+	}
+}
+
+// Sparse сообщает, что ответ строится по Fieldset, а не полным
+// представлением записи.
+func (fs Fieldset) Sparse() bool {
+	return len(fs.Fields) > 0 || len(fs.Include) > 0
+}
+
+// Columns возвращает колонки для SELECT или nil, если нужны все. id и
+// version читаются и отдаются всегда: по ним строятся ETag, курсоры и
+// встроенные связи. Поля должны быть проверены ValidateFieldset.
+func (fs Fieldset) Columns() []string {
+	if len(fs.Fields) == 0 {
+		return nil
+	}
+	columns := []string{"id", "version"}
+	for _, field := range fs.Fields {
+		if !slices.Contains(fs.FieldSafelist, field) {
+			panic("unsafe field: " + field)
+		}
+		if !slices.Contains(columns, field) {
+			columns = append(columns, field)
+		}
+	}
+	return columns
+}
+
+func (fs Fieldset) Includes(name string) bool {
+	return slices.Contains(fs.Include, name)
+}
+
+// Pick оставляет в представлении записи только выбранные поля.
+func (fs Fieldset) Pick(view map[string]any) map[string]any {
+	columns := fs.Columns()
+	if columns == nil {
+		return view
+	}
+	picked := make(map[string]any, len(columns))
+	for _, column := range columns {
+		picked[column] = view[column]
+	}
+	return picked
+}
@@ -0,0 +1,423 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"gorm.io/gorm/clause"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Язык фильтров для списков:
+//
+//	filter=year>=1990 and runtime<120 and genres in (Drama, Crime)
+//	filter=not (rating<=2 or author="bot") and created_at>=2024-01-01
+//
+// Сравнения: = != < <= > >= ~ (подстрока без учёта регистра) и in (...).
+// Условия объединяются and, or, not и скобками; and связывает сильнее or.
+// Строки со пробелами или спецсимволами берутся в кавычки "..." или '...'.
+// Допустимые поля и операторы задаёт модель через FilterFields: имена
+// колонок берутся только оттуда, значения всегда уходят параметрами.
+
+const (
+	maxFilterLength = 1000
+	maxFilterTerms  = 20
+	maxFilterDepth  = 10
+	maxFilterList   = 50
+)
+
+type FilterType int
+
+const (
+	FilterInt FilterType = iota
+	FilterFloat
+	FilterString
+	FilterTime
+	// FilterStringArray — jsonb-массив строк; "=" и "in" проверяют, что
+	// в массиве есть значение (хотя бы одно из списка).
+	FilterStringArray
+)
+
+type FilterField struct {
+	Column string
+	Type   FilterType
+	Ops    []string
+}
+
+// FilterFields — белый список полей фильтра по их именам в запросе.
+type FilterFields map[string]FilterField
+
+var (
+	OrderedOps = []string{"=", "!=", "<", "<=", ">", ">=", "in"}
+	TextOps    = []string{"=", "!=", "~", "in"}
+	ArrayOps   = []string{"=", "!=", "in"}
+)
+
+// FilterError — ошибка разбора с позицией (в байтах) во входной строке.
+type FilterError struct {
+	Pos int
+	Msg string
+}
+
+func (e *FilterError) Error() string {
+	return fmt.Sprintf("at position %d: %s", e.Pos, e.Msg)
+}
+
+// ParseFilter разбирает выражение и переводит его в условие WHERE.
+func ParseFilter(input string, fields FilterFields) (clause.Expr, error) {
+	if len(input) > maxFilterLength {
+		return clause.Expr{}, &FilterError{Pos: maxFilterLength, Msg: fmt.Sprintf("must not be more than %d bytes long", maxFilterLength)}
+	}
+	tokens, err := lexFilter(input)
+	if err != nil {
+		return clause.Expr{}, err
+	}
+	p := &filterParser{tokens: tokens, fields: fields}
+
+	var b filterBuilder
+	if err := p.parseOr(&b, 0); err != nil {
+		return clause.Expr{}, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return clause.Expr{}, p.errorf(tok, "unexpected %s", tok)
+	}
+	return clause.Expr{SQL: b.sql.String(), Vars: b.vars}, nil
+}
+
+// ---- лексер ----
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of filter"
+	case tokString:
+		return strconv.Quote(t.text)
+	default:
+		return "'" + t.text + "'"
+	}
+}
+
+// keyword сравнивает слово с ключевым без учёта регистра.
+func (t token) keyword(kw string) bool {
+	return t.kind == tokWord && strings.EqualFold(t.text, kw)
+}
+
+func lexFilter(input string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(input); {
+		ch := rune(input[i])
+		switch {
+		case unicode.IsSpace(ch):
+			i++
+		case ch == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: i})
+			i++
+		case ch == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: i})
+			i++
+		case ch == ',':
+			tokens = append(tokens, token{kind: tokComma, text: ",", pos: i})
+			i++
+		case ch == '"' || ch == '\'':
+			start := i
+			var sb strings.Builder
+			i++
+			for {
+				if i >= len(input) {
+					return nil, &FilterError{Pos: start, Msg: "unterminated string"}
+				}
+				if input[i] == '\\' && i+1 < len(input) {
+					sb.WriteByte(input[i+1])
+					i += 2
+					continue
+				}
+				if rune(input[i]) == ch {
+					i++
+					break
+				}
+				sb.WriteByte(input[i])
+				i++
+			}
+			tokens = append(tokens, token{kind: tokString, text: sb.String(), pos: start})
+		case strings.ContainsRune("=!<>~", ch):
+			start := i
+			op := input[i : i+1]
+			if i+1 < len(input) && input[i+1] == '=' && ch != '=' && ch != '~' {
+				op = input[i : i+2]
+			}
+			if op == "!" {
+				return nil, &FilterError{Pos: start, Msg: "unexpected '!', did you mean '!='?"}
+			}
+			i += len(op)
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: start})
+		default:
+			start := i
+			for i < len(input) && isWordByte(input[i]) {
+				i++
+			}
+			if i == start {
+				return nil, &FilterError{Pos: start, Msg: fmt.Sprintf("unexpected character %q", input[i])}
+			}
+			tokens = append(tokens, token{kind: tokWord, text: input[start:i], pos: start})
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(input)}), nil
+}
+
+// isWordByte допускает в словах буквы, цифры и символы дат, чисел и
+// идентификаторов; байты UTF-8 старше ASCII — часть букв.
+func isWordByte(b byte) bool {
+	return b >= 0x80 || b == '_' || b == '.' || b == '-' || b == ':' || b == '+' ||
+		'0' <= b && b <= '9' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
+
+// ---- парсер ----
+
+type filterParser struct {
+	tokens []token
+	i      int
+	fields FilterFields
+	terms  int
+}
+
+type filterBuilder struct {
+	sql  strings.Builder
+	vars []any
+}
+
+func (b *filterBuilder) write(sql string, vars ...any) {
+	b.sql.WriteString(sql)
+	b.vars = append(b.vars, vars...)
+}
+
+func (p *filterParser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *filterParser) next() token {
+	tok := p.tokens[p.i]
+	if tok.kind != tokEOF {
+		p.i++
+	}
+	return tok
+}
+
+func (p *filterParser) errorf(tok token, format string, args ...any) error {
+	return &FilterError{Pos: tok.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *filterParser) parseOr(b *filterBuilder, depth int) error {
+	return p.parseChain(b, depth, "or", p.parseAnd)
+}
+
+func (p *filterParser) parseAnd(b *filterBuilder, depth int) error {
+	return p.parseChain(b, depth, "and", p.parseUnary)
+}
+
+// parseChain разбирает последовательность операндов через and/or.
+// Каждый операнд берётся в скобки, так что приоритет в SQL совпадает
+// с приоритетом в выражении.
+func (p *filterParser) parseChain(b *filterBuilder, depth int, kw string, operand func(*filterBuilder, int) error) error {
+	b.write("(")
+	if err := operand(b, depth); err != nil {
+		return err
+	}
+	for p.peek().keyword(kw) {
+		p.next()
+		b.write(" " + strings.ToUpper(kw) + " ")
+		if err := operand(b, depth); err != nil {
+			return err
+		}
+	}
+	b.write(")")
+	return nil
+}
+
+func (p *filterParser) parseUnary(b *filterBuilder, depth int) error {
+	if depth > maxFilterDepth {
+		return p.errorf(p.peek(), "filter is nested too deeply")
+	}
+	tok := p.peek()
+	switch {
+	case tok.keyword("not"):
+		p.next()
+		b.write("NOT ")
+		return p.parseUnary(b, depth+1)
+	case tok.kind == tokLParen:
+		p.next()
+		if err := p.parseOr(b, depth+1); err != nil {
+			return err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return p.errorf(closing, "expected ')' but found %s", closing)
+		}
+		return nil
+	default:
+		return p.parseComparison(b)
+	}
+}
+
+func (p *filterParser) parseComparison(b *filterBuilder) error {
+	name := p.next()
+	if name.kind != tokWord {
+		return p.errorf(name, "expected field name but found %s", name)
+	}
+	field, ok := p.fields[strings.ToLower(name.text)]
+	if !ok {
+		return p.errorf(name, "unknown field %q, allowed: %s", name.text, strings.Join(p.fieldNames(), ", "))
+	}
+
+	p.terms++
+	if p.terms > maxFilterTerms {
+		return p.errorf(name, "filter must not contain more than %d conditions", maxFilterTerms)
+	}
+
+	opTok := p.next()
+	op := opTok.text
+	switch {
+	case opTok.kind == tokOp:
+	case opTok.keyword("in"):
+		op = "in"
+	default:
+		return p.errorf(opTok, "expected operator after %q but found %s", name.text, opTok)
+	}
+	if !slices.Contains(field.Ops, op) {
+		return p.errorf(opTok, "operator %q is not supported for field %q, allowed: %s", op, name.text, strings.Join(field.Ops, " "))
+	}
+
+	if op != "in" {
+		valTok := p.next()
+		value, err := p.value(field, valTok)
+		if err != nil {
+			return err
+		}
+		return field.build(b, op, []any{value})
+	}
+
+	if open := p.next(); open.kind != tokLParen {
+		return p.errorf(open, "expected '(' after in but found %s", open)
+	}
+	var values []any
+	for {
+		valTok := p.next()
+		value, err := p.value(field, valTok)
+		if err != nil {
+			return err
+		}
+		values = append(values, value)
+		if len(values) > maxFilterList {
+			return p.errorf(valTok, "in list must not contain more than %d values", maxFilterList)
+		}
+
+		sep := p.next()
+		if sep.kind == tokRParen {
+			break
+		}
+		if sep.kind != tokComma {
+			return p.errorf(sep, "expected ',' or ')' but found %s", sep)
+		}
+	}
+	return field.build(b, op, values)
+}
+
+// value проверяет значение по типу поля. Числа с плавающей точкой
+// передаются строкой и приводятся к numeric, чтобы не сравнивать
+// decimal-колонки с неточным float.
+func (p *filterParser) value(field FilterField, tok token) (any, error) {
+	if tok.kind != tokWord && tok.kind != tokString {
+		return nil, p.errorf(tok, "expected value but found %s", tok)
+	}
+	switch field.Type {
+	case FilterInt:
+		n, err := strconv.ParseInt(tok.text, 10, 64)
+		if err != nil {
+			return nil, p.errorf(tok, "%s is not an integer", tok)
+		}
+		return n, nil
+	case FilterFloat:
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, p.errorf(tok, "%s is not a number", tok)
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	case FilterTime:
+		if t, err := time.Parse(time.RFC3339, tok.text); err == nil {
+			return t, nil
+		}
+		t, err := time.Parse(time.DateOnly, tok.text)
+		if err != nil {
+			return nil, p.errorf(tok, "%s is not a date, expected RFC 3339 or YYYY-MM-DD", tok)
+		}
+		return t, nil
+	default:
+		return tok.text, nil
+	}
+}
+
+func (p *filterParser) fieldNames() []string {
+	names := make([]string, 0, len(p.fields))
+	for name := range p.fields {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (f FilterField) build(b *filterBuilder, op string, values []any) error {
+	col := f.Column
+	cast := ""
+	if f.Type == FilterFloat {
+		cast = "::numeric"
+	}
+
+	switch {
+	case f.Type == FilterStringArray:
+		// @> по одному элементу: так работает GIN-индекс по jsonb.
+		conds := make([]string, len(values))
+		for i, v := range values {
+			elem, err := json.Marshal([]any{v})
+			if err != nil {
+				return err
+			}
+			conds[i] = col + " @> ?"
+			values[i] = string(elem)
+		}
+		sql := "(" + strings.Join(conds, " OR ") + ")"
+		if op == "!=" {
+			sql = "NOT " + sql
+		}
+		b.write(sql, values...)
+	case op == "in":
+		b.write(col+" IN ?", values)
+	case op == "~":
+		b.write(col+" ILIKE '%' || ? || '%'", escapeLike(values[0].(string)))
+	default:
+		b.write(col+" "+op+" ?"+cast, values[0])
+	}
+	return nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,153 @@
+package data
+
+import (
+	"errors"
+	"gorm.io/gorm/clause"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testFilterFields = FilterFields{
+	"year":       {Column: "year", Type: FilterInt, Ops: OrderedOps},
+	"rating":     {Column: "rating", Type: FilterFloat, Ops: OrderedOps},
+	"title":      {Column: "title", Type: FilterString, Ops: TextOps},
+	"created_at": {Column: "created_at", Type: FilterTime, Ops: OrderedOps},
+	"genres":     {Column: "genres", Type: FilterStringArray, Ops: ArrayOps},
+}
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		sql   string
+		vars  []any
+	}{
+		{name: "int equal", input: "year=1994", sql: "((year = ?))", vars: []any{int64(1994)}},
+		{name: "int not equal", input: "year != 1994", sql: "((year != ?))", vars: []any{int64(1994)}},
+		{name: "int less", input: "year<1994", sql: "((year < ?))", vars: []any{int64(1994)}},
+		{name: "int less or equal", input: "year<=1994", sql: "((year <= ?))", vars: []any{int64(1994)}},
+		{name: "int greater", input: "year>1994", sql: "((year > ?))", vars: []any{int64(1994)}},
+		{name: "int greater or equal", input: "year>=1994", sql: "((year >= ?))", vars: []any{int64(1994)}},
+		{name: "int in", input: "year in (1994, 1995)", sql: "((year IN ?))", vars: []any{[]any{int64(1994), int64(1995)}}},
+		{name: "float as numeric", input: "rating>=3.7", sql: "((rating >= ?::numeric))", vars: []any{"3.7"}},
+		{name: "float normalized", input: "rating<4", sql: "((rating < ?::numeric))", vars: []any{"4"}},
+		{name: "string bare word", input: "title=Heat", sql: "((title = ?))", vars: []any{"Heat"}},
+		{name: "string double quotes", input: `title="The Godfather"`, sql: "((title = ?))", vars: []any{"The Godfather"}},
+		{name: "string single quotes", input: `title='Pulp Fiction'`, sql: "((title = ?))", vars: []any{"Pulp Fiction"}},
+		{name: "string escaped quote", input: `title="say \"hi\""`, sql: "((title = ?))", vars: []any{`say "hi"`}},
+		{name: "string unicode", input: "title=Сталкер", sql: "((title = ?))", vars: []any{"Сталкер"}},
+		{name: "string contains escapes like", input: `title~"50%_off"`, sql: "((title ILIKE '%' || ? || '%'))", vars: []any{`50\%\_off`}},
+		{name: "string in", input: `title in (Heat, "Se7en")`, sql: "((title IN ?))", vars: []any{[]any{"Heat", "Se7en"}}},
+		{name: "date", input: "created_at>=2024-01-01", sql: "((created_at >= ?))", vars: []any{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}},
+		{name: "timestamp", input: "created_at<2024-01-02T03:04:05Z", sql: "((created_at < ?))", vars: []any{time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}},
+		{name: "array contains", input: "genres=Drama", sql: "(((genres @> ?)))", vars: []any{`["Drama"]`}},
+		{name: "array not contains", input: "genres!=Drama", sql: "((NOT (genres @> ?)))", vars: []any{`["Drama"]`}},
+		{name: "array in", input: "genres in (Drama, Crime)", sql: "(((genres @> ? OR genres @> ?)))", vars: []any{`["Drama"]`, `["Crime"]`}},
+		{name: "and binds tighter than or", input: "year>2000 or year<1950 and rating>4", sql: "((year > ?) OR (year < ? AND rating > ?::numeric))", vars: []any{int64(2000), int64(1950), "4"}},
+		{name: "not with parentheses", input: "not (year=1 or year=2)", sql: "((NOT ((year = ?) OR (year = ?))))", vars: []any{int64(1), int64(2)}},
+		{name: "keywords and fields ignore case", input: "YEAR=1 AND Title=x", sql: "((year = ? AND title = ?))", vars: []any{int64(1), "x"}},
+		{name: "injection in value stays a parameter", input: `title="x' OR 1=1 --"`, sql: "((title = ?))", vars: []any{"x' OR 1=1 --"}},
+		{name: "injection in like value", input: `title~"'; DROP TABLE movies; --"`, sql: "((title ILIKE '%' || ? || '%'))", vars: []any{"'; DROP TABLE movies; --"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := ParseFilter(tt.input, testFilterFields)
+			if err != nil {
+				t.Fatalf("ParseFilter(%q): %v", tt.input, err)
+			}
+			if expr.SQL != tt.sql {
+				t.Errorf("SQL = %q, want %q", expr.SQL, tt.sql)
+			}
+			if !reflect.DeepEqual(expr.Vars, tt.vars) {
+				t.Errorf("vars = %#v, want %#v", expr.Vars, tt.vars)
+			}
+		})
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	terms := make([]string, maxFilterTerms+1)
+	for i := range terms {
+		terms[i] = "year=1"
+	}
+	values := strings.Repeat("1,", maxFilterList) + "1"
+
+	tests := []struct {
+		name  string
+		input string
+		pos   int
+		msg   string
+	}{
+		{name: "empty", input: "", pos: 0, msg: "expected field name but found end of filter"},
+		{name: "unknown field", input: "budget>1", pos: 0, msg: `unknown field "budget", allowed: created_at, genres, rating, title, year`},
+		{name: "column name is not a field", input: "1=1", pos: 0, msg: `unknown field "1", allowed: created_at, genres, rating, title, year`},
+		{name: "unsupported operator", input: "title<x", pos: 5, msg: `operator "<" is not supported for field "title", allowed: = != ~ in`},
+		{name: "like on array", input: "genres~Dr", pos: 6, msg: `operator "~" is not supported for field "genres", allowed: = != in`},
+		{name: "missing operator", input: "year 1994", pos: 5, msg: `expected operator after "year" but found '1994'`},
+		{name: "missing value", input: "year=", pos: 5, msg: "expected value but found end of filter"},
+		{name: "not an integer", input: "year=abc", pos: 5, msg: "'abc' is not an integer"},
+		{name: "quoted not an integer", input: `year="1 OR 1=1"`, pos: 5, msg: `"1 OR 1=1" is not an integer`},
+		{name: "not a number", input: "rating=x1", pos: 7, msg: "'x1' is not a number"},
+		{name: "not a date", input: "created_at>=yesterday", pos: 12, msg: "'yesterday' is not a date, expected RFC 3339 or YYYY-MM-DD"},
+		{name: "unterminated string", input: `title="abc`, pos: 6, msg: "unterminated string"},
+		{name: "bang", input: "year ! 1", pos: 5, msg: "unexpected '!', did you mean '!='?"},
+		{name: "semicolon", input: "year=1; DROP TABLE movies", pos: 6, msg: "unexpected character ';'"},
+		{name: "comment", input: "year=1 -- x", pos: 7, msg: "unexpected '--'"},
+		{name: "closing parenthesis breaks out", input: "title=x) OR (1=1", pos: 7, msg: "unexpected ')'"},
+		{name: "unclosed parenthesis", input: "(year=1", pos: 7, msg: "expected ')' but found end of filter"},
+		{name: "in without list", input: "year in 1994", pos: 8, msg: "expected '(' after in but found '1994'"},
+		{name: "in list without comma", input: "year in (1 2)", pos: 11, msg: "expected ',' or ')' but found '2'"},
+		{name: "in list with bad value", input: "year in (1, x)", pos: 12, msg: "'x' is not an integer"},
+		{name: "trailing condition", input: "year=1 year=2", pos: 7, msg: "unexpected 'year'"},
+		{name: "dangling and", input: "year=1 and", pos: 10, msg: "expected field name but found end of filter"},
+		{name: "too long", input: "title=" + strings.Repeat("a", maxFilterLength), pos: maxFilterLength, msg: "must not be more than 1000 bytes long"},
+		{name: "too many conditions", input: strings.Join(terms, " and "), pos: maxFilterTerms * len("year=1 and "), msg: "filter must not contain more than 20 conditions"},
+		{name: "nested too deeply", input: strings.Repeat("not ", maxFilterDepth+1) + "year=1", pos: 4 * (maxFilterDepth + 1), msg: "filter is nested too deeply"},
+		{name: "in list too long", input: "year in (" + values + ")", pos: 9 + 2*maxFilterList, msg: "in list must not contain more than 50 values"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFilter(tt.input, testFilterFields)
+			var fe *FilterError
+			if !errors.As(err, &fe) {
+				t.Fatalf("ParseFilter(%q) error = %v, want *FilterError", tt.input, err)
+			}
+			if fe.Pos != tt.pos || fe.Msg != tt.msg {
+				t.Errorf("error = %d %q, want %d %q", fe.Pos, fe.Msg, tt.pos, tt.msg)
+			}
+		})
+	}
+}
+
+func TestFilterErrorMessage(t *testing.T) {
+	err := &FilterError{Pos: 5, Msg: "'abc' is not an integer"}
+	if got, want := err.Error(), "at position 5: 'abc' is not an integer"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestFilterClause(t *testing.T) {
+	expr, err := Filters{}.FilterClause()
+	if expr != nil || err != nil {
+		t.Errorf("empty filter: %v, %v, want nil, nil", expr, err)
+	}
+
+	expr, err = Filters{Filter: "year=1", FilterFields: testFilterFields}.FilterClause()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := expr.(clause.Expr).SQL; got != "((year = ?))" {
+		t.Errorf("SQL = %q", got)
+	}
+
+	// Без ValidateFilters ошибка возвращается, а не роняет обработчик.
+	_, err = Filters{Filter: "budget>1", FilterFields: testFilterFields}.FilterClause()
+	var fe *FilterError
+	if !errors.As(err, &fe) {
+		t.Errorf("error = %v, want *FilterError", err)
+	}
+}
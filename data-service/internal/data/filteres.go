@@ -2,6 +2,7 @@ package data
 
 import (
 	"data-service/internal/validator"
+	"gorm.io/gorm/clause"
 	"math"
 	"strings"
 )
@@ -11,9 +12,24 @@ type Filters struct {
 	PageSize     int
 	Sort         string
 	SortSafelist []string
+	// Filter — выражение на языке ParseFilter, FilterFields — поля,
+	// которые в нём разрешены.
+	Filter       string
+	FilterFields FilterFields
+	// After и Before — курсоры keyset-пагинации, заменяют Page. Count —
+	// как считать общее число записей: CountExact, CountEstimated или
+	// CountNone.
+	After  string
+	Before string
+	Count  string
+	// Columns — колонки выборки из Fieldset.Columns; nil — все.
+	Columns []string
 }
 
 func ValidateFilters(v *validator.Validator, f Filters) {
+	// Глубокие страницы по номеру работают как раньше, но OFFSET читает и
+	// отбрасывает все пропущенные строки, поэтому для них лучше курсор
+	// next_cursor из метаданных. С этими границами Offset не переполняется.
 	v.Check(f.Page > 0, "page", "must be greater than zero")
 	v.Check(f.Page <= 10_000_000, "page", "must be a maximum of 10 million")
 
@@ -21,6 +37,24 @@ func ValidateFilters(v *validator.Validator, f Filters) {
 	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
 
 	v.Check(validator.PermittedValue(f.Sort, f.SortSafelist...), "sort", "invalid sort value")
+	v.Check(f.Count == "" || validator.PermittedValue(f.Count, CountExact, CountEstimated, CountNone), "count", "must be exact, estimated or none")
+
+	if v.Valid() {
+		validateCursor(v, f)
+	}
+
+	ValidateFilterExpr(v, f)
+}
+
+// ValidateFilterExpr проверяет только выражение фильтра — для выборок
+// без пагинации.
+func ValidateFilterExpr(v *validator.Validator, f Filters) {
+	if f.Filter == "" {
+		return
+	}
+	if _, err := ParseFilter(f.Filter, f.FilterFields); err != nil {
+		v.AddError("filter", err.Error())
+	}
 }
 
 func (f Filters) SortColumn() string {
@@ -40,6 +74,20 @@ func (f Filters) SortDirection() string {
 	return "ASC"
 }
 
+// FilterClause возвращает условие по выражению фильтра или nil, если
+// фильтр не задан. Выражение проверяет ValidateFilters; если проверку
+// пропустили, ошибка разбора возвращается как *FilterError.
+func (f Filters) FilterClause() (clause.Expression, error) {
+	if f.Filter == "" {
+		return nil, nil
+	}
+	expr, err := ParseFilter(f.Filter, f.FilterFields)
+	if err != nil {
+		return nil, err
+	}
+	return expr, nil
+}
+
 func (f Filters) Limit() int {
 	return f.PageSize
 }
@@ -54,6 +102,10 @@ type Metadata struct {
 	FirstPage    int `json:"first_page,omitempty"`
 	LastPage     int `json:"last_page,omitempty"`
 	TotalRecords int `json:"total_record,omitempty"`
+	// TotalEstimated — TotalRecords взят из оценки планировщика.
+	TotalEstimated bool   `json:"total_estimated,omitempty"`
+	NextCursor     string `json:"next_cursor,omitempty"`
+	PrevCursor     string `json:"prev_cursor,omitempty"`
 }
 
 func CalculateMetadata(totalRecords, page, pageSize int) Metadata {
@@ -1,11 +1,12 @@
 package data
 
 import (
+	"data-service/internal/rules"
 	"data-service/internal/validator"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 	"gorm.io/plugin/optimisticlock"
-	"strings"
+	"time"
 )
 
 type Review struct {
@@ -14,22 +15,51 @@ type Review struct {
 	MovieId       uint                   `gorm:"not null" json:"movie_id"`
 	Rating        float32                `gorm:"type:decimal(2,1);check:rating >= 0.5 AND rating <= 5.0" json:"rating"`
 	Comment       string                 `gorm:"type:text;not null" json:"comment"`
-	Author        string                 `gorm:"size:50;not null" json:"author"`
+	Author        string                 `gorm:"size:50;not null;index" json:"author"`
 	Version       optimisticlock.Version `gorm:"default:1" json:"version"`
 
 	Movie Movie `gorm:"foreignKey:MovieId;references:ID" json:"-"`
 }
 
 func ValidateReview(v *validator.Validator, review *Review) {
-	v.Check(review.CorrelationId != uuid.Nil, "correlation_id", "must be a valid UUID")
+	rules.ValidateReview(v, rules.Review{
+		CorrelationId: review.CorrelationId,
+		MovieId:       review.MovieId,
+		Rating:        review.Rating,
+		Comment:       review.Comment,
+		Author:        review.Author,
+	})
+}
+
+const (
+	AuthorExact   = "exact"
+	AuthorPartial = "partial"
+)
+
+// ReviewFilter — условия выборки отзывов. Нулевые значения не фильтруют.
+// Интервал дат полуоткрытый: CreatedFrom включается, CreatedTo — нет.
+type ReviewFilter struct {
+	MovieID     uint
+	Author      string
+	AuthorMatch string
+	RatingMin   float64
+	RatingMax   float64
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	// Query ищется в комментарии и авторе через search_vector в
+	// конфигурации Language.
+	Query    string
+	Language string
+}
 
-	v.Check(review.MovieId != 0, "movie_id", "must be provided")
+func ValidateReviewFilter(v *validator.Validator, f ReviewFilter) {
+	v.Check(validator.PermittedValue(f.AuthorMatch, AuthorExact, AuthorPartial), "author_match", "must be exact or partial")
 
-	v.Check(review.Rating >= 0.5 && review.Rating <= 5.0, "rating", "must be between 0.5 and 5.0")
+	v.Check(f.RatingMin == 0 || f.RatingMin >= 0.5 && f.RatingMin <= 5.0, "rating_min", "must be between 0.5 and 5.0")
+	v.Check(f.RatingMax == 0 || f.RatingMax >= 0.5 && f.RatingMax <= 5.0, "rating_max", "must be between 0.5 and 5.0")
+	v.Check(f.RatingMin == 0 || f.RatingMax == 0 || f.RatingMin <= f.RatingMax, "rating_max", "must not be less than rating_min")
 
-	v.Check(strings.TrimSpace(review.Comment) != "", "comment", "must be provided")
-	v.Check(len(review.Comment) <= 1000, "comment", "must not be more than 1000 characters")
+	v.Check(f.CreatedFrom.IsZero() || f.CreatedTo.IsZero() || f.CreatedFrom.Before(f.CreatedTo), "created_to", "must be after created_from")
 
-	v.Check(strings.TrimSpace(review.Author) != "", "author", "must be provided")
-	v.Check(len(review.Author) <= 50, "author", "must not be more than 50 characters")
+	v.Check(len(f.Query) <= 200, "q", "must not be more than 200 bytes long")
 }
@@ -0,0 +1,58 @@
+package data
+
+import (
+	"data-service/internal/validator"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateReviewFilter(t *testing.T) {
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		filter ReviewFilter
+		errors map[string]string
+	}{
+		{name: "exact author", filter: ReviewFilter{Author: "alice", AuthorMatch: AuthorExact}},
+		{name: "partial author", filter: ReviewFilter{Author: "ali", AuthorMatch: AuthorPartial}},
+		{name: "unknown author match", filter: ReviewFilter{AuthorMatch: "prefix"}, errors: map[string]string{"author_match": "must be exact or partial"}},
+		{name: "empty author match", filter: ReviewFilter{}, errors: map[string]string{"author_match": "must be exact or partial"}},
+		{name: "rating bounds", filter: ReviewFilter{AuthorMatch: AuthorExact, RatingMin: 0.5, RatingMax: 5}},
+		{name: "single rating", filter: ReviewFilter{AuthorMatch: AuthorExact, RatingMin: 3.5, RatingMax: 3.5}},
+		{name: "only rating_max", filter: ReviewFilter{AuthorMatch: AuthorExact, RatingMax: 2}},
+		{name: "rating_min too low", filter: ReviewFilter{AuthorMatch: AuthorExact, RatingMin: 0.4}, errors: map[string]string{"rating_min": "must be between 0.5 and 5.0"}},
+		{name: "rating_min negative", filter: ReviewFilter{AuthorMatch: AuthorExact, RatingMin: -1}, errors: map[string]string{"rating_min": "must be between 0.5 and 5.0"}},
+		{name: "rating_max too high", filter: ReviewFilter{AuthorMatch: AuthorExact, RatingMax: 5.1}, errors: map[string]string{"rating_max": "must be between 0.5 and 5.0"}},
+		{name: "rating NaN", filter: ReviewFilter{AuthorMatch: AuthorExact, RatingMin: math.NaN()}, errors: map[string]string{"rating_min": "must be between 0.5 and 5.0"}},
+		{name: "rating_max below rating_min", filter: ReviewFilter{AuthorMatch: AuthorExact, RatingMin: 4, RatingMax: 3.5}, errors: map[string]string{"rating_max": "must not be less than rating_min"}},
+		{name: "created range", filter: ReviewFilter{AuthorMatch: AuthorExact, CreatedFrom: day, CreatedTo: day.AddDate(0, 0, 1)}},
+		{name: "only created_from", filter: ReviewFilter{AuthorMatch: AuthorExact, CreatedFrom: day}},
+		{name: "only created_to", filter: ReviewFilter{AuthorMatch: AuthorExact, CreatedTo: day}},
+		{name: "empty created range", filter: ReviewFilter{AuthorMatch: AuthorExact, CreatedFrom: day, CreatedTo: day}, errors: map[string]string{"created_to": "must be after created_from"}},
+		{name: "created_to before created_from", filter: ReviewFilter{AuthorMatch: AuthorExact, CreatedFrom: day, CreatedTo: day.Add(-time.Second)}, errors: map[string]string{"created_to": "must be after created_from"}},
+		{name: "longest query", filter: ReviewFilter{AuthorMatch: AuthorExact, Query: strings.Repeat("a", 200)}},
+		{name: "query too long", filter: ReviewFilter{AuthorMatch: AuthorExact, Query: strings.Repeat("я", 101)}, errors: map[string]string{"q": "must not be more than 200 bytes long"}},
+		{
+			name:   "several errors",
+			filter: ReviewFilter{AuthorMatch: "any", RatingMin: 6, CreatedFrom: day, CreatedTo: day},
+			errors: map[string]string{"author_match": "must be exact or partial", "rating_min": "must be between 0.5 and 5.0", "created_to": "must be after created_from"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateReviewFilter(v, tt.filter)
+			if len(v.Errors) == 0 && tt.errors == nil {
+				return
+			}
+			if !reflect.DeepEqual(v.Errors, tt.errors) {
+				t.Errorf("errors = %v, want %v", v.Errors, tt.errors)
+			}
+		})
+	}
+}
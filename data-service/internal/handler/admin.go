@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"data-service/internal/problem"
+	"github.com/gin-gonic/gin"
+	"strings"
+)
+
+// requireAdmin пропускает только запросы с заголовком
+// Authorization: Bearer <ADMIN_TOKEN>. Без настроенного токена
+// административные маршруты закрыты для всех.
+func (h *Handler) requireAdmin(c *gin.Context) {
+	if h.adminToken == "" {
+		problem.Forbidden(c, "admin endpoints are disabled")
+		return
+	}
+
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) != 1 {
+		c.Header("WWW-Authenticate", `Bearer realm="admin"`)
+		problem.Unauthorized(c, "a valid admin token is required")
+		return
+	}
+	c.Next()
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"data-service/internal/problem"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireAdmin(t *testing.T) {
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		status        int
+		code          string
+	}{
+		{name: "valid token", token: "secret", authorization: "Bearer secret", status: http.StatusNoContent},
+		{name: "no header", token: "secret", status: http.StatusUnauthorized, code: problem.CodeUnauthorized},
+		{name: "wrong token", token: "secret", authorization: "Bearer secreT", status: http.StatusUnauthorized, code: problem.CodeUnauthorized},
+		{name: "wrong scheme", token: "secret", authorization: "Basic secret", status: http.StatusUnauthorized, code: problem.CodeUnauthorized},
+		{name: "disabled", authorization: "Bearer ", status: http.StatusForbidden, code: problem.CodeForbidden},
+	}
+
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handler{adminToken: tt.token}
+			router := gin.New()
+			router.DELETE("/purge", h.requireAdmin, func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+			req := httptest.NewRequest(http.MethodDelete, "/purge", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.code == "" {
+				return
+			}
+			var p problem.Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if p.Code != tt.code {
+				t.Errorf("code = %q, want %q", p.Code, tt.code)
+			}
+		})
+	}
+}
+
+func TestPurgeRequiresAdmin(t *testing.T) {
+	router := newTestRouter(t)
+	for _, path := range []string{"/api/trash/movies/5", "/v2/trash/reviews/7"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, path, nil))
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("DELETE %s: status = %d, want %d", path, rec.Code, http.StatusForbidden)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"data-service/internal/models"
+	"data-service/internal/problem"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+)
+
+// modelError переводит ошибки слоя models в problem-ответ и не отдаёт
+// клиенту текст ошибок GORM. Запись из корзины даёт 410 с путём для
+// восстановления, несуществующая — 404.
+func (h *Handler) modelError(c *gin.Context, err error, notFound string) {
+	var deleted *models.DeletedError
+	switch {
+	case errors.Is(err, models.ErrRecordNotFound):
+		problem.NotFound(c, notFound)
+	case errors.As(err, &deleted):
+		problem.Gone(c, "the record has been deleted, it can be restored until it is purged from the trash",
+			restorePath(c, deleted))
+	case errors.Is(err, models.ErrEditConflict):
+		problem.Conflict(c, "unable to update the record due to an edit conflict, please try again")
+	case errors.Is(err, models.ErrMovieDeleted):
+		problem.Conflict(c, "the movie of this review is deleted, restore the movie first")
+	default:
+		h.serverError(c, err, "the server encountered a problem and could not process the request")
+	}
+}
+
+// writeError — modelError для запросов с If-Match: конфликт версий значит,
+// что запись изменилась после проверки предусловия.
+func (h *Handler) writeError(c *gin.Context, err error, notFound string) {
+	if errors.Is(err, models.ErrEditConflict) {
+		problem.PreconditionFailed(c, "the record has been modified, fetch it again and retry with the new ETag")
+		return
+	}
+	h.modelError(c, err, notFound)
+}
+
+func (h *Handler) serverError(c *gin.Context, err error, detail string) {
+	h.logger.ErrorContext(c.Request.Context(), detail, "error", err, "method", c.Request.Method, "path", c.Request.URL.Path)
+	problem.Internal(c, detail)
+}
+
+// restorePath строит путь восстановления записи в той же версии API, что
+// и запрос. Для отзыва, удалённого вместе с фильмом, это путь фильма.
+func restorePath(c *gin.Context, deleted *models.DeletedError) string {
+	prefix := "/api"
+	if isV2(c) {
+		prefix = "/v2"
+	}
+	if deleted.MovieID != 0 {
+		return fmt.Sprintf("%s/movies/%d/restore", prefix, deleted.MovieID)
+	}
+	return fmt.Sprintf("%s/%s/%d/restore", prefix, deleted.Table, deleted.ID)
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"data-service/internal/problem"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var deletedAt = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// deletedMovie и deletedReview — ответы на запрос missingError о записи в
+// корзине.
+func deletedMovie(id int64) fakeRule {
+	return fakeRule{
+		match: `FROM "movies" WHERE id = $1 AND deleted_at IS NOT NULL`,
+		cols:  []string{"id", "deleted_at"},
+		rows:  [][]driver.Value{{id, deletedAt}},
+	}
+}
+
+func deletedReview(id, movieID int64, withMovie bool) fakeRule {
+	return fakeRule{
+		match: `JOIN movies m ON m.id = r.movie_id WHERE r.id = $1 AND r.deleted_at IS NOT NULL`,
+		cols:  []string{"id", "deleted_at", "movie_id", "with_movie"},
+		rows:  [][]driver.Value{{id, deletedAt, movieID, withMovie}},
+	}
+}
+
+func TestMissingRecords(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		rules   []fakeRule
+		status  int
+		code    string
+		restore string
+	}{
+		{
+			name:   "unknown movie",
+			method: http.MethodGet,
+			path:   "/api/movies/42",
+			status: http.StatusNotFound,
+			code:   problem.CodeNotFound,
+		},
+		{
+			name:   "delete unknown movie",
+			method: http.MethodDelete,
+			path:   "/api/movies/42",
+			status: http.StatusNotFound,
+			code:   problem.CodeNotFound,
+		},
+		{
+			name:   "unknown review",
+			method: http.MethodGet,
+			path:   "/v2/reviews/42",
+			status: http.StatusNotFound,
+			code:   problem.CodeNotFound,
+		},
+		{
+			name:    "deleted movie",
+			method:  http.MethodGet,
+			path:    "/api/movies/5",
+			rules:   []fakeRule{deletedMovie(5)},
+			status:  http.StatusGone,
+			code:    problem.CodeGone,
+			restore: "/api/movies/5/restore",
+		},
+		{
+			name:    "deleted movie v2",
+			method:  http.MethodGet,
+			path:    "/v2/movies/5",
+			rules:   []fakeRule{deletedMovie(5)},
+			status:  http.StatusGone,
+			code:    problem.CodeGone,
+			restore: "/v2/movies/5/restore",
+		},
+		{
+			name:    "delete deleted movie",
+			method:  http.MethodDelete,
+			path:    "/api/movies/5",
+			rules:   []fakeRule{deletedMovie(5)},
+			status:  http.StatusGone,
+			code:    problem.CodeGone,
+			restore: "/api/movies/5/restore",
+		},
+		{
+			name:    "deleted review",
+			method:  http.MethodGet,
+			path:    "/api/reviews/7",
+			rules:   []fakeRule{deletedReview(7, 3, false)},
+			status:  http.StatusGone,
+			code:    problem.CodeGone,
+			restore: "/api/reviews/7/restore",
+		},
+		{
+			name:    "review deleted with its movie",
+			method:  http.MethodGet,
+			path:    "/api/reviews/7",
+			rules:   []fakeRule{deletedReview(7, 3, true)},
+			status:  http.StatusGone,
+			code:    problem.CodeGone,
+			restore: "/api/movies/3/restore",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newTestRouter(t, tt.rules...)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, tt.status, rec.Body)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != problem.ContentType {
+				t.Errorf("Content-Type = %q, want %q", ct, problem.ContentType)
+			}
+
+			var p problem.Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if p.Status != tt.status || p.Code != tt.code {
+				t.Errorf("status, code = %d, %q, want %d, %q", p.Status, p.Code, tt.status, tt.code)
+			}
+			if p.Type != "urn:reviews-movies:problem:"+tt.code || p.Title == "" || p.Detail == "" {
+				t.Errorf("incomplete problem: %+v", p)
+			}
+			if p.Instance != tt.path {
+				t.Errorf("instance = %q, want %q", p.Instance, tt.path)
+			}
+			if p.Restore != tt.restore {
+				t.Errorf("restore = %q, want %q", p.Restore, tt.restore)
+			}
+		})
+	}
+}
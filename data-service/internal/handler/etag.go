@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"data-service/internal/data"
+	"data-service/internal/problem"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/plugin/optimisticlock"
+	"hash/fnv"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// etag строит ETag из версии optimisticlock: каждое изменение записи
+// увеличивает версию, поэтому других признаков представления не нужно.
+func etag(version optimisticlock.Version) string {
+	return `"` + strconv.FormatInt(version.Int64, 10) + `"`
+}
+
+// fieldsetETag — ETag представления по fields. Выборочное представление
+// отличается от полного при той же версии, поэтому к версии добавляется
+// FNV-1a от отсортированного списка полей. If-Match по-прежнему принимает
+// только ETag полного представления.
+func fieldsetETag(version optimisticlock.Version, fs data.Fieldset) string {
+	if len(fs.Fields) == 0 {
+		return etag(version)
+	}
+	h := fnv.New32a()
+	h.Write([]byte(strings.Join(slices.Sorted(slices.Values(fs.Fields)), ",")))
+	return fmt.Sprintf(`"%d-%08x"`, version.Int64, h.Sum32())
+}
+
+// notModified выставляет ETag представления и отвечает 304, если
+// If-None-Match совпал с ним. Сравнение слабое, как требует RFC 9110.
+func notModified(c *gin.Context, version optimisticlock.Version, fs data.Fieldset) bool {
+	tag := fieldsetETag(version, fs)
+	c.Header("ETag", tag)
+
+	for _, candidate := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == tag {
+			c.AbortWithStatus(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// preconditionMet проверяет обязательный If-Match для изменяющих запросов.
+// Без заголовка отвечает 428, при несовпадении версии — 412. Слабые ETag
+// для If-Match не подходят.
+func preconditionMet(c *gin.Context, version optimisticlock.Version) bool {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		problem.PreconditionRequired(c, "this request must be conditional, send If-Match with the ETag of the record")
+		return false
+	}
+
+	tag := etag(version)
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+
+	problem.PreconditionFailed(c, "the record has been modified, fetch it again and retry with the new ETag")
+	return false
+}
@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"data-service/internal/data"
+	"database/sql"
+	"gorm.io/plugin/optimisticlock"
+	"testing"
+)
+
+// Шлюз строит тот же ETag в api-service/internal/handler/etag.go: значения
+// в тестах обоих сервисов должны совпадать.
+func TestFieldsetETag(t *testing.T) {
+	version := optimisticlock.Version(sql.NullInt64{Int64: 3, Valid: true})
+	tests := []struct {
+		name   string
+		fields []string
+		want   string
+	}{
+		{name: "full", want: `"3"`},
+		{name: "sparse", fields: []string{"title", "year"}, want: `"3-0775d28a"`},
+		{name: "sparse reordered", fields: []string{"year", "title"}, want: `"3-0775d28a"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fieldsetETag(version, data.Fieldset{Fields: tt.fields}); got != tt.want {
+				t.Errorf("fieldsetETag = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,324 @@
+package handler
+
+import (
+	"cmp"
+	"compress/gzip"
+	"data-service/internal/data"
+	"data-service/internal/metrics"
+	"data-service/internal/models"
+	"data-service/internal/problem"
+	"data-service/internal/validator"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	mimeNDJSON = "application/x-ndjson"
+	mimeCSV    = "text/csv"
+
+	// exportFlushEvery — через сколько строк выгрузка сбрасывается клиенту.
+	exportFlushEvery = 500
+
+	// exportErrorTrailer сообщает клиенту, что выгрузка оборвалась на середине:
+	// статус 200 к этому моменту уже отправлен.
+	exportErrorTrailer = "X-Export-Error"
+)
+
+// movieColumns и reviewColumns — колонки CSV; они же допустимые значения
+// параметра fields.
+var (
+	movieColumns  = []string{"id", "correlation_id", "title", "year", "runtime", "genres", "version", "created_at", "updated_at"}
+	reviewColumns = []string{"id", "correlation_id", "movie_id", "rating", "comment", "author", "version", "created_at", "updated_at"}
+)
+
+func (h *Handler) ExportMoviesHandler(c *gin.Context) {
+	filters, ok := exportFilters(c, movieSortSafelist, models.MovieFilterFields)
+	if !ok {
+		return
+	}
+
+	out, ok := newExport(c, "movies", movieColumns, movieRecord, movieResponse)
+	if !ok {
+		return
+	}
+	err := h.models.Movies.Stream(c.Request.Context(), c.Query("title"), c.QueryArray("genres"), filters, out.write)
+	h.finishExport(c, out.exportStream, err)
+}
+
+func (h *Handler) ExportReviewsHandler(c *gin.Context) {
+	filter, ok := h.reviewFilter(c)
+	if !ok {
+		return
+	}
+	filters, ok := exportFilters(c, reviewSortSafelist, models.ReviewFilterFields)
+	if !ok {
+		return
+	}
+
+	out, ok := newExport(c, "reviews", reviewColumns, reviewRecord, reviewResponse)
+	if !ok {
+		return
+	}
+	err := h.models.Reviews.Stream(c.Request.Context(), filter, filters, out.write)
+	h.finishExport(c, out.exportStream, err)
+}
+
+// exportFilters проверяет сортировку и выражение фильтра. Пагинации
+// у выгрузки нет.
+func exportFilters(c *gin.Context, safelist []string, fields data.FilterFields) (data.Filters, bool) {
+	filters := data.Filters{
+		Sort:         c.DefaultQuery("sort", "id"),
+		SortSafelist: safelist,
+		Filter:       c.Query("filter"),
+		FilterFields: fields,
+	}
+
+	v := validator.New()
+	v.Check(validator.PermittedValue(filters.Sort, safelist...), "sort", "invalid sort value")
+	if data.ValidateFilterExpr(v, filters); !v.Valid() {
+		problem.Validation(c, v.Errors)
+		return filters, false
+	}
+	return filters, true
+}
+
+// exportStream пишет строки выгрузки в ответ по мере чтения курсора.
+// Заголовки и первые байты уходят клиенту только с первой строкой, поэтому
+// ошибку запроса к базе ещё можно вернуть обычным problem-ответом.
+type exportStream struct {
+	c        *gin.Context
+	resource string
+	format   string
+	columns  []string
+
+	gz   *gzip.Writer
+	w    io.Writer
+	csv  *csv.Writer
+	json *json.Encoder
+
+	started bool
+	rows    int
+}
+
+type export[T any] struct {
+	*exportStream
+	record func(*T) []string
+	// present выбирает JSON-представление строки по версии API.
+	present func(*gin.Context, *T) any
+}
+
+// newExport выбирает формат по Accept и сжатие по Accept-Encoding.
+func newExport[T any](c *gin.Context, resource string, columns []string, record func(*T) []string, present func(*gin.Context, *T) any) (*export[T], bool) {
+	format := negotiateFormat(c, mimeNDJSON, mimeCSV)
+	if format == "" {
+		problem.NotAcceptable(c, "supported formats are "+mimeNDJSON+" and "+mimeCSV)
+		return nil, false
+	}
+
+	s := &exportStream{c: c, resource: resource, format: format, columns: columns, w: c.Writer}
+	if acceptsGzip(c.GetHeader("Accept-Encoding")) {
+		s.gz = gzip.NewWriter(c.Writer)
+		s.w = s.gz
+	}
+	if format == mimeCSV {
+		s.csv = csv.NewWriter(s.w)
+	} else {
+		s.json = json.NewEncoder(s.w)
+	}
+	return &export[T]{exportStream: s, record: record, present: present}, true
+}
+
+func (e *export[T]) write(v *T) error {
+	e.start()
+	var err error
+	if e.csv != nil {
+		err = e.csv.Write(e.record(v))
+	} else {
+		err = e.json.Encode(e.present(e.c, v))
+	}
+	if err != nil {
+		return err
+	}
+
+	e.rows++
+	if e.rows%exportFlushEvery == 0 {
+		return e.flush()
+	}
+	return nil
+}
+
+// start отправляет заголовки ответа и строку заголовков CSV.
+func (s *exportStream) start() {
+	if s.started {
+		return
+	}
+	s.started = true
+
+	ext := "ndjson"
+	if s.csv != nil {
+		ext = "csv"
+	}
+	header := s.c.Writer.Header()
+	header.Set("Content-Type", s.format+"; charset=utf-8")
+	header.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, s.resource, ext))
+	header.Add("Vary", "Accept")
+	header.Add("Vary", "Accept-Encoding")
+	header.Set("Trailer", exportErrorTrailer)
+	if s.gz != nil {
+		header.Set("Content-Encoding", "gzip")
+	}
+	s.c.Status(http.StatusOK)
+
+	// Выгрузка идёт дольше WriteTimeout сервера — снимаем дедлайн записи
+	// для этого соединения.
+	_ = http.NewResponseController(s.c.Writer).SetWriteDeadline(time.Time{})
+
+	if s.csv != nil {
+		_ = s.csv.Write(s.columns)
+	}
+}
+
+func (s *exportStream) flush() error {
+	if s.csv != nil {
+		s.csv.Flush()
+		if err := s.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if s.gz != nil {
+		if err := s.gz.Flush(); err != nil {
+			return err
+		}
+	}
+	s.c.Writer.Flush()
+	return nil
+}
+
+func (h *Handler) finishExport(c *gin.Context, s *exportStream, err error) {
+	metrics.ExportRows.WithLabelValues(s.resource, s.format).Add(float64(s.rows))
+
+	if err != nil {
+		if !s.started {
+			h.serverError(c, err, "failed to export "+s.resource)
+			return
+		}
+		// Поток gzip намеренно не закрывается: обрезанный архив клиент
+		// не примет за полную выгрузку.
+		_ = s.flush()
+		c.Writer.Header().Set(exportErrorTrailer, "export interrupted")
+		h.logger.ErrorContext(c.Request.Context(), "export interrupted",
+			"resource", s.resource, "rows", s.rows, "error", err)
+		return
+	}
+
+	s.start()
+	if err := s.flush(); err == nil && s.gz != nil {
+		err = s.gz.Close()
+	}
+	if err != nil {
+		h.logger.ErrorContext(c.Request.Context(), "export flush failed", "resource", s.resource, "error", err)
+	}
+}
+
+func movieRecord(m *data.Movie) []string {
+	return []string{
+		strconv.FormatUint(uint64(m.ID), 10),
+		m.CorrelationId.String(),
+		m.Title,
+		strconv.Itoa(int(m.Year)),
+		strconv.Itoa(int(m.Runtime)),
+		strings.Join(m.Genres, "|"),
+		strconv.FormatInt(m.Version.Int64, 10),
+		m.CreatedAt.UTC().Format(time.RFC3339),
+		m.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+func reviewRecord(r *data.Review) []string {
+	return []string{
+		strconv.FormatUint(uint64(r.ID), 10),
+		r.CorrelationId.String(),
+		strconv.FormatUint(uint64(r.MovieId), 10),
+		strconv.FormatFloat(float64(r.Rating), 'f', 1, 32),
+		r.Comment,
+		r.Author,
+		strconv.FormatInt(r.Version.Int64, 10),
+		r.CreatedAt.UTC().Format(time.RFC3339),
+		r.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// acceptsGzip проверяет Accept-Encoding с учётом "gzip;q=0": явный gzip
+// важнее "*".
+func acceptsGzip(header string) bool {
+	gzipQ, anyQ := -1.0, -1.0
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		switch coding = strings.TrimSpace(coding); {
+		case strings.EqualFold(coding, "gzip"):
+			gzipQ = qvalue(params)
+		case coding == "*":
+			anyQ = qvalue(params)
+		}
+	}
+	if gzipQ >= 0 {
+		return gzipQ > 0
+	}
+	return anyQ > 0
+}
+
+// negotiateFormat выбирает формат по Accept с учётом q. c.NegotiateFormat
+// смотрит только на порядок типов и выдал бы "text/csv;q=0".
+func negotiateFormat(c *gin.Context, offered ...string) string {
+	accept := c.GetHeader("Accept")
+	if strings.TrimSpace(accept) == "" {
+		return offered[0]
+	}
+
+	type mediaRange struct {
+		typ string
+		q   float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		typ, params, _ := strings.Cut(part, ";")
+		if typ = strings.TrimSpace(typ); typ != "" {
+			if q := qvalue(params); q > 0 {
+				ranges = append(ranges, mediaRange{typ: typ, q: q})
+			}
+		}
+	}
+	if len(ranges) == 0 {
+		return ""
+	}
+	slices.SortStableFunc(ranges, func(a, b mediaRange) int {
+		return cmp.Compare(b.q, a.q)
+	})
+
+	accepted := make([]string, len(ranges))
+	for i, r := range ranges {
+		accepted[i] = r.typ
+	}
+	c.SetAccepted(accepted...)
+	return c.NegotiateFormat(offered...)
+}
+
+// qvalue возвращает параметр q из параметров заголовка, по умолчанию 1.
+func qvalue(params string) float64 {
+	for _, param := range strings.Split(params, ";") {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+			if q, err := strconv.ParseFloat(v, 64); err == nil {
+				return q
+			}
+		}
+	}
+	return 1
+}
@@ -0,0 +1,294 @@
+package handler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"data-service/internal/problem"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var exportTime = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// exportMovies отвечает на запрос выгрузки фильмов двумя строками; с err
+// выборка обрывается после них.
+func exportMovies(err error) fakeRule {
+	return fakeRule{
+		match: `FROM "movies"`,
+		cols:  []string{"id", "correlation_id", "title", "year", "runtime", "genres", "version", "created_at", "updated_at"},
+		rows: [][]driver.Value{
+			{int64(1), "4f8a3b0e-2c1d-4e5f-8a9b-0c1d2e3f4a5b", "Heat", int64(1995), int64(170), []byte(`["Crime","Drama"]`), int64(2), exportTime, exportTime},
+			{int64(2), "5f8a3b0e-2c1d-4e5f-8a9b-0c1d2e3f4a5b", "Up, \"the\" movie", int64(2009), int64(96), []byte(`["Family"]`), int64(1), exportTime, exportTime},
+		},
+		err: err,
+	}
+}
+
+const (
+	exportMoviesCSV = "id,correlation_id,title,year,runtime,genres,version,created_at,updated_at\n" +
+		"1,4f8a3b0e-2c1d-4e5f-8a9b-0c1d2e3f4a5b,Heat,1995,170,Crime|Drama,2,2024-03-01T12:00:00Z,2024-03-01T12:00:00Z\n" +
+		"2,5f8a3b0e-2c1d-4e5f-8a9b-0c1d2e3f4a5b,\"Up, \"\"the\"\" movie\",2009,96,Family,1,2024-03-01T12:00:00Z,2024-03-01T12:00:00Z\n"
+	exportMoviesNDJSON = `{"ID":1,"CreatedAt":"2024-03-01T12:00:00Z","UpdatedAt":"2024-03-01T12:00:00Z","DeletedAt":null,"correlation_id":"4f8a3b0e-2c1d-4e5f-8a9b-0c1d2e3f4a5b","title":"Heat","year":1995,"runtime":170,"genres":["Crime","Drama"],"version":2}` + "\n" +
+		`{"ID":2,"CreatedAt":"2024-03-01T12:00:00Z","UpdatedAt":"2024-03-01T12:00:00Z","DeletedAt":null,"correlation_id":"5f8a3b0e-2c1d-4e5f-8a9b-0c1d2e3f4a5b","title":"Up, \"the\" movie","year":2009,"runtime":96,"genres":["Family"],"version":1}` + "\n"
+)
+
+func TestExport(t *testing.T) {
+	errCursor := errors.New("connection reset")
+
+	tests := []struct {
+		name           string
+		path           string
+		accept         string
+		acceptEncoding string
+		rules          []fakeRule
+		status         int
+		contentType    string
+		disposition    string
+		body           string
+		code           string
+		trailer        string
+	}{
+		{
+			name:   "ndjson",
+			path:   "/api/export/movies",
+			accept: "application/x-ndjson",
+			rules:  []fakeRule{exportMovies(nil)},
+			status: http.StatusOK, contentType: "application/x-ndjson; charset=utf-8", disposition: `attachment; filename="movies.ndjson"`,
+			body: exportMoviesNDJSON,
+		},
+		{
+			name:   "csv",
+			path:   "/api/export/movies",
+			accept: "text/csv",
+			rules:  []fakeRule{exportMovies(nil)},
+			status: http.StatusOK, contentType: "text/csv; charset=utf-8", disposition: `attachment; filename="movies.csv"`,
+			body: exportMoviesCSV,
+		},
+		{
+			name:   "csv preferred by quality",
+			path:   "/api/export/movies",
+			accept: "application/x-ndjson;q=0.5, text/csv",
+			rules:  []fakeRule{exportMovies(nil)},
+			status: http.StatusOK, contentType: "text/csv; charset=utf-8", disposition: `attachment; filename="movies.csv"`,
+			body: exportMoviesCSV,
+		},
+		{
+			name:   "refused format",
+			path:   "/api/export/movies",
+			accept: "application/x-ndjson;q=0, text/*",
+			rules:  []fakeRule{exportMovies(nil)},
+			status: http.StatusOK, contentType: "text/csv; charset=utf-8", disposition: `attachment; filename="movies.csv"`,
+			body: exportMoviesCSV,
+		},
+		{
+			name:   "no accept header",
+			path:   "/api/export/movies",
+			rules:  []fakeRule{exportMovies(nil)},
+			status: http.StatusOK, contentType: "application/x-ndjson; charset=utf-8", disposition: `attachment; filename="movies.ndjson"`,
+			body: exportMoviesNDJSON,
+		},
+		{
+			name:   "any format",
+			path:   "/api/export/movies",
+			accept: "*/*",
+			rules:  []fakeRule{exportMovies(nil)},
+			status: http.StatusOK, contentType: "application/x-ndjson; charset=utf-8", disposition: `attachment; filename="movies.ndjson"`,
+			body: exportMoviesNDJSON,
+		},
+		{
+			name:   "not acceptable",
+			path:   "/api/export/movies",
+			accept: "application/json",
+			rules:  []fakeRule{exportMovies(nil)},
+			status: http.StatusNotAcceptable, contentType: problem.ContentType,
+			code: problem.CodeNotAcceptable,
+		},
+		{
+			name:   "every format refused",
+			path:   "/api/export/movies",
+			accept: "text/csv;q=0, application/x-ndjson;q=0",
+			status: http.StatusNotAcceptable, contentType: problem.ContentType,
+			code: problem.CodeNotAcceptable,
+		},
+		{
+			name:           "gzip",
+			path:           "/api/export/movies",
+			accept:         "text/csv",
+			acceptEncoding: "br, gzip",
+			rules:          []fakeRule{exportMovies(nil)},
+			status:         http.StatusOK, contentType: "text/csv; charset=utf-8", disposition: `attachment; filename="movies.csv"`,
+			body: exportMoviesCSV,
+		},
+		{
+			name:   "empty csv keeps the header row",
+			path:   "/api/export/movies",
+			accept: "text/csv",
+			status: http.StatusOK, contentType: "text/csv; charset=utf-8", disposition: `attachment; filename="movies.csv"`,
+			body: "id,correlation_id,title,year,runtime,genres,version,created_at,updated_at\n",
+		},
+		{
+			name:   "empty reviews csv",
+			path:   "/api/export/reviews",
+			accept: "text/csv",
+			status: http.StatusOK, contentType: "text/csv; charset=utf-8", disposition: `attachment; filename="reviews.csv"`,
+			body: "id,correlation_id,movie_id,rating,comment,author,version,created_at,updated_at\n",
+		},
+		{
+			name:   "failure before the first row",
+			path:   "/api/export/movies",
+			accept: "text/csv",
+			rules:  []fakeRule{{match: `FROM "movies"`, err: errCursor}},
+			status: http.StatusInternalServerError, contentType: problem.ContentType,
+			code: problem.CodeInternal,
+		},
+		{
+			name:   "failure mid-stream",
+			path:   "/api/export/movies",
+			accept: "application/x-ndjson",
+			rules:  []fakeRule{exportMovies(errCursor)},
+			status: http.StatusOK, contentType: "application/x-ndjson; charset=utf-8", disposition: `attachment; filename="movies.ndjson"`,
+			body:    exportMoviesNDJSON,
+			trailer: "export interrupted",
+		},
+		{
+			name:   "invalid sort",
+			path:   "/api/export/movies?sort=password",
+			accept: "text/csv",
+			status: http.StatusUnprocessableEntity, contentType: problem.ContentType,
+			code: problem.CodeValidationFailed,
+		},
+		{
+			name:   "invalid filter",
+			path:   "/api/export/movies?filter=password%20eq%201",
+			accept: "text/csv",
+			status: http.StatusUnprocessableEntity, contentType: problem.ContentType,
+			code: problem.CodeValidationFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newTestRouter(t, tt.rules...)
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			res := rec.Result()
+
+			if res.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d; body %s", res.StatusCode, tt.status, rec.Body)
+			}
+			if ct := res.Header.Get("Content-Type"); ct != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", ct, tt.contentType)
+			}
+
+			if tt.code != "" {
+				var p problem.Problem
+				if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+					t.Fatalf("decode body: %v", err)
+				}
+				if p.Code != tt.code {
+					t.Errorf("code = %q, want %q", p.Code, tt.code)
+				}
+				return
+			}
+
+			if cd := res.Header.Get("Content-Disposition"); cd != tt.disposition {
+				t.Errorf("Content-Disposition = %q, want %q", cd, tt.disposition)
+			}
+			if vary := res.Header.Values("Vary"); strings.Join(vary, ", ") != "Accept, Accept-Encoding" {
+				t.Errorf("Vary = %q", vary)
+			}
+
+			body := rec.Body.Bytes()
+			if tt.acceptEncoding != "" {
+				if ce := res.Header.Get("Content-Encoding"); ce != "gzip" {
+					t.Fatalf("Content-Encoding = %q, want gzip", ce)
+				}
+				gz, err := gzip.NewReader(bytes.NewReader(body))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if body, err = io.ReadAll(gz); err != nil {
+					t.Fatal(err)
+				}
+			} else if ce := res.Header.Get("Content-Encoding"); ce != "" {
+				t.Errorf("Content-Encoding = %q, want none", ce)
+			}
+			if string(body) != tt.body {
+				t.Errorf("body =\n%s\nwant\n%s", body, tt.body)
+			}
+
+			if got := res.Trailer.Get(exportErrorTrailer); got != tt.trailer {
+				t.Errorf("trailer %s = %q, want %q", exportErrorTrailer, got, tt.trailer)
+			}
+		})
+	}
+}
+
+// Оборванная gzip-выгрузка не должна распаковываться как полная.
+func TestExportGzipInterrupted(t *testing.T) {
+	router := newTestRouter(t, exportMovies(errors.New("connection reset")))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/export/movies", nil)
+	req.Header.Set("Accept", "text/csv")
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	res := rec.Result()
+
+	if res.StatusCode != http.StatusOK || res.Trailer.Get(exportErrorTrailer) == "" {
+		t.Fatalf("status = %d, trailer = %q", res.StatusCode, res.Trailer.Get(exportErrorTrailer))
+	}
+	gz, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(gz)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("read error = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	if string(body) != exportMoviesCSV {
+		t.Errorf("rows before the failure =\n%s\nwant\n%s", body, exportMoviesCSV)
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{header: "", want: false},
+		{header: "gzip", want: true},
+		{header: "GZIP", want: true},
+		{header: "deflate, gzip;q=0.5", want: true},
+		{header: "br, identity", want: false},
+		{header: "gzip;q=0", want: false},
+		{header: "gzip; q=0.0", want: false},
+		{header: "gzip;q=0.001", want: true},
+		{header: "*", want: true},
+		{header: "*;q=0", want: false},
+		{header: "*;q=0, gzip", want: true},
+		{header: "gzip;q=0, *", want: false},
+		{header: "x-gzip", want: false},
+		{header: "gzip;level=1;q=0", want: false},
+	}
+	for _, tt := range tests {
+		if got := acceptsGzip(tt.header); got != tt.want {
+			t.Errorf("acceptsGzip(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"context"
+	"data-service/internal/health"
+	"data-service/internal/models"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeRule отвечает строками rows на любой запрос, в тексте которого есть
+// match. Если задан err, выборка обрывается этой ошибкой после rows.
+type fakeRule struct {
+	match string
+	cols  []string
+	rows  [][]driver.Value
+	err   error
+}
+
+// fakeDB — драйвер database/sql для тестов обработчиков без Postgres.
+// Запрос без подходящего правила возвращает пустую выборку.
+type fakeDB struct {
+	rules []fakeRule
+}
+
+func (db *fakeDB) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: db}, nil }
+func (db *fakeDB) Driver() driver.Driver                        { return db }
+func (db *fakeDB) Open(string) (driver.Conn, error)             { return &fakeConn{db: db}, nil }
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("fakedb: prepare") }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	for _, rule := range c.db.rules {
+		if strings.Contains(query, rule.match) {
+			return &fakeRows{cols: rule.cols, rows: rule.rows, err: rule.err}, nil
+		}
+	}
+	return &fakeRows{}, nil
+}
+
+func (c *fakeConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(0), nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct {
+	cols []string
+	rows [][]driver.Value
+	err  error
+}
+
+func (r *fakeRows) Columns() []string { return r.cols }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		if r.err != nil {
+			return r.err
+		}
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// testSearchOptions — настройки поиска по умолчанию из config.LoadConfig.
+var testSearchOptions = models.SearchOptions{
+	Languages:        []string{"english", "russian"},
+	FuzzyThreshold:   0.5,
+	SuggestThreshold: 0.3,
+	SuggestMax:       20,
+}
+
+// newTestRouter собирает роутер data-service поверх fakeDB с правилами rules.
+func newTestRouter(t *testing.T, rules ...fakeRule) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	conn := sql.OpenDB(&fakeDB{rules: rules})
+	t.Cleanup(func() { conn.Close() })
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewHandler(
+		models.NewModels(db, testSearchOptions, time.Hour),
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		health.New(time.Second),
+		"",
+	)
+	return h.Routes()
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"context"
+	"data-service/internal/data"
+	"data-service/internal/problem"
+	"data-service/internal/validator"
+	"github.com/gin-gonic/gin"
+)
+
+// includedReviewsLimit — сколько последних отзывов встраивается в фильм
+// по include=reviews.
+const includedReviewsLimit = 10
+
+// movieIncludes — связи, которые можно встроить в фильм. Для отзывов
+// include не поддерживается.
+var movieIncludes = []string{"reviews", "stats"}
+
+// fieldset разбирает параметры fields и include. Имена полей те же, что
+// у колонок выгрузки.
+func fieldset(c *gin.Context, fields, includes []string) (data.Fieldset, bool) {
+	fs := data.Fieldset{
+		Fields:          data.ParseList(c.Query("fields")),
+		FieldSafelist:   fields,
+		Include:         data.ParseList(c.Query("include")),
+		IncludeSafelist: includes,
+	}
+
+	v := validator.New()
+	if data.ValidateFieldset(v, fs); !v.Valid() {
+		problem.Validation(c, v.Errors)
+		return fs, false
+	}
+	return fs, true
+}
+
+// movieViews строит представления фильмов по fs и встраивает связи из
+// include. Связи читаются одним запросом на все фильмы страницы.
+func (h *Handler) movieViews(ctx context.Context, fs data.Fieldset, movies []*data.Movie) ([]map[string]any, error) {
+	ids := make([]uint, len(movies))
+	for i, movie := range movies {
+		ids[i] = movie.ID
+	}
+
+	var related map[uint][]*data.Review
+	if fs.Includes("reviews") && len(ids) > 0 {
+		var err error
+		if related, err = h.models.Reviews.LatestByMovies(ctx, ids, includedReviewsLimit); err != nil {
+			return nil, err
+		}
+	}
+	views := make([]map[string]any, len(movies))
+	for i, movie := range movies {
+		views[i] = fs.Pick(movieView(movie))
+		if related != nil {
+			reviews := make([]map[string]any, len(related[movie.ID]))
+			for j, review := range related[movie.ID] {
+				reviews[j] = reviewView(review)
+			}
+			views[i]["reviews"] = reviews
+		}
+	}
+
+	if fs.Includes("stats") && len(ids) > 0 {
+		stats, err := h.models.Reviews.RatingStatsByMovies(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		for i, movie := range movies {
+			views[i]["stats"] = stats[movie.ID]
+		}
+	}
+	return views, nil
+}
+
+func reviewViews(fs data.Fieldset, reviews []*data.Review) []map[string]any {
+	views := make([]map[string]any, len(reviews))
+	for i, review := range reviews {
+		views[i] = fs.Pick(reviewView(review))
+	}
+	return views
+}
+
+// movieView и reviewView — представления записей для fields и include.
+// Ключи совпадают с movieColumns и reviewColumns.
+func movieView(m *data.Movie) map[string]any {
+	return map[string]any{
+		"id":             m.ID,
+		"correlation_id": m.CorrelationId,
+		"title":          m.Title,
+		"year":           m.Year,
+		"runtime":        m.Runtime,
+		"genres":         m.Genres,
+		"version":        m.Version,
+		"created_at":     m.CreatedAt,
+		"updated_at":     m.UpdatedAt,
+	}
+}
+
+func reviewView(r *data.Review) map[string]any {
+	return map[string]any{
+		"id":             r.ID,
+		"correlation_id": r.CorrelationId,
+		"movie_id":       r.MovieId,
+		"rating":         r.Rating,
+		"comment":        r.Comment,
+		"author":         r.Author,
+		"version":        r.Version,
+		"created_at":     r.CreatedAt,
+		"updated_at":     r.UpdatedAt,
+	}
+}
@@ -1,59 +1,108 @@
 package handler
 
 import (
+	"data-service/internal/health"
+	"data-service/internal/metrics"
 	"data-service/internal/models"
+	"data-service/internal/problem"
+	"data-service/internal/requestid"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"log/slog"
 )
 
 type Handler struct {
-	models *models.Models
-	logger *slog.Logger
+	models     *models.Models
+	logger     *slog.Logger
+	health     *health.Checker
+	adminToken string
 }
 
-func NewHandler(models *models.Models, logger *slog.Logger) *Handler {
+// NewHandler создаёт обработчики. adminToken открывает административные
+// маршруты; пустой выключает их.
+func NewHandler(models *models.Models, logger *slog.Logger, health *health.Checker, adminToken string) *Handler {
 	return &Handler{
-		models: models,
-		logger: logger,
+		models:     models,
+		logger:     logger,
+		health:     health,
+		adminToken: adminToken,
 	}
 }
 
 func (h *Handler) Routes() *gin.Engine {
 	router := gin.New()
 
+	router.Use(otelgin.Middleware("data-service"))
+	router.Use(requestid.Middleware())
 	router.Use(gin.LoggerWithFormatter(func(p gin.LogFormatterParams) string {
-		h.logger.Info("request", map[string]string{
-			"method": p.Method, "path": p.Path,
-			"status": fmt.Sprint(p.StatusCode), "latency": p.Latency.String(),
-		})
+		h.logger.InfoContext(p.Request.Context(), "request",
+			"method", p.Method, "path", p.Path,
+			"status", fmt.Sprint(p.StatusCode), "latency", p.Latency.String(),
+		)
 		return ""
 	}))
-	router.Use(gin.Recovery())
+	router.Use(gin.CustomRecovery(func(c *gin.Context, recovered any) {
+		h.serverError(c, fmt.Errorf("panic: %v", recovered), "the server encountered a problem and could not process the request")
+	}))
+	router.NoRoute(func(c *gin.Context) {
+		problem.NotFound(c, "the requested resource could not be found")
+	})
+	router.Use(metrics.Middleware())
+
+	router.GET("/metrics", metrics.Handler())
+	router.GET("/healthz", health.LivenessHandler)
+	router.GET("/readyz", h.health.ReadinessHandler)
+
+	h.registerAPI(router.Group("/api", apiVersion(apiV1)))
+	h.registerAPI(router.Group("/v2", apiVersion(apiV2)))
+
+	return router
+}
 
-	api := router.Group("/api")
+// registerAPI регистрирует маршруты API в группе. Группы /api и /v2
+// различаются только представлением записей в ответах.
+func (h *Handler) registerAPI(api *gin.RouterGroup) {
+	movies := api.Group("/movies")
+	{
+		movies.GET("/:id", h.GetMovieByIdHandler)
+		movies.GET("/:id/stats", h.GetMovieStatsHandler)
+		movies.GET("/suggest", h.SuggestMoviesHandler)
+		movies.PATCH("/:id", h.UpdateMovieHandler)
+		movies.DELETE("/:id", h.DeleteMovieHandler)
+		movies.POST("/:id/restore", h.RestoreMovieHandler)
+		movies.GET("/", h.ListMovieHandler)
+		movies.GET("/by-correlation/:correlation_id", h.GetMovieByCorrelation)
+		movies.GET("/top/", h.TopRatedMoviesHandler)
+		movies.GET("/without-reviews", h.GetWithoutReviews)
+		movies.GET("/variance", h.GetControversialMovies)
+		movies.GET("/avg-rating", h.GetAvgRatingByGenre)
+	}
+	reviews := api.Group("/reviews")
 	{
-		movies := api.Group("/movies")
-		{
-			movies.GET("/:id", h.GetMovieByIdHandler)
-			movies.PATCH("/:id", h.UpdateMovieHandler)
-			movies.DELETE("/:id", h.DeleteMovieHandler)
-			movies.GET("/", h.ListMovieHandler)
-			movies.GET("/by-correlation/:correlation_id", h.GetMovieByCorrelation)
-			movies.GET("/top/", h.TopRatedMoviesHandler)
-			movies.GET("/without-reviews", h.GetWithoutReviews)
-			movies.GET("/variance", h.GetControversialMovies)
-			movies.GET("/avg-rating", h.GetAvgRatingByGenre)
-		}
-		reviews := api.Group("/reviews")
-		{
-			reviews.GET("/:id", h.GetReviewByIdHandler)
-			reviews.PATCH("/:id", h.UpdateReviewHandler)
-			reviews.DELETE("/:id", h.DeleteReviewHandler)
-			reviews.GET("/", h.ListReviewHandler)
-			reviews.GET("/by-correlation/:correlation_id", h.GetReviewByCorrelation)
-		}
+		reviews.GET("/:id", h.GetReviewByIdHandler)
+		reviews.PATCH("/:id", h.UpdateReviewHandler)
+		reviews.DELETE("/:id", h.DeleteReviewHandler)
+		reviews.POST("/:id/restore", h.RestoreReviewHandler)
+		reviews.GET("/", h.ListReviewHandler)
+		reviews.GET("/by-correlation/:correlation_id", h.GetReviewByCorrelation)
 	}
+	api.GET("/search", h.SearchHandler)
 
-	return router
+	// Корзина: просмотр и окончательное удаление. Удаление необратимо,
+	// поэтому требует токена администратора.
+	trash := api.Group("/trash")
+	{
+		trash.GET("/", h.TrashHandler)
+
+		admin := trash.Group("", h.requireAdmin)
+		admin.DELETE("/movies/:id", h.PurgeMovieHandler)
+		admin.DELETE("/reviews/:id", h.PurgeReviewHandler)
+	}
+
+	export := api.Group("/export")
+	{
+		export.GET("/movies", h.ExportMoviesHandler)
+		export.GET("/reviews", h.ExportReviewsHandler)
+	}
 }
@@ -1,8 +1,11 @@
 package handler
 
 import (
+	"context"
 	"data-service/internal/data"
+	appkafka "data-service/internal/kafka"
 	"data-service/internal/models"
+	"data-service/internal/problem"
 	"data-service/internal/validator"
 	"encoding/json"
 	"errors"
@@ -22,13 +25,9 @@ type MovieInput struct {
 	CorrelationId uuid.UUID `json:"correlation_id"`
 }
 
-type MovieDTO struct {
-	ID      uint     `json:"id"`
-	Title   string   `json:"title"`
-	Year    int32    `json:"year"`
-	Runtime int32    `json:"runtime"`
-	Genres  []string `json:"genres"`
-	Version int32    `json:"version"`
+var movieSortSafelist = []string{
+	"id", "title", "year", "runtime",
+	"-id", "-title", "-year", "-runtime",
 }
 
 type MovieUpdateInput struct {
@@ -38,11 +37,11 @@ type MovieUpdateInput struct {
 	Genres  []string `json:"genres,omitempty"`
 }
 
-func (h *Handler) HandleMovieMessage(message []byte, offset kafka.Offset) error {
+func (h *Handler) HandleMovieMessage(ctx context.Context, message []byte, offset kafka.Offset) error {
 	var input MovieInput
 
 	if err := json.Unmarshal(message, &input); err != nil {
-		return fmt.Errorf("json unmarshal failed: %w", err)
+		return fmt.Errorf("%w: json unmarshal failed: %w", appkafka.ErrMalformedMessage, err)
 	}
 
 	movie := &data.Movie{
@@ -56,18 +55,23 @@ func (h *Handler) HandleMovieMessage(message []byte, offset kafka.Offset) error
 	v := validator.New()
 
 	if data.ValidateMovie(v, movie); !v.Valid() {
-		return fmt.Errorf("no correct valid movie: %s", v.Errors)
+		return fmt.Errorf("%w: no correct valid movie: %s", appkafka.ErrInvalidMessage, v.Errors)
 	}
 
-	err := h.models.Movies.Insert(movie)
+	err := h.models.Movies.Insert(ctx, movie)
 
 	if err != nil {
-		return fmt.Errorf("falied create movie: %s", v.Errors)
+		switch {
+		case errors.Is(err, models.ErrDuplicateKey):
+			return fmt.Errorf("%w: %w", appkafka.ErrDuplicateMessage, err)
+		case errors.Is(err, models.ErrRecordNotFound):
+			return fmt.Errorf("%w: %w", appkafka.ErrInvalidMessage, err)
+		default:
+			return fmt.Errorf("failed create movie: %w", err)
+		}
 	}
 
-	h.logger.Debug("KAFKA CREATE MOVIE OFFSET", map[string]string{
-		"offset": offset.String(),
-	})
+	h.logger.DebugContext(ctx, "KAFKA CREATE MOVIE OFFSET", "offset", offset.String())
 
 	return nil
 }
@@ -76,49 +80,82 @@ func (h *Handler) GetMovieByIdHandler(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil || id <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		problem.BadRequest(c, "invalid id")
 		return
 	}
 
-	movie, err := h.models.Movies.Get(uint(id))
+	fs, ok := fieldset(c, movieColumns, movieIncludes)
+	if !ok {
+		return
+	}
+
+	movie, err := h.models.Movies.Get(c.Request.Context(), uint(id), fs.Columns()...)
 	if err != nil {
-		c.JSON(http.StatusNotFound, err)
+		h.modelError(c, err, "movie not found")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"movie": movie})
+	h.writeMovie(c, fs, movie)
 }
 
 func (h *Handler) GetMovieByCorrelation(c *gin.Context) {
 	corrID, err := uuid.Parse(c.Param("correlation_id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid correlation_id"})
+		problem.BadRequest(c, "invalid correlation_id")
+		return
+	}
+	fs, ok := fieldset(c, movieColumns, movieIncludes)
+	if !ok {
 		return
 	}
-	movie, err := h.models.Movies.GetMovieByCorrelation(corrID)
+
+	movie, err := h.models.Movies.GetMovieByCorrelation(c.Request.Context(), corrID, fs.Columns()...)
 	if err != nil {
-		c.JSON(http.StatusNotFound, err)
+		h.modelError(c, err, "movie not found")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"movie": movie})
+	h.writeMovie(c, fs, movie)
+}
+
+// writeMovie отвечает фильмом в полном или выборочном представлении.
+// Встроенные отзывы и статистика меняются без изменения версии фильма,
+// поэтому с include ответ не получает ETag.
+func (h *Handler) writeMovie(c *gin.Context, fs data.Fieldset, movie *data.Movie) {
+	if len(fs.Include) == 0 && notModified(c, movie.Version, fs) {
+		return
+	}
+	if !fs.Sparse() {
+		c.JSON(http.StatusOK, gin.H{"movie": movieResponse(c, movie)})
+		return
+	}
+
+	views, err := h.movieViews(c.Request.Context(), fs, []*data.Movie{movie})
+	if err != nil {
+		h.serverError(c, err, "failed to fetch movie")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"movie": views[0]})
 }
 
 func (h *Handler) UpdateMovieHandler(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil || id == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		problem.BadRequest(c, "invalid id")
 		return
 	}
 
-	existing, err := h.models.Movies.Get(uint(id))
+	existing, err := h.models.Movies.Get(c.Request.Context(), uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "movie not found"})
+		h.modelError(c, err, "movie not found")
+		return
+	}
+	if !preconditionMet(c, existing.Version) {
 		return
 	}
 
 	var input MovieUpdateInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+		problem.BadRequest(c, "invalid input data")
 		return
 	}
 
@@ -146,34 +183,40 @@ func (h *Handler) UpdateMovieHandler(c *gin.Context) {
 	v := validator.New()
 	data.ValidateMovie(v, updates)
 	if !v.Valid() {
-		c.JSON(http.StatusUnprocessableEntity, v.Errors)
+		problem.Validation(c, v.Errors)
 		return
 	}
 
-	err = h.models.Movies.Update(updates)
+	err = h.models.Movies.Update(c.Request.Context(), updates)
 	if err != nil {
-		if errors.Is(err, models.ErrEditConflict) {
-			c.JSON(http.StatusConflict, gin.H{"error": "edit conflict"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update movie"})
-		}
+		h.writeError(c, err, "movie not found")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"movie": updates})
+	c.Header("ETag", etag(updates.Version))
+	c.JSON(http.StatusOK, gin.H{"movie": movieResponse(c, updates)})
 }
 
 func (h *Handler) DeleteMovieHandler(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil || id == 0 {
+		problem.BadRequest(c, "invalid id")
+		return
+	}
+
+	existing, err := h.models.Movies.Get(c.Request.Context(), uint(id))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		h.modelError(c, err, "movie not found")
+		return
+	}
+	if !preconditionMet(c, existing.Version) {
 		return
 	}
 
-	err = h.models.Movies.Delete(uint(id))
+	err = h.models.Movies.Delete(c.Request.Context(), existing.ID, existing.Version)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "movie not found"})
+		h.writeError(c, err, "movie not found")
 		return
 	}
 
@@ -196,42 +239,90 @@ func (h *Handler) ListMovieHandler(c *gin.Context) {
 	pageStr := c.DefaultQuery("page", "1")
 	page, err := strconv.Atoi(pageStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid page"})
+		problem.BadRequest(c, "invalid page")
 		return
 	}
 	pageSizeStr := c.DefaultQuery("page_size", "20")
 	pageSize, err := strconv.Atoi(pageSizeStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid page_size"})
+		problem.BadRequest(c, "invalid page_size")
+		return
+	}
+
+	fs, ok := fieldset(c, movieColumns, movieIncludes)
+	if !ok {
 		return
 	}
 
 	filters := data.Filters{
-		Page:     page,
-		PageSize: pageSize,
-		Sort:     sort,
-		SortSafelist: []string{
-			"id", "title", "year", "runtime",
-			"-id", "-title", "-year", "-runtime",
-		},
+		Page:         page,
+		PageSize:     pageSize,
+		Sort:         sort,
+		SortSafelist: movieSortSafelist,
+		Filter:       c.Query("filter"),
+		FilterFields: models.MovieFilterFields,
+		After:        c.Query("after"),
+		Before:       c.Query("before"),
+		Count:        c.Query("count"),
+		Columns:      fs.Columns(),
+	}
+
+	v := validator.New()
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		problem.Validation(c, v.Errors)
+		return
 	}
 
 	movies, metadata, err := h.models.Movies.GetAll(c.Request.Context(), title, genres, filters)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch movies"})
+		h.serverError(c, err, "failed to fetch movies")
 		return
 	}
 
+	if !fs.Sparse() {
+		c.JSON(http.StatusOK, gin.H{
+			"movies":   moviesResponse(c, movies),
+			"metadata": metadata,
+		})
+		return
+	}
+
+	views, err := h.movieViews(c.Request.Context(), fs, movies)
+	if err != nil {
+		h.serverError(c, err, "failed to fetch movies")
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"movies":   movies,
+		"movies":   views,
 		"metadata": metadata,
 	})
 }
 
+func (h *Handler) GetMovieStatsHandler(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil || id == 0 {
+		problem.BadRequest(c, "invalid id")
+		return
+	}
+
+	if _, err := h.models.Movies.Get(c.Request.Context(), uint(id)); err != nil {
+		h.modelError(c, err, "movie not found")
+		return
+	}
+
+	stats, err := h.models.Reviews.GetRatingStats(c.Request.Context(), uint(id))
+	if err != nil {
+		h.serverError(c, err, "failed to fetch movie stats")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"stats": stats})
+}
+
 func (h *Handler) TopRatedMoviesHandler(c *gin.Context) {
 	res, err := h.models.Movies.GetTopRated(c.Request.Context(), 10)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch top rated movies"})
+		h.serverError(c, err, "failed to fetch top rated movies")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"top_movies": res})
@@ -240,7 +331,7 @@ func (h *Handler) TopRatedMoviesHandler(c *gin.Context) {
 func (h *Handler) GetWithoutReviews(c *gin.Context) {
 	res, err := h.models.Movies.GetWithoutReviews(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch movies"})
+		h.serverError(c, err, "failed to fetch movies")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"movies_without_reviews": res})
@@ -249,7 +340,7 @@ func (h *Handler) GetWithoutReviews(c *gin.Context) {
 func (h *Handler) GetControversialMovies(c *gin.Context) {
 	res, err := h.models.Movies.GetControversialMovies(c.Request.Context(), 10)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch movies"})
+		h.serverError(c, err, "failed to fetch movies")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"movies_variance": res})
@@ -258,7 +349,7 @@ func (h *Handler) GetControversialMovies(c *gin.Context) {
 func (h *Handler) GetAvgRatingByGenre(c *gin.Context) {
 	res, err := h.models.Movies.GetAvgRatingByGenre(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch movies"})
+		h.serverError(c, err, "failed to fetch movies")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"rating_average": res})
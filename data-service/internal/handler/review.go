@@ -1,8 +1,11 @@
 package handler
 
 import (
+	"context"
 	"data-service/internal/data"
+	appkafka "data-service/internal/kafka"
 	"data-service/internal/models"
+	"data-service/internal/problem"
 	"data-service/internal/validator"
 	"encoding/json"
 	"errors"
@@ -12,6 +15,8 @@ import (
 	"github.com/google/uuid"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type ReviewInput struct {
@@ -22,11 +27,16 @@ type ReviewInput struct {
 	Author        string    `json:"author"`
 }
 
-func (h *Handler) HandleReviewMessage(message []byte, offset kafka.Offset) error {
+var reviewSortSafelist = []string{
+	"id", "rating", "author", "created_at",
+	"-id", "-rating", "-author", "-created_at",
+}
+
+func (h *Handler) HandleReviewMessage(ctx context.Context, message []byte, offset kafka.Offset) error {
 	var input ReviewInput
 
 	if err := json.Unmarshal(message, &input); err != nil {
-		return fmt.Errorf("json unmarshal failed: %w", err)
+		return fmt.Errorf("%w: json unmarshal failed: %w", appkafka.ErrMalformedMessage, err)
 	}
 
 	review := &data.Review{
@@ -40,18 +50,23 @@ func (h *Handler) HandleReviewMessage(message []byte, offset kafka.Offset) error
 	v := validator.New()
 
 	if data.ValidateReview(v, review); !v.Valid() {
-		return fmt.Errorf("no correct valid review: %s", v.Errors)
+		return fmt.Errorf("%w: no correct valid review: %s", appkafka.ErrInvalidMessage, v.Errors)
 	}
 
-	err := h.models.Reviews.Insert(review)
+	err := h.models.Reviews.Insert(ctx, review)
 
 	if err != nil {
-		return fmt.Errorf("falied create review: %s", v.Errors)
+		switch {
+		case errors.Is(err, models.ErrDuplicateKey):
+			return fmt.Errorf("%w: %w", appkafka.ErrDuplicateMessage, err)
+		case errors.Is(err, models.ErrRecordNotFound):
+			return fmt.Errorf("%w: %w", appkafka.ErrInvalidMessage, err)
+		default:
+			return fmt.Errorf("failed create review: %w", err)
+		}
 	}
 
-	h.logger.Info("KAFKA CREATE REVIEW OFFSET", map[string]string{
-		"offset": offset.String(),
-	})
+	h.logger.InfoContext(ctx, "KAFKA CREATE REVIEW OFFSET", "offset", offset.String())
 
 	return nil
 }
@@ -60,44 +75,70 @@ func (h *Handler) GetReviewByIdHandler(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil || id <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		problem.BadRequest(c, "invalid id")
 		return
 	}
 
-	review, err := h.models.Reviews.Get(uint(id))
-	if err != nil {
-		c.JSON(http.StatusNotFound, err)
+	fs, ok := fieldset(c, reviewColumns, nil)
+	if !ok {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"review": review})
+	review, err := h.models.Reviews.Get(c.Request.Context(), uint(id), fs.Columns()...)
+	if err != nil {
+		h.modelError(c, err, "review not found")
+		return
+	}
+	if notModified(c, review.Version, fs) {
+		return
+	}
+	if fs.Sparse() {
+		c.JSON(http.StatusOK, gin.H{"review": fs.Pick(reviewView(review))})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"review": reviewResponse(c, review)})
 }
 
 func (h *Handler) GetReviewByCorrelation(c *gin.Context) {
 	corrID, err := uuid.Parse(c.Param("correlation_id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid correlation_id"})
+		problem.BadRequest(c, "invalid correlation_id")
 		return
 	}
-	review, err := h.models.Reviews.GetReviewByCorrelation(corrID)
+	fs, ok := fieldset(c, reviewColumns, nil)
+	if !ok {
+		return
+	}
+
+	review, err := h.models.Reviews.GetReviewByCorrelation(c.Request.Context(), corrID, fs.Columns()...)
 	if err != nil {
-		c.JSON(http.StatusNotFound, err)
+		h.modelError(c, err, "review not found")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"review": review})
+	if notModified(c, review.Version, fs) {
+		return
+	}
+	if fs.Sparse() {
+		c.JSON(http.StatusOK, gin.H{"review": fs.Pick(reviewView(review))})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"review": reviewResponse(c, review)})
 }
 
 func (h *Handler) UpdateReviewHandler(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil || id == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		problem.BadRequest(c, "invalid id")
 		return
 	}
 
-	existing, err := h.models.Reviews.Get(uint(id))
+	existing, err := h.models.Reviews.Get(c.Request.Context(), uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "review not found"})
+		h.modelError(c, err, "review not found")
+		return
+	}
+	if !preconditionMet(c, existing.Version) {
 		return
 	}
 
@@ -106,7 +147,7 @@ func (h *Handler) UpdateReviewHandler(c *gin.Context) {
 		Comment *string  `json:"comment"`
 	}
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+		problem.BadRequest(c, "invalid input data")
 		return
 	}
 
@@ -129,34 +170,40 @@ func (h *Handler) UpdateReviewHandler(c *gin.Context) {
 	v := validator.New()
 	data.ValidateReview(v, updates)
 	if !v.Valid() {
-		c.JSON(http.StatusUnprocessableEntity, v.Errors)
+		problem.Validation(c, v.Errors)
 		return
 	}
 
-	err = h.models.Reviews.Update(updates)
+	err = h.models.Reviews.Update(c.Request.Context(), updates)
 	if err != nil {
-		if errors.Is(err, models.ErrEditConflict) {
-			c.JSON(http.StatusConflict, gin.H{"error": "edit conflict"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update review"})
-		}
+		h.writeError(c, err, "review not found")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"review": updates})
+	c.Header("ETag", etag(updates.Version))
+	c.JSON(http.StatusOK, gin.H{"review": reviewResponse(c, updates)})
 }
 
 func (h *Handler) DeleteReviewHandler(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil || id == 0 {
+		problem.BadRequest(c, "invalid id")
+		return
+	}
+
+	existing, err := h.models.Reviews.Get(c.Request.Context(), uint(id))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		h.modelError(c, err, "review not found")
+		return
+	}
+	if !preconditionMet(c, existing.Version) {
 		return
 	}
 
-	err = h.models.Reviews.Delete(uint(id))
+	err = h.models.Reviews.Delete(c.Request.Context(), existing.ID, existing.Version)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "review not found"})
+		h.writeError(c, err, "review not found")
 		return
 	}
 
@@ -164,60 +211,140 @@ func (h *Handler) DeleteReviewHandler(c *gin.Context) {
 }
 
 func (h *Handler) ListReviewHandler(c *gin.Context) {
-	movieIDStr := c.DefaultQuery("movie_id", "0")
-	movieID, err := strconv.ParseUint(movieIDStr, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid movie_id"})
+	filter, ok := h.reviewFilter(c)
+	if !ok {
 		return
 	}
-
-	author := c.DefaultQuery("author", "")
-	ratingStr := c.DefaultQuery("rating", "0")
-	rating, err := strconv.ParseFloat(ratingStr, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid rating"})
+	fs, ok := fieldset(c, reviewColumns, nil)
+	if !ok {
 		return
 	}
 
 	pageStr := c.DefaultQuery("page", "1")
 	page, err := strconv.Atoi(pageStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid page"})
+		problem.BadRequest(c, "invalid page")
 		return
 	}
 
 	pageSizeStr := c.DefaultQuery("page_size", "20")
 	pageSize, err := strconv.Atoi(pageSizeStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid page_size"})
+		problem.BadRequest(c, "invalid page_size")
 		return
 	}
 
 	sort := c.DefaultQuery("sort", "id")
 
 	filters := data.Filters{
-		Page:     page,
-		PageSize: pageSize,
-		Sort:     sort,
-		SortSafelist: []string{
-			"id", "rating", "author", "-id", "-rating", "-author",
-		},
-	}
-
-	reviews, metadata, err := h.models.Reviews.GetAll(
-		c.Request.Context(),
-		uint(movieID),
-		author,
-		float32(rating),
-		filters,
-	)
+		Page:         page,
+		PageSize:     pageSize,
+		Sort:         sort,
+		SortSafelist: reviewSortSafelist,
+		Filter:       c.Query("filter"),
+		FilterFields: models.ReviewFilterFields,
+		After:        c.Query("after"),
+		Before:       c.Query("before"),
+		Count:        c.Query("count"),
+		Columns:      fs.Columns(),
+	}
+
+	v := validator.New()
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		problem.Validation(c, v.Errors)
+		return
+	}
+
+	reviews, metadata, err := h.models.Reviews.GetAll(c.Request.Context(), filter, filters)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch reviews"})
+		h.serverError(c, err, "failed to fetch reviews")
 		return
 	}
 
+	if fs.Sparse() {
+		c.JSON(http.StatusOK, gin.H{
+			"reviews":  reviewViews(fs, reviews),
+			"metadata": metadata,
+		})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"reviews":  reviews,
+		"reviews":  reviewsResponse(c, reviews),
 		"metadata": metadata,
 	})
 }
+
+// reviewFilter разбирает параметры выборки отзывов, общие для списка и
+// выгрузки. rating — сокращение для rating_min = rating_max. Даты
+// принимаются в RFC 3339 или как YYYY-MM-DD.
+func (h *Handler) reviewFilter(c *gin.Context) (data.ReviewFilter, bool) {
+	filter := data.ReviewFilter{
+		Author:      strings.TrimSpace(c.Query("author")),
+		AuthorMatch: c.DefaultQuery("author_match", data.AuthorExact),
+		Query:       c.Query("q"),
+		Language:    c.DefaultQuery("lang", h.models.Search.Languages[0]),
+	}
+
+	movieID, err := strconv.ParseUint(c.DefaultQuery("movie_id", "0"), 10, 32)
+	if err != nil {
+		problem.BadRequest(c, "invalid movie_id")
+		return filter, false
+	}
+	filter.MovieID = uint(movieID)
+
+	for _, p := range []struct {
+		name string
+		dst  []*float64
+	}{
+		{"rating", []*float64{&filter.RatingMin, &filter.RatingMax}},
+		{"rating_min", []*float64{&filter.RatingMin}},
+		{"rating_max", []*float64{&filter.RatingMax}},
+	} {
+		value := c.Query(p.name)
+		if value == "" {
+			continue
+		}
+		r, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			problem.BadRequest(c, "invalid "+p.name)
+			return filter, false
+		}
+		for _, dst := range p.dst {
+			*dst = r
+		}
+	}
+
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{
+		{"created_from", &filter.CreatedFrom},
+		{"created_to", &filter.CreatedTo},
+	} {
+		value := c.Query(p.name)
+		if value == "" {
+			continue
+		}
+		t, err := parseTime(value)
+		if err != nil {
+			problem.BadRequest(c, "invalid "+p.name+", expected RFC 3339 or YYYY-MM-DD")
+			return filter, false
+		}
+		*p.dst = t
+	}
+
+	v := validator.New()
+	v.Check(filter.Query == "" || h.models.Search.HasLanguage(filter.Language), "lang", "must be one of "+strings.Join(h.models.Search.Languages, ", "))
+	if data.ValidateReviewFilter(v, filter); !v.Valid() {
+		problem.Validation(c, v.Errors)
+		return filter, false
+	}
+	return filter, true
+}
+
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"data-service/internal/data"
+	"data-service/internal/models"
+	"data-service/internal/problem"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReviewFilter(t *testing.T) {
+	march1 := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		query  string
+		filter data.ReviewFilter
+		status int
+		detail string
+		errors map[string]string
+	}{
+		{name: "defaults", filter: data.ReviewFilter{AuthorMatch: data.AuthorExact, Language: "english"}},
+		{
+			name:   "exact author is trimmed",
+			query:  "author=%20alice%20",
+			filter: data.ReviewFilter{Author: "alice", AuthorMatch: data.AuthorExact, Language: "english"},
+		},
+		{
+			name:   "partial author",
+			query:  "author=ali&author_match=partial",
+			filter: data.ReviewFilter{Author: "ali", AuthorMatch: data.AuthorPartial, Language: "english"},
+		},
+		{
+			name:   "unknown author match",
+			query:  "author=ali&author_match=prefix",
+			status: http.StatusUnprocessableEntity, errors: map[string]string{"author_match": "must be exact or partial"},
+		},
+		{
+			name:   "movie",
+			query:  "movie_id=7",
+			filter: data.ReviewFilter{MovieID: 7, AuthorMatch: data.AuthorExact, Language: "english"},
+		},
+		{name: "negative movie", query: "movie_id=-1", status: http.StatusBadRequest, detail: "invalid movie_id"},
+		{
+			name:   "rating sets both bounds",
+			query:  "rating=4",
+			filter: data.ReviewFilter{AuthorMatch: data.AuthorExact, RatingMin: 4, RatingMax: 4, Language: "english"},
+		},
+		{
+			name:   "rating range",
+			query:  "rating_min=3.5&rating_max=4.5",
+			filter: data.ReviewFilter{AuthorMatch: data.AuthorExact, RatingMin: 3.5, RatingMax: 4.5, Language: "english"},
+		},
+		{
+			name:   "rating_max overrides rating",
+			query:  "rating=4&rating_max=4.5",
+			filter: data.ReviewFilter{AuthorMatch: data.AuthorExact, RatingMin: 4, RatingMax: 4.5, Language: "english"},
+		},
+		{name: "rating not a number", query: "rating=good", status: http.StatusBadRequest, detail: "invalid rating"},
+		{name: "rating_min not a number", query: "rating_min=1,5", status: http.StatusBadRequest, detail: "invalid rating_min"},
+		{
+			name:   "rating out of range",
+			query:  "rating=5.5",
+			status: http.StatusUnprocessableEntity,
+			errors: map[string]string{"rating_min": "must be between 0.5 and 5.0", "rating_max": "must be between 0.5 and 5.0"},
+		},
+		{
+			name:   "rating NaN",
+			query:  "rating_max=NaN",
+			status: http.StatusUnprocessableEntity, errors: map[string]string{"rating_max": "must be between 0.5 and 5.0"},
+		},
+		{
+			name:   "inverted rating range",
+			query:  "rating_min=4&rating_max=3",
+			status: http.StatusUnprocessableEntity, errors: map[string]string{"rating_max": "must not be less than rating_min"},
+		},
+		{
+			name:   "dates",
+			query:  "created_from=2024-03-01&created_to=2024-03-02",
+			filter: data.ReviewFilter{AuthorMatch: data.AuthorExact, CreatedFrom: march1, CreatedTo: march1.AddDate(0, 0, 1), Language: "english"},
+		},
+		{
+			name:   "RFC 3339 with offset",
+			query:  "created_from=2024-03-01T03:00:00%2B03:00",
+			filter: data.ReviewFilter{AuthorMatch: data.AuthorExact, CreatedFrom: march1, Language: "english"},
+		},
+		{
+			name:   "unparsable date",
+			query:  "created_to=01.03.2024",
+			status: http.StatusBadRequest, detail: "invalid created_to, expected RFC 3339 or YYYY-MM-DD",
+		},
+		{
+			name:   "empty date range",
+			query:  "created_from=2024-03-01&created_to=2024-03-01T00:00:00Z",
+			status: http.StatusUnprocessableEntity, errors: map[string]string{"created_to": "must be after created_from"},
+		},
+		{
+			name:   "query in another language",
+			query:  "q=хороший&lang=russian",
+			filter: data.ReviewFilter{AuthorMatch: data.AuthorExact, Query: "хороший", Language: "russian"},
+		},
+		{
+			name:   "unknown language",
+			query:  "q=good&lang=german",
+			status: http.StatusUnprocessableEntity, errors: map[string]string{"lang": "must be one of english, russian"},
+		},
+		{
+			name:   "language without query",
+			query:  "lang=german",
+			filter: data.ReviewFilter{AuthorMatch: data.AuthorExact, Language: "german"},
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+	h := &Handler{models: &models.Models{Search: &models.SearchModel{Languages: testSearchOptions.Languages}}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(rec)
+			c.Request = httptest.NewRequest(http.MethodGet, "/api/reviews/?"+tt.query, nil)
+
+			filter, ok := h.reviewFilter(c)
+			if tt.status == 0 {
+				if !ok {
+					t.Fatalf("reviewFilter failed: %d %s", rec.Code, rec.Body)
+				}
+				if !filter.CreatedFrom.Equal(tt.filter.CreatedFrom) || !filter.CreatedTo.Equal(tt.filter.CreatedTo) {
+					t.Errorf("created = %v..%v, want %v..%v", filter.CreatedFrom, filter.CreatedTo, tt.filter.CreatedFrom, tt.filter.CreatedTo)
+				}
+				filter.CreatedFrom, filter.CreatedTo = tt.filter.CreatedFrom, tt.filter.CreatedTo
+				if !reflect.DeepEqual(filter, tt.filter) {
+					t.Errorf("filter = %+v, want %+v", filter, tt.filter)
+				}
+				return
+			}
+
+			if ok || rec.Code != tt.status {
+				t.Fatalf("ok, status = %v, %d, want false, %d", ok, rec.Code, tt.status)
+			}
+			var p problem.Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if p.Detail != tt.detail && tt.detail != "" || !reflect.DeepEqual(p.Errors, tt.errors) {
+				t.Errorf("detail, errors = %q, %v, want %q, %v", p.Detail, p.Errors, tt.detail, tt.errors)
+			}
+		})
+	}
+}
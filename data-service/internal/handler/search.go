@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"data-service/internal/data"
+	"data-service/internal/models"
+	"data-service/internal/problem"
+	"data-service/internal/validator"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxSearchQueryLen = 200
+
+// SearchHandler ищет по названиям фильмов и комментариям отзывов.
+// Параметры: q — запрос в синтаксисе websearch, lang — конфигурация
+// текстового поиска, type — movie или review (можно несколько раз).
+func (h *Handler) SearchHandler(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	lang := c.DefaultQuery("lang", h.models.Search.Languages[0])
+	types := c.QueryArray("type")
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil {
+		problem.BadRequest(c, "invalid page")
+		return
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if err != nil {
+		problem.BadRequest(c, "invalid page_size")
+		return
+	}
+
+	filters := data.Filters{
+		Page:         page,
+		PageSize:     pageSize,
+		Sort:         "rank",
+		SortSafelist: []string{"rank"},
+	}
+
+	v := validator.New()
+	v.Check(query != "", "q", "must be provided")
+	v.Check(len(query) <= maxSearchQueryLen, "q", "must not be more than 200 bytes long")
+	v.Check(h.models.Search.HasLanguage(lang), "lang", "must be one of "+strings.Join(h.models.Search.Languages, ", "))
+	for _, t := range types {
+		v.Check(validator.PermittedValue(t, models.SearchMovies, models.SearchReviews), "type", "must be movie or review")
+	}
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		problem.Validation(c, v.Errors)
+		return
+	}
+
+	hits, metadata, err := h.models.Search.Search(c.Request.Context(), query, lang, types, filters)
+	if err != nil {
+		h.serverError(c, err, "failed to search")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"hits":     hits,
+		"metadata": metadata,
+	})
+}
+
+// SuggestMoviesHandler дополняет название по началу (prefix) и предлагает
+// похожие названия, если в запросе опечатка.
+func (h *Handler) SuggestMoviesHandler(c *gin.Context) {
+	prefix := strings.TrimSpace(c.Query("prefix"))
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil {
+		problem.BadRequest(c, "invalid limit")
+		return
+	}
+
+	maxLimit := h.models.Search.SuggestMax
+	v := validator.New()
+	v.Check(prefix != "", "prefix", "must be provided")
+	v.Check(utf8.RuneCountInString(prefix) <= 100, "prefix", "must not be more than 100 characters long")
+	v.Check(limit > 0, "limit", "must be greater than zero")
+	v.Check(limit <= maxLimit, "limit", fmt.Sprintf("must be a maximum of %d", maxLimit))
+	if !v.Valid() {
+		problem.Validation(c, v.Errors)
+		return
+	}
+
+	suggestions, err := h.models.Search.Suggest(c.Request.Context(), prefix, limit)
+	if err != nil {
+		h.serverError(c, err, "failed to suggest movies")
+		return
+	}
+	c.JSON(http.StatusOK, suggestions)
+}
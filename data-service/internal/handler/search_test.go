@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"data-service/internal/data/dto"
+	"data-service/internal/problem"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// searchHits отвечает на запрос поиска одним фильмом и одним отзывом.
+var searchHits = fakeRule{
+	match: "ts_headline",
+	cols:  []string{"type", "id", "movie_id", "title", "rank", "total", "snippet"},
+	rows: [][]driver.Value{
+		{"movie", int64(7), int64(7), "Heat", 0.9, int64(2), "<mark>Heat</mark>"},
+		{"review", int64(3), int64(7), "Heat", 0.4, int64(2), "the &lt;best&gt; <mark>heat</mark>"},
+	},
+}
+
+func TestSearch(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		code   string
+		errors map[string]string
+	}{
+		{name: "query", path: "/api/search?q=heat", status: http.StatusOK},
+		{name: "language and types", path: "/api/search?q=heat&lang=russian&type=movie&type=review", status: http.StatusOK},
+		{name: "longest query", path: "/api/search?q=" + strings.Repeat("a", maxSearchQueryLen), status: http.StatusOK},
+		{
+			name: "missing query", path: "/api/search", status: http.StatusUnprocessableEntity, code: problem.CodeValidationFailed,
+			errors: map[string]string{"q": "must be provided"},
+		},
+		{
+			name: "blank query", path: "/api/search?q=%20%20", status: http.StatusUnprocessableEntity, code: problem.CodeValidationFailed,
+			errors: map[string]string{"q": "must be provided"},
+		},
+		{
+			name: "query too long", path: "/api/search?q=" + strings.Repeat("я", maxSearchQueryLen/2+1), status: http.StatusUnprocessableEntity, code: problem.CodeValidationFailed,
+			errors: map[string]string{"q": "must not be more than 200 bytes long"},
+		},
+		{
+			name: "unknown language", path: "/api/search?q=heat&lang=german", status: http.StatusUnprocessableEntity, code: problem.CodeValidationFailed,
+			errors: map[string]string{"lang": "must be one of english, russian"},
+		},
+		{
+			name: "unknown type", path: "/api/search?q=heat&type=movie&type=actor", status: http.StatusUnprocessableEntity, code: problem.CodeValidationFailed,
+			errors: map[string]string{"type": "must be movie or review"},
+		},
+		{
+			name: "page size out of range", path: "/api/search?q=heat&page_size=101", status: http.StatusUnprocessableEntity, code: problem.CodeValidationFailed,
+			errors: map[string]string{"page_size": "must be a maximum of 100"},
+		},
+		{name: "page not a number", path: "/api/search?q=heat&page=two", status: http.StatusBadRequest, code: problem.CodeInvalidRequest},
+		{name: "page size not a number", path: "/api/search?q=heat&page_size=ten", status: http.StatusBadRequest, code: problem.CodeInvalidRequest},
+	}
+
+	router := newTestRouter(t, searchHits)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.code != "" {
+				var p problem.Problem
+				if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+					t.Fatalf("decode body: %v", err)
+				}
+				if p.Code != tt.code || !reflect.DeepEqual(p.Errors, tt.errors) {
+					t.Errorf("code, errors = %q, %v, want %q, %v", p.Code, p.Errors, tt.code, tt.errors)
+				}
+				return
+			}
+
+			var resp struct {
+				Hits     []dto.SearchHit `json:"hits"`
+				Metadata struct {
+					TotalRecords int `json:"total_record"`
+				} `json:"metadata"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			want := []dto.SearchHit{
+				{Type: "movie", ID: 7, MovieID: 7, Title: "Heat", Snippet: "<mark>Heat</mark>", Rank: 0.9},
+				{Type: "review", ID: 3, MovieID: 7, Title: "Heat", Snippet: "the &lt;best&gt; <mark>heat</mark>", Rank: 0.4},
+			}
+			if !reflect.DeepEqual(resp.Hits, want) || resp.Metadata.TotalRecords != 2 {
+				t.Errorf("hits, total = %+v, %d", resp.Hits, resp.Metadata.TotalRecords)
+			}
+		})
+	}
+}
+
+func TestSuggestMovies(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		code   string
+		errors map[string]string
+	}{
+		{name: "prefix", path: "/api/movies/suggest?prefix=godf", status: http.StatusOK},
+		{name: "largest limit", path: "/api/movies/suggest?prefix=godf&limit=20", status: http.StatusOK},
+		{name: "longest prefix", path: "/api/movies/suggest?prefix=" + strings.Repeat("я", 100), status: http.StatusOK},
+		{
+			name: "missing prefix", path: "/api/movies/suggest", status: http.StatusUnprocessableEntity, code: problem.CodeValidationFailed,
+			errors: map[string]string{"prefix": "must be provided"},
+		},
+		{
+			name: "prefix too long", path: "/api/movies/suggest?prefix=" + strings.Repeat("я", 101), status: http.StatusUnprocessableEntity, code: problem.CodeValidationFailed,
+			errors: map[string]string{"prefix": "must not be more than 100 characters long"},
+		},
+		{
+			name: "zero limit", path: "/api/movies/suggest?prefix=godf&limit=0", status: http.StatusUnprocessableEntity, code: problem.CodeValidationFailed,
+			errors: map[string]string{"limit": "must be greater than zero"},
+		},
+		{
+			name: "limit above SuggestMax", path: "/api/movies/suggest?prefix=godf&limit=21", status: http.StatusUnprocessableEntity, code: problem.CodeValidationFailed,
+			errors: map[string]string{"limit": "must be a maximum of 20"},
+		},
+		{name: "limit not a number", path: "/api/movies/suggest?prefix=godf&limit=ten", status: http.StatusBadRequest, code: problem.CodeInvalidRequest},
+	}
+
+	router := newTestRouter(t,
+		fakeRule{match: "LIKE", cols: []string{"id", "title", "year"}, rows: [][]driver.Value{{int64(1), "The Godfather", int64(1972)}}},
+		fakeRule{match: "<%", cols: []string{"id", "title", "year", "similarity"}, rows: [][]driver.Value{{int64(2), "Godzilla", int64(1954), 0.4}}},
+	)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.code != "" {
+				var p problem.Problem
+				if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+					t.Fatalf("decode body: %v", err)
+				}
+				if p.Code != tt.code || !reflect.DeepEqual(p.Errors, tt.errors) {
+					t.Errorf("code, errors = %q, %v, want %q, %v", p.Code, p.Errors, tt.code, tt.errors)
+				}
+				return
+			}
+
+			var got dto.MovieSuggestions
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			want := dto.MovieSuggestions{
+				Completions: []dto.MovieSuggestion{{ID: 1, Title: "The Godfather", Year: 1972}},
+				DidYouMean:  []dto.MovieSuggestion{{ID: 2, Title: "Godzilla", Year: 1954, Similarity: 0.4}},
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("suggestions = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"data-service/internal/data"
+	"data-service/internal/models"
+	"data-service/internal/problem"
+	"data-service/internal/validator"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// TrashHandler показывает мягко удалённые фильмы и отзывы с датой
+// окончательного удаления. type — movie или review (можно несколько раз).
+func (h *Handler) TrashHandler(c *gin.Context) {
+	types := c.QueryArray("type")
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil {
+		problem.BadRequest(c, "invalid page")
+		return
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if err != nil {
+		problem.BadRequest(c, "invalid page_size")
+		return
+	}
+
+	filters := data.Filters{
+		Page:         page,
+		PageSize:     pageSize,
+		Sort:         "-deleted_at",
+		SortSafelist: []string{"-deleted_at"},
+	}
+
+	v := validator.New()
+	for _, t := range types {
+		v.Check(validator.PermittedValue(t, models.TrashMovies, models.TrashReviews), "type", "must be movie or review")
+	}
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		problem.Validation(c, v.Errors)
+		return
+	}
+
+	items, metadata, err := h.models.Trash.List(c.Request.Context(), types, filters)
+	if err != nil {
+		h.serverError(c, err, "failed to fetch trash")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":    items,
+		"metadata": metadata,
+	})
+}
+
+func (h *Handler) RestoreMovieHandler(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+
+	movie, err := h.models.Movies.Restore(c.Request.Context(), id)
+	if err != nil {
+		h.modelError(c, err, "movie not found in trash")
+		return
+	}
+
+	c.Header("ETag", etag(movie.Version))
+	c.JSON(http.StatusOK, gin.H{"movie": movieResponse(c, movie)})
+}
+
+func (h *Handler) RestoreReviewHandler(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+
+	review, err := h.models.Reviews.Restore(c.Request.Context(), id)
+	if err != nil {
+		h.modelError(c, err, "review not found in trash")
+		return
+	}
+
+	c.Header("ETag", etag(review.Version))
+	c.JSON(http.StatusOK, gin.H{"review": reviewResponse(c, review)})
+}
+
+// PurgeMovieHandler окончательно удаляет фильм из корзины вместе с его
+// отзывами, не дожидаясь плановой очистки.
+func (h *Handler) PurgeMovieHandler(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.models.Movies.Purge(c.Request.Context(), id); err != nil {
+		h.modelError(c, err, "movie not found in trash")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Operation purge successfully completed"})
+}
+
+func (h *Handler) PurgeReviewHandler(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.models.Reviews.Purge(c.Request.Context(), id); err != nil {
+		h.modelError(c, err, "review not found in trash")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Operation purge successfully completed"})
+}
+
+// idParam разбирает :id. При ошибке ответ уже отправлен.
+func idParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		problem.BadRequest(c, "invalid id")
+		return 0, false
+	}
+	return uint(id), true
+}
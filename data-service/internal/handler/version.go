@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"data-service/internal/data"
+	"data-service/internal/data/dto"
+	"github.com/gin-gonic/gin"
+)
+
+// Версии API. /api отдаёт записи так, как они сериализуются из моделей
+// GORM, и остаётся ради совместимости; /v2 — через представления из dto.
+const (
+	apiV1 = 1
+	apiV2 = 2
+)
+
+const apiVersionKey = "api_version"
+
+func apiVersion(version int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(apiVersionKey, version)
+		c.Next()
+	}
+}
+
+func isV2(c *gin.Context) bool {
+	return c.GetInt(apiVersionKey) >= apiV2
+}
+
+// movieResponse и остальные функции ниже выбирают представление записи
+// по версии API запроса.
+func movieResponse(c *gin.Context, m *data.Movie) any {
+	if isV2(c) {
+		return dto.NewMovie(m)
+	}
+	return m
+}
+
+func moviesResponse(c *gin.Context, movies []*data.Movie) any {
+	if isV2(c) {
+		return dto.NewMovies(movies)
+	}
+	return movies
+}
+
+func reviewResponse(c *gin.Context, r *data.Review) any {
+	if isV2(c) {
+		return dto.NewReview(r)
+	}
+	return r
+}
+
+func reviewsResponse(c *gin.Context, reviews []*data.Review) any {
+	if isV2(c) {
+		return dto.NewReviews(reviews)
+	}
+	return reviews
+}
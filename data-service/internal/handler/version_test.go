@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// versionRules отвечают на выборки фильмов и отзывов: count(*) — двумя
+// записями, остальные запросы — строками exportMovies и reviewRows.
+var versionRules = []fakeRule{
+	{match: "SELECT count(*)", cols: []string{"count"}, rows: [][]driver.Value{{int64(2)}}},
+	exportMovies(nil),
+	{
+		match: `FROM "reviews"`,
+		cols:  []string{"id", "correlation_id", "movie_id", "rating", "comment", "author", "version", "created_at", "updated_at"},
+		rows: [][]driver.Value{
+			{int64(3), "6f8a3b0e-2c1d-4e5f-8a9b-0c1d2e3f4a5b", int64(1), 4.5, "Tense", "alice", int64(1), exportTime, exportTime},
+		},
+	},
+}
+
+const (
+	movieV1 = `{"ID":1,"CreatedAt":"2024-03-01T12:00:00Z","UpdatedAt":"2024-03-01T12:00:00Z","DeletedAt":null,` +
+		`"correlation_id":"4f8a3b0e-2c1d-4e5f-8a9b-0c1d2e3f4a5b","title":"Heat","year":1995,"runtime":170,"genres":["Crime","Drama"],"version":2}`
+	movieV2 = `{"id":1,"correlation_id":"4f8a3b0e-2c1d-4e5f-8a9b-0c1d2e3f4a5b","title":"Heat","year":1995,"runtime":170,` +
+		`"genres":["Crime","Drama"],"version":2,"created_at":"2024-03-01T12:00:00Z","updated_at":"2024-03-01T12:00:00Z"}`
+	secondMovieV1 = `{"ID":2,"CreatedAt":"2024-03-01T12:00:00Z","UpdatedAt":"2024-03-01T12:00:00Z","DeletedAt":null,` +
+		`"correlation_id":"5f8a3b0e-2c1d-4e5f-8a9b-0c1d2e3f4a5b","title":"Up, \"the\" movie","year":2009,"runtime":96,"genres":["Family"],"version":1}`
+	secondMovieV2 = `{"id":2,"correlation_id":"5f8a3b0e-2c1d-4e5f-8a9b-0c1d2e3f4a5b","title":"Up, \"the\" movie","year":2009,"runtime":96,` +
+		`"genres":["Family"],"version":1,"created_at":"2024-03-01T12:00:00Z","updated_at":"2024-03-01T12:00:00Z"}`
+	reviewV1 = `{"ID":3,"CreatedAt":"2024-03-01T12:00:00Z","UpdatedAt":"2024-03-01T12:00:00Z","DeletedAt":null,` +
+		`"correlation_id":"6f8a3b0e-2c1d-4e5f-8a9b-0c1d2e3f4a5b","movie_id":1,"rating":4.5,"comment":"Tense","author":"alice","version":1}`
+	reviewV2 = `{"id":3,"correlation_id":"6f8a3b0e-2c1d-4e5f-8a9b-0c1d2e3f4a5b","movie_id":1,"rating":4.5,"comment":"Tense",` +
+		`"author":"alice","version":1,"created_at":"2024-03-01T12:00:00Z","updated_at":"2024-03-01T12:00:00Z"}`
+)
+
+// TestResponseShapes фиксирует JSON записей в /api и /v2: ключи, их
+// порядок и типы значений. Изменение любого из них ломает клиентов.
+func TestResponseShapes(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		// key — ключ ответа с записью или списком записей.
+		key  string
+		want string
+	}{
+		{name: "v1 movie", path: "/api/movies/1", key: "movie", want: movieV1},
+		{name: "v2 movie", path: "/v2/movies/1", key: "movie", want: movieV2},
+		{name: "v1 movies", path: "/api/movies/", key: "movies", want: "[" + movieV1 + "," + secondMovieV1 + "]"},
+		{name: "v2 movies", path: "/v2/movies/", key: "movies", want: "[" + movieV2 + "," + secondMovieV2 + "]"},
+		{name: "v1 review", path: "/api/reviews/3", key: "review", want: reviewV1},
+		{name: "v2 review", path: "/v2/reviews/3", key: "review", want: reviewV2},
+		{name: "v1 reviews", path: "/api/reviews/", key: "reviews", want: "[" + reviewV1 + "]"},
+		{name: "v2 reviews", path: "/v2/reviews/", key: "reviews", want: "[" + reviewV2 + "]"},
+		{name: "v1 movies export", path: "/api/export/movies", want: movieV1 + "\n" + secondMovieV1 + "\n"},
+		{name: "v2 movies export", path: "/v2/export/movies", want: movieV2 + "\n" + secondMovieV2 + "\n"},
+		{name: "v1 reviews export", path: "/api/export/reviews", want: reviewV1 + "\n"},
+		{name: "v2 reviews export", path: "/v2/export/reviews", want: reviewV2 + "\n"},
+	}
+
+	router := newTestRouter(t, versionRules...)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d; body %s", rec.Code, rec.Body)
+			}
+
+			got := rec.Body.String()
+			if tt.key != "" {
+				var body map[string]json.RawMessage
+				if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+					t.Fatalf("decode body: %v", err)
+				}
+				got = string(body[tt.key])
+			}
+			if got != tt.want {
+				t.Errorf("%s =\n%s\nwant\n%s", tt.path, got, tt.want)
+			}
+		})
+	}
+}
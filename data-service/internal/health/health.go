@@ -0,0 +1,114 @@
+package health
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+
+	checkTimeout = 2 * time.Second
+)
+
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+type Component struct {
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+	Latency string `json:"latency"`
+}
+
+type Report struct {
+	Status     string               `json:"status"`
+	CheckedAt  time.Time            `json:"checked_at"`
+	Components map[string]Component `json:"components"`
+}
+
+// Checker выполняет проверки зависимостей и кэширует результат на ttl,
+// чтобы частые запросы оркестратора не нагружали БД и брокер.
+type Checker struct {
+	checks []check
+	ttl    time.Duration
+
+	mu     sync.Mutex
+	report *Report
+}
+
+func New(ttl time.Duration) *Checker {
+	return &Checker{ttl: ttl}
+}
+
+func (c *Checker) Add(name string, fn CheckFunc) {
+	c.checks = append(c.checks, check{name: name, fn: fn})
+}
+
+func (c *Checker) Check(ctx context.Context) Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.report != nil && time.Since(c.report.CheckedAt) < c.ttl {
+		return *c.report
+	}
+
+	report := Report{
+		Status:     StatusUp,
+		CheckedAt:  time.Now(),
+		Components: make(map[string]Component, len(c.checks)),
+	}
+
+	var (
+		wg  sync.WaitGroup
+		rmu sync.Mutex
+	)
+	for _, ch := range c.checks {
+		wg.Add(1)
+		go func(ch check) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := ch.fn(ctx)
+			comp := Component{Status: StatusUp, Latency: time.Since(start).String()}
+			if err != nil {
+				comp.Status = StatusDown
+				comp.Error = err.Error()
+			}
+
+			rmu.Lock()
+			report.Components[ch.name] = comp
+			if err != nil {
+				report.Status = StatusDown
+			}
+			rmu.Unlock()
+		}(ch)
+	}
+	wg.Wait()
+
+	c.report = &report
+	return report
+}
+
+func (c *Checker) ReadinessHandler(ctx *gin.Context) {
+	report := c.Check(ctx.Request.Context())
+	status := http.StatusOK
+	if report.Status != StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+	ctx.JSON(status, report)
+}
+
+func LivenessHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": StatusUp})
+}
@@ -1,24 +1,57 @@
 package kafka
 
 import (
+	"context"
+	"data-service/internal/metrics"
+	"data-service/internal/requestid"
+	"errors"
 	"fmt"
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 	"log"
+	"log/slog"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 const (
 	sessionTimeOut = 7000 // ms
 	noTimeout      = -1
+	lagTimeout     = 5000 // ms
 )
 
+var tracer = otel.Tracer("data-service/internal/kafka")
+
+// Ошибки, которыми обработчик сообщает причину отказа от сообщения.
+var (
+	ErrMalformedMessage = errors.New("malformed message")
+	ErrInvalidMessage   = errors.New("invalid message")
+	ErrDuplicateMessage = errors.New("duplicate message")
+)
+
+type HandlerFunc func(ctx context.Context, msg []byte, offset kafka.Offset) error
+
 type Consumer struct {
-	consumer       *kafka.Consumer
+	consumer *kafka.Consumer
+	logger   *slog.Logger
+	topic    string
+	group    string
+
+	mu             sync.Mutex
+	lag            map[int32]int64
 	stop           bool
 	consumerNumber int
+
+	stopWatch chan struct{}
+	watchDone chan struct{}
 }
 
-func NewConsumer(address []string, topic, consumerGroup string) (*Consumer, error) {
+func NewConsumer(address []string, topic, consumerGroup string, logger *slog.Logger) (*Consumer, error) {
 	cfg := &kafka.ConfigMap{
 		"bootstrap.servers":  strings.Join(address, ","),
 		"group.id":           consumerGroup,
@@ -34,11 +67,15 @@ func NewConsumer(address []string, topic, consumerGroup string) (*Consumer, erro
 	}
 	return &Consumer{
 		consumer: c,
+		logger:   logger,
+		topic:    topic,
+		group:    consumerGroup,
+		lag:      make(map[int32]int64),
 		stop:     false,
 	}, nil
 }
 
-func (c *Consumer) StartWithFunc(hf func(msg []byte, offset kafka.Offset) error) {
+func (c *Consumer) StartWithFunc(hf HandlerFunc) {
 	for !c.stop {
 		kafkaMsg, err := c.consumer.ReadMessage(noTimeout)
 		if err != nil {
@@ -47,18 +84,180 @@ func (c *Consumer) StartWithFunc(hf func(msg []byte, offset kafka.Offset) error)
 		if kafkaMsg == nil {
 			continue
 		}
-		if err := hf(kafkaMsg.Value, kafkaMsg.TopicPartition.Offset); err != nil {
-			log.Printf("handler error: %v", err)
-			continue
+		c.handle(kafkaMsg, hf)
+	}
+}
+
+func (c *Consumer) handle(kafkaMsg *kafka.Message, hf HandlerFunc) {
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), headerCarrier{msg: kafkaMsg})
+	ctx = messageContext(ctx, kafkaMsg)
+
+	ctx, span := tracer.Start(ctx, "process "+c.topic,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingOperationTypeDeliver,
+			semconv.MessagingDestinationName(c.topic),
+			semconv.MessagingKafkaConsumerGroup(c.group),
+			semconv.MessagingDestinationPartitionID(strconv.Itoa(int(kafkaMsg.TopicPartition.Partition))),
+			semconv.MessagingKafkaMessageOffset(int(kafkaMsg.TopicPartition.Offset)),
+			semconv.MessagingKafkaMessageKey(string(kafkaMsg.Key)),
+		),
+	)
+	defer span.End()
+
+	start := time.Now()
+	err := hf(ctx, kafkaMsg.Value, kafkaMsg.TopicPartition.Offset)
+	metrics.KafkaHandlerDuration.WithLabelValues(c.topic).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		metrics.KafkaMessages.WithLabelValues(c.topic, "rejected").Inc()
+		metrics.KafkaRejections.WithLabelValues(c.topic, rejectReason(err)).Inc()
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		c.logger.ErrorContext(ctx, "handler error", "error", err)
+		return
+	}
+	metrics.KafkaMessages.WithLabelValues(c.topic, "processed").Inc()
+}
+
+// WatchLag раз в interval пересчитывает отставание группы: закоммиченные
+// смещения сравниваются с high watermark, полученным от брокера. Опрос не
+// зависит от входящих сообщений, поэтому отставание простаивающего или
+// зависшего потребителя тоже растёт. Останавливается в Stop.
+func (c *Consumer) WatchLag(interval time.Duration) {
+	c.stopWatch = make(chan struct{})
+	c.watchDone = make(chan struct{})
+
+	go func() {
+		defer close(c.watchDone)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			c.pollLag()
+			select {
+			case <-c.stopWatch:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// pollLag обновляет отставание по назначенным партициям. При ошибке
+// брокера остаются значения прошлого опроса.
+func (c *Consumer) pollLag() {
+	assigned, err := c.consumer.Assignment()
+	if err != nil {
+		c.logger.Warn("kafka lag poll failed", "topic", c.topic, "error", err)
+		return
+	}
+	committed, err := c.consumer.Committed(assigned, lagTimeout)
+	if err != nil {
+		c.logger.Warn("kafka lag poll failed", "topic", c.topic, "error", err)
+		return
+	}
+
+	lag := make(map[int32]int64, len(committed))
+	for _, tp := range committed {
+		low, high, err := c.consumer.QueryWatermarkOffsets(c.topic, tp.Partition, lagTimeout)
+		if err != nil {
+			c.logger.Warn("kafka lag poll failed", "topic", c.topic, "partition", tp.Partition, "error", err)
+			return
+		}
+		// Без коммита группа читает партицию с начала (auto.offset.reset).
+		offset := int64(tp.Offset)
+		if offset < 0 {
+			offset = low
 		}
+		lag[tp.Partition] = max(high-offset, 0)
+	}
+
+	c.mu.Lock()
+	previous := c.lag
+	c.lag = lag
+	c.mu.Unlock()
+
+	for partition := range previous {
+		if _, ok := lag[partition]; !ok {
+			metrics.KafkaLag.DeleteLabelValues(c.topic, strconv.Itoa(int(partition)), c.group)
+		}
+	}
+	for partition, l := range lag {
+		metrics.KafkaLag.
+			WithLabelValues(c.topic, strconv.Itoa(int(partition)), c.group).
+			Set(float64(l))
+	}
+}
+
+// Lag возвращает суммарное отставание по назначенным партициям на момент
+// последнего опроса WatchLag.
+func (c *Consumer) Lag() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var total int64
+	for _, l := range c.lag {
+		total += l
+	}
+	return total
+}
+
+func (c *Consumer) CheckMembership(_ context.Context) error {
+	if c.consumer.IsClosed() {
+		return errors.New("consumer is closed")
+	}
+	assigned, err := c.consumer.Assignment()
+	if err != nil {
+		return err
+	}
+	if len(assigned) == 0 {
+		return fmt.Errorf("no partitions of %s assigned in group %s", c.topic, c.group)
+	}
+	return nil
+}
+
+func (c *Consumer) CheckLag(maxLag int64) func(context.Context) error {
+	return func(_ context.Context) error {
+		if lag := c.Lag(); lag > maxLag {
+			return fmt.Errorf("lag %d exceeds threshold %d", lag, maxLag)
+		}
+		return nil
+	}
+}
+
+func rejectReason(err error) string {
+	switch {
+	case errors.Is(err, ErrMalformedMessage):
+		return "malformed"
+	case errors.Is(err, ErrInvalidMessage):
+		return "invalid"
+	case errors.Is(err, ErrDuplicateMessage):
+		return "duplicate"
+	default:
+		return "failed"
 	}
 }
 
 func (c *Consumer) Stop() error {
 	c.stop = true
+	if c.stopWatch != nil {
+		close(c.stopWatch)
+		<-c.watchDone
+	}
 	if _, err := c.consumer.Commit(); err != nil {
 		return err
 	}
 	log.Print("Commited offset")
 	return c.consumer.Close()
 }
+
+func messageContext(ctx context.Context, msg *kafka.Message) context.Context {
+	for _, h := range msg.Headers {
+		if h.Key == requestid.Header && len(h.Value) > 0 {
+			return requestid.NewContext(ctx, string(h.Value))
+		}
+	}
+	return ctx
+}
@@ -0,0 +1,95 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"strings"
+	"time"
+)
+
+const flushTimeout = 5000 // ms
+
+// Producer публикует сообщения в те же топики, что читают консьюмеры
+// сервиса. Нужен служебным командам вроде загрузчика MovieLens; сам
+// data-service сообщений не отправляет.
+type Producer struct {
+	producer *kafka.Producer
+}
+
+// Message — одно сообщение пакетной публикации.
+type Message struct {
+	Key   string
+	Value []byte
+}
+
+func NewProducer(address []string) (*Producer, error) {
+	p, err := kafka.NewProducer(&kafka.ConfigMap{
+		"bootstrap.servers": strings.Join(address, ","),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error with new producer: %w", err)
+	}
+	return &Producer{producer: p}, nil
+}
+
+// ProduceBatch ставит все сообщения в очередь разом и ждёт подтверждения
+// каждого. Возвращает ошибки доставки по индексам messages.
+func (p *Producer) ProduceBatch(ctx context.Context, topic string, messages []Message) []error {
+	ctx, span := tracer.Start(ctx, "publish "+topic,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingOperationTypePublish,
+			semconv.MessagingDestinationName(topic),
+			semconv.MessagingBatchMessageCount(len(messages)),
+		),
+	)
+	defer span.End()
+
+	errs := make([]error, len(messages))
+	events := make(chan kafka.Event, len(messages))
+
+	pending := 0
+	for i, m := range messages {
+		msg := &kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+			Key:            []byte(m.Key),
+			Value:          m.Value,
+			Timestamp:      time.Now(),
+			Opaque:         i,
+		}
+		otel.GetTextMapPropagator().Inject(ctx, headerCarrier{msg: msg})
+		if err := p.producer.Produce(msg, events); err != nil {
+			errs[i] = err
+			continue
+		}
+		pending++
+	}
+
+	failed := 0
+	for ; pending > 0; pending-- {
+		ev, ok := (<-events).(*kafka.Message)
+		if ok && ev.TopicPartition.Error != nil {
+			errs[ev.Opaque.(int)] = ev.TopicPartition.Error
+		}
+	}
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		span.SetStatus(codes.Error, fmt.Sprintf("%d of %d messages failed", failed, len(messages)))
+	}
+	return errs
+}
+
+func (p *Producer) Close() {
+	p.producer.Flush(flushTimeout)
+	p.producer.Close()
+}
@@ -2,23 +2,92 @@ package models
 
 import (
 	"errors"
+	"fmt"
 	"gorm.io/gorm"
+	"time"
 )
 
 var (
 	ErrRecordNotFound = errors.New("record not found")
 	ErrEditConflict   = errors.New("edit conflict")
 	ErrDuplicateKey   = errors.New("duplicate key value violates unique constraint")
+	// ErrRecordDeleted — запись есть только в корзине. Подробности — в
+	// *DeletedError.
+	ErrRecordDeleted = errors.New("record is deleted")
+	// ErrMovieDeleted — отзыв нельзя восстановить, пока его фильм в корзине.
+	ErrMovieDeleted = errors.New("movie is deleted")
 )
 
+// DeletedError сообщает, что запрошенная запись мягко удалена и её можно
+// восстановить. Table — таблица записи. MovieID заполнен у отзыва,
+// удалённого вместе с фильмом: такой отзыв возвращается восстановлением
+// фильма.
+type DeletedError struct {
+	Table     string
+	ID        uint
+	DeletedAt time.Time
+	MovieID   uint
+}
+
+func (e *DeletedError) Error() string {
+	return fmt.Sprintf("%s %d is deleted", e.Table, e.ID)
+}
+
+func (e *DeletedError) Is(target error) bool {
+	return target == ErrRecordDeleted
+}
+
 type Models struct {
 	Movies  *MovieModel
 	Reviews *ReviewModel
+	Search  *SearchModel
+	Trash   *TrashModel
+}
+
+// SearchOptions — настройки полнотекстового и нечёткого поиска.
+type SearchOptions struct {
+	Languages        []string
+	FuzzyThreshold   float64
+	SuggestThreshold float64
+	SuggestMax       int
 }
 
-func NewModels(db *gorm.DB) *Models {
+func NewModels(db *gorm.DB, search SearchOptions, trashRetention time.Duration) *Models {
 	return &Models{
-		Movies:  &MovieModel{DB: db},
+		Movies:  &MovieModel{DB: db, FuzzyThreshold: search.FuzzyThreshold},
 		Reviews: &ReviewModel{DB: db},
+		Search:  &SearchModel{DB: db, Languages: search.Languages, SuggestThreshold: search.SuggestThreshold, SuggestMax: search.SuggestMax},
+		Trash:   &TrashModel{DB: db, Retention: trashRetention},
+	}
+}
+
+// selectColumns сужает выборку до columns; без них читаются все колонки.
+func selectColumns(db *gorm.DB, columns []string) *gorm.DB {
+	if len(columns) == 0 {
+		return db
+	}
+	return db.Select(columns)
+}
+
+// missingError вызывается, когда запись по условию не нашлась, и отличает
+// запись в корзине (*DeletedError) от несуществующей (ErrRecordNotFound).
+func missingError(db *gorm.DB, table string, query any, args ...any) error {
+	var row struct {
+		ID        uint
+		DeletedAt time.Time
+	}
+	err := db.Unscoped().
+		Table(table).
+		Select("id, deleted_at").
+		Where(query, args...).
+		Where("deleted_at IS NOT NULL").
+		Limit(1).
+		Scan(&row).Error
+	if err != nil {
+		return err
+	}
+	if row.ID == 0 {
+		return ErrRecordNotFound
 	}
+	return &DeletedError{Table: table, ID: row.ID, DeletedAt: row.DeletedAt}
 }
@@ -8,20 +8,36 @@ import (
 	"errors"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/plugin/optimisticlock"
 	"strings"
 )
 
+// MovieFilterFields — поля, доступные в параметре filter списка фильмов.
+var MovieFilterFields = data.FilterFields{
+	"id":         {Column: "id", Type: data.FilterInt, Ops: data.OrderedOps},
+	"title":      {Column: "title", Type: data.FilterString, Ops: data.TextOps},
+	"year":       {Column: "year", Type: data.FilterInt, Ops: data.OrderedOps},
+	"runtime":    {Column: "runtime", Type: data.FilterInt, Ops: data.OrderedOps},
+	"genres":     {Column: "genres", Type: data.FilterStringArray, Ops: data.ArrayOps},
+	"created_at": {Column: "created_at", Type: data.FilterTime, Ops: data.OrderedOps},
+	"updated_at": {Column: "updated_at", Type: data.FilterTime, Ops: data.OrderedOps},
+}
+
 type MovieModel struct {
 	DB *gorm.DB
+	// FuzzyThreshold — порог word_similarity, при котором название
+	// считается совпавшим с фильтром title несмотря на опечатки.
+	FuzzyThreshold float64
 }
 
-func (m *MovieModel) Insert(movie *data.Movie) error {
-	res := m.DB.Create(movie)
+func (m *MovieModel) Insert(ctx context.Context, movie *data.Movie) error {
+	res := m.DB.WithContext(ctx).Create(movie)
 
 	if res.Error != nil {
 		switch {
 		case strings.Contains(res.Error.Error(), "duplicate key value violates unique constraint"):
-			return errors.New("duplicate key value violates unique constraint")
+			return ErrDuplicateKey
 		default:
 			return res.Error
 		}
@@ -29,16 +45,18 @@ func (m *MovieModel) Insert(movie *data.Movie) error {
 	return nil
 }
 
-func (m *MovieModel) GetMovieByCorrelation(corrId uuid.UUID) (*data.Movie, error) {
+// GetMovieByCorrelation и Get читают только columns, если они заданы. Запись
+// из корзины возвращается как *DeletedError.
+func (m *MovieModel) GetMovieByCorrelation(ctx context.Context, corrId uuid.UUID, columns ...string) (*data.Movie, error) {
 	var movie data.Movie
 
-	err := m.DB.
+	err := selectColumns(m.DB.WithContext(ctx), columns).
 		Where("correlation_id = ?", corrId).
 		First(&movie).Error
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, ErrRecordNotFound
+			return nil, missingError(m.DB.WithContext(ctx), "movies", "correlation_id = ?", corrId)
 		}
 		return nil, err
 	}
@@ -46,20 +64,20 @@ func (m *MovieModel) GetMovieByCorrelation(corrId uuid.UUID) (*data.Movie, error
 	return &movie, nil
 }
 
-func (m *MovieModel) Get(id uint) (*data.Movie, error) {
+func (m *MovieModel) Get(ctx context.Context, id uint, columns ...string) (*data.Movie, error) {
 	var movie data.Movie
-	err := m.DB.First(&movie, id).Error
+	err := selectColumns(m.DB.WithContext(ctx), columns).First(&movie, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, ErrRecordNotFound
+			return nil, missingError(m.DB.WithContext(ctx), "movies", "id = ?", id)
 		}
 		return nil, err
 	}
 	return &movie, nil
 }
 
-func (m *MovieModel) Update(movie *data.Movie) error {
-	result := m.DB.
+func (m *MovieModel) Update(ctx context.Context, movie *data.Movie) error {
+	result := m.DB.WithContext(ctx).
 		Model(&data.Movie{}).
 		Where("id = ? AND version = ?", movie.ID, movie.Version).
 		Updates(movie)
@@ -70,54 +88,197 @@ func (m *MovieModel) Update(movie *data.Movie) error {
 	if result.RowsAffected == 0 {
 		return ErrEditConflict
 	}
+	// optimisticlock увеличивает версию только в базе.
+	movie.Version.Int64++
 	return nil
 }
 
-func (m *MovieModel) Delete(id uint) error {
-	result := m.DB.Delete(&data.Movie{}, id)
-	return result.Error
+// Delete переносит фильм в корзину, только если его версия всё ещё равна
+// version. Отзывы фильма уходят в корзину вместе с ним с той же меткой
+// deleted_at — по ней Restore вернёт только их, а не отзывы, удалённые
+// раньше.
+func (m *MovieModel) Delete(ctx context.Context, id uint, version optimisticlock.Version) error {
+	return m.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.
+			Where("version = ?", version.Int64).
+			Delete(&data.Movie{}, id)
+
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrEditConflict
+		}
+
+		return tx.Exec(`
+			UPDATE reviews r SET deleted_at = m.deleted_at
+			FROM movies m
+			WHERE m.id = ? AND r.movie_id = m.id AND r.deleted_at IS NULL`, id).Error
+	})
+}
+
+// Restore возвращает фильм из корзины вместе с отзывами, удалёнными
+// вместе с ним. Восстановление меняет версию записи.
+func (m *MovieModel) Restore(ctx context.Context, id uint) (*data.Movie, error) {
+	err := m.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`
+			UPDATE reviews r SET deleted_at = NULL
+			FROM movies m
+			WHERE m.id = ? AND m.deleted_at IS NOT NULL
+				AND r.movie_id = m.id AND r.deleted_at = m.deleted_at`, id).Error
+		if err != nil {
+			return err
+		}
+
+		result := tx.Exec(`
+			UPDATE movies SET deleted_at = NULL, version = version + 1, updated_at = now()
+			WHERE id = ? AND deleted_at IS NOT NULL`, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m.Get(ctx, id)
+}
+
+// Purge окончательно удаляет фильм из корзины вместе со всеми его
+// отзывами. Фильм не из корзины не удаляется.
+func (m *MovieModel) Purge(ctx context.Context, id uint) error {
+	return m.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var movie data.Movie
+		err := tx.Unscoped().
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").
+			Where("deleted_at IS NOT NULL").
+			First(&movie, id).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRecordNotFound
+			}
+			return err
+		}
+
+		if err := tx.Unscoped().Where("movie_id = ?", id).Delete(&data.Review{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&data.Movie{}, id).Error
+	})
 }
 
 func (m *MovieModel) GetAll(ctx context.Context, title string, genres []string, filters data.Filters) ([]*data.Movie, data.Metadata, error) {
 	var (
-		movies       []*data.Movie
-		totalRecords int64
+		movies   []*data.Movie
+		metadata data.Metadata
 	)
 
-	db := m.DB.WithContext(ctx).Model(&data.Movie{})
+	err := m.withTitleFilter(ctx, title, func(tx *gorm.DB) error {
+		db, err := filterMovies(tx.Model(&data.Movie{}), title, genres, filters)
+		if err != nil {
+			return err
+		}
+		movies, metadata, err = paginate(db, filters, movieSortKey)
+		return err
+	})
+	if err != nil {
+		return nil, data.Metadata{}, err
+	}
+
+	return movies, metadata, nil
+}
+
+// movieSortKey — значение колонки сортировки для курсора.
+func movieSortKey(m *data.Movie, column string) (uint, any) {
+	switch column {
+	case "title":
+		return m.ID, m.Title
+	case "year":
+		return m.ID, m.Year
+	case "runtime":
+		return m.ID, m.Runtime
+	default:
+		return m.ID, m.ID
+	}
+}
+
+// Stream отдаёт фильмы по одному через курсор, не загружая выборку в память.
+// Фильтры те же, что у GetAll, но без пагинации. Ошибка fn прерывает выборку.
+func (m *MovieModel) Stream(ctx context.Context, title string, genres []string, filters data.Filters, fn func(*data.Movie) error) error {
+	return m.withTitleFilter(ctx, title, func(tx *gorm.DB) error {
+		db, err := filterMovies(tx.Model(&data.Movie{}), title, genres, filters)
+		if err != nil {
+			return err
+		}
+
+		rows, err := db.Order(filters.KeysetOrder()).Rows()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var movie data.Movie
+			if err := db.ScanRows(rows, &movie); err != nil {
+				return err
+			}
+			if err := fn(&movie); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	})
+}
+
+// withTitleFilter выполняет fn в транзакции с порогом FuzzyThreshold для
+// оператора <%, если фильтр по названию задан. Без фильтра транзакция
+// не нужна.
+func (m *MovieModel) withTitleFilter(ctx context.Context, title string, fn func(tx *gorm.DB) error) error {
+	db := m.DB.WithContext(ctx)
+	if title == "" {
+		return fn(db)
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := setWordSimilarityThreshold(tx, m.FuzzyThreshold); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+func filterMovies(db *gorm.DB, title string, genres []string, filters data.Filters) (*gorm.DB, error) {
+	expr, err := filters.FilterClause()
+	if err != nil {
+		return nil, err
+	}
+	if expr != nil {
+		db = db.Where(expr)
+	}
 
 	if title != "" {
 		tsQuery := strings.TrimSpace(title)
+		// search_vector содержит и лексемы 'simple', поэтому точное
+		// совпадение слов находится как раньше, но по индексу. Опечатки
+		// ("Godfater") ловит триграммное сравнение с порогом из
+		// withTitleFilter.
 		db = db.Where(
-			"to_tsvector('simple', title) @@ plainto_tsquery('simple', ?)",
-			tsQuery,
+			"(search_vector @@ plainto_tsquery('simple', ?) OR lower(?) <% lower(title))",
+			tsQuery, tsQuery,
 		)
 	}
 
 	if len(genres) > 0 {
 		jsonGenres, err := json.Marshal(genres)
 		if err != nil {
-			return nil, data.Metadata{}, err
+			return nil, err
 		}
 		db = db.Where("genres @> ?", jsonGenres)
 	}
-
-	if err := db.Count(&totalRecords).Error; err != nil {
-		return nil, data.Metadata{}, err
-	}
-
-	sortExpr := filters.SortColumn() + " " + filters.SortDirection() + ", id ASC"
-	db = db.Order(sortExpr)
-
-	db = db.Limit(filters.Limit()).Offset(filters.Offset())
-
-	if err := db.Find(&movies).Error; err != nil {
-		return nil, data.Metadata{}, err
-	}
-
-	metadata := data.CalculateMetadata(int(totalRecords), filters.Page, filters.PageSize)
-
-	return movies, metadata, nil
+	return db, nil
 }
 
 func (m *MovieModel) GetTopRated(ctx context.Context, limit int) ([]dto2.MovieRating, error) {
@@ -126,7 +287,8 @@ func (m *MovieModel) GetTopRated(ctx context.Context, limit int) ([]dto2.MovieRa
 	err := m.DB.WithContext(ctx).
 		Table("movies AS m").
 		Select("m.id, m.title, m.year, m.runtime, AVG(r.rating) AS avg_rating").
-		Joins("JOIN reviews r ON m.id = r.movie_id").
+		Joins("JOIN reviews r ON m.id = r.movie_id AND r.deleted_at IS NULL").
+		Where("m.deleted_at IS NULL").
 		Group("m.id").
 		Order("avg_rating DESC").
 		Limit(limit).
@@ -145,8 +307,8 @@ func (m *MovieModel) GetWithoutReviews(ctx context.Context) ([]dto2.MovieRating,
 	err := m.DB.WithContext(ctx).
 		Table("movies AS m").
 		Select("m.id, m.title, m.year, m.runtime").
-		Joins("LEFT JOIN reviews r ON m.id = r.movie_id").
-		Where("r.id IS NULL").
+		Joins("LEFT JOIN reviews r ON m.id = r.movie_id AND r.deleted_at IS NULL").
+		Where("m.deleted_at IS NULL AND r.id IS NULL").
 		Scan(&results).Error
 
 	if err != nil {
@@ -167,7 +329,8 @@ func (m *MovieModel) GetControversialMovies(ctx context.Context, limit int) ([]d
 			m.runtime,
 			VAR_SAMP(r.rating) AS variance
 		FROM movies m
-		JOIN reviews r ON m.id = r.movie_id
+		JOIN reviews r ON m.id = r.movie_id AND r.deleted_at IS NULL
+		WHERE m.deleted_at IS NULL
 		GROUP BY m.id
 		ORDER BY variance DESC
 		LIMIT ?
@@ -184,7 +347,8 @@ func (m *MovieModel) GetAvgRatingByGenre(ctx context.Context) ([]dto2.GenreRatin
 			jsonb_array_elements_text(genres) AS genre,
 			AVG(r.rating) AS avg_rating
 		FROM movies m
-		JOIN reviews r ON m.id = r.movie_id
+		JOIN reviews r ON m.id = r.movie_id AND r.deleted_at IS NULL
+		WHERE m.deleted_at IS NULL
 		GROUP BY genre
 		ORDER BY avg_rating DESC
 	`).Scan(&result).Error
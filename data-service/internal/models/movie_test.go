@@ -0,0 +1,104 @@
+package models
+
+import (
+	"context"
+	"data-service/internal/data"
+	"database/sql/driver"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// statement — запрос или команда, дошедшие до fakeDB.
+type statement struct {
+	query string
+	args  []driver.Value
+}
+
+// recordStatements открывает MovieModel поверх fakeDB, который запоминает
+// все запросы и отвечает пустой выборкой.
+func recordStatements(t *testing.T, threshold float64) (*MovieModel, *[]statement) {
+	t.Helper()
+	var stmts []statement
+	db := newTestDB(t, func(query string, named []driver.NamedValue) ([]string, [][]driver.Value) {
+		args := make([]driver.Value, len(named))
+		for i, a := range named {
+			args[i] = a.Value
+		}
+		stmts = append(stmts, statement{query: query, args: args})
+		if strings.HasPrefix(query, "SELECT count(*)") {
+			return []string{"count"}, [][]driver.Value{{int64(0)}}
+		}
+		return nil, nil
+	})
+	return &MovieModel{DB: db, FuzzyThreshold: threshold}, &stmts
+}
+
+func TestTitleFilter(t *testing.T) {
+	tests := []struct {
+		name      string
+		title     string
+		genres    []string
+		threshold float64
+		// setConfig — порог, переданный в set_config; пустой — команды
+		// быть не должно.
+		setConfig string
+		fuzzy     []driver.Value
+	}{
+		{name: "no title"},
+		{name: "genres only", genres: []string{"Crime"}},
+		{name: "typo", title: "Godfater", threshold: 0.5, setConfig: "0.5", fuzzy: []driver.Value{"Godfater", "Godfater"}},
+		{name: "trimmed", title: "  Godfater ", threshold: 0.45, setConfig: "0.45", fuzzy: []driver.Value{"Godfater", "Godfater"}},
+		{name: "with genres", title: "Heat", genres: []string{"Crime"}, threshold: 0.3, setConfig: "0.3", fuzzy: []driver.Value{"Heat", "Heat"}},
+	}
+
+	filters := data.Filters{Page: 1, PageSize: 20, Sort: "id", SortSafelist: []string{"id"}}
+	methods := map[string]func(m *MovieModel, title string, genres []string) error{
+		"GetAll": func(m *MovieModel, title string, genres []string) error {
+			_, _, err := m.GetAll(context.Background(), title, genres, filters)
+			return err
+		},
+		"Stream": func(m *MovieModel, title string, genres []string) error {
+			return m.Stream(context.Background(), title, genres, filters, func(*data.Movie) error { return nil })
+		},
+	}
+
+	for _, tt := range tests {
+		for method, call := range methods {
+			t.Run(tt.name+"/"+method, func(t *testing.T) {
+				m, stmts := recordStatements(t, tt.threshold)
+				if err := call(m, tt.title, tt.genres); err != nil {
+					t.Fatal(err)
+				}
+
+				selects := *stmts
+				if tt.setConfig != "" {
+					// Порог действует только внутри транзакции, поэтому
+					// set_config обязан идти раньше выборки.
+					first := selects[0]
+					if !strings.Contains(first.query, "set_config('pg_trgm.word_similarity_threshold', $1, true)") ||
+						!slices.Equal(first.args, []driver.Value{tt.setConfig}) {
+						t.Fatalf("first statement = %q %v, want set_config with %s", first.query, first.args, tt.setConfig)
+					}
+					selects = selects[1:]
+				}
+
+				for _, s := range selects {
+					if strings.Contains(s.query, "set_config") {
+						t.Errorf("unexpected set_config: %q", s.query)
+					}
+					fuzzy := strings.Contains(s.query, "search_vector @@ plainto_tsquery('simple', $1) OR lower($2) <% lower(title)")
+					if fuzzy != (tt.fuzzy != nil) {
+						t.Errorf("title filter = %v in %q", fuzzy, s.query)
+					}
+					if fuzzy && !slices.Equal(s.args[:2], tt.fuzzy) {
+						t.Errorf("title filter args = %v, want %v", s.args[:2], tt.fuzzy)
+					}
+					if got := strings.Contains(s.query, "genres @>"); got != (tt.genres != nil) {
+						t.Errorf("genres filter = %v in %q", got, s.query)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"data-service/internal/data"
+	"encoding/json"
+	"gorm.io/gorm"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// paginate выбирает страницу по номеру или по курсору и считает общее
+// число записей так, как просит filters.Count. db — запрос с уже
+// наложенными фильтрами; key возвращает id строки и значение колонки
+// сортировки для курсора.
+func paginate[T any](db *gorm.DB, filters data.Filters, key func(row *T, column string) (uint, any)) ([]*T, data.Metadata, error) {
+	var total int64
+	var err error
+	switch filters.Count {
+	case data.CountNone:
+	case data.CountEstimated:
+		total, err = estimateCount[T](db)
+	default:
+		err = db.Session(&gorm.Session{}).Count(&total).Error
+	}
+	if err != nil {
+		return nil, data.Metadata{}, err
+	}
+
+	q := db.Order(filters.KeysetOrder())
+	if columns := filters.Columns; len(columns) > 0 {
+		// Колонка сортировки нужна для курсора, даже если её не просили.
+		if !slices.Contains(columns, filters.SortColumn()) {
+			columns = append(slices.Clip(columns), filters.SortColumn())
+		}
+		q = q.Select(columns)
+	}
+	expr, err := filters.KeysetClause()
+	if err != nil {
+		return nil, data.Metadata{}, err
+	}
+	if expr != nil {
+		q = q.Where(expr)
+	} else {
+		q = q.Offset(filters.Offset())
+	}
+
+	// Лишняя строка показывает, есть ли что-то дальше.
+	var rows []*T
+	if err := q.Limit(filters.Limit() + 1).Find(&rows).Error; err != nil {
+		return nil, data.Metadata{}, err
+	}
+	more := len(rows) > filters.Limit()
+	if more {
+		rows = rows[:filters.Limit()]
+	}
+	if filters.Backward() {
+		slices.Reverse(rows)
+	}
+
+	var next, prev string
+	if len(rows) > 0 {
+		cursor := func(row *T) string {
+			id, value := key(row, filters.SortColumn())
+			return data.Cursor{Sort: filters.Sort, Value: cursorValue(value), ID: id}.Encode()
+		}
+		// Вперёд от страницы есть строки, если пришли назад (курсор Before)
+		// или выбралась лишняя строка; назад — если пришли вперёд по курсору
+		// или со второй и дальше страницы.
+		if more && !filters.Backward() || filters.Backward() {
+			next = cursor(rows[len(rows)-1])
+		}
+		if more && filters.Backward() || filters.After != "" || !filters.Keyset() && filters.Page > 1 {
+			prev = cursor(rows[0])
+		}
+	}
+
+	return rows, data.PageMetadata(filters, int(total), next, prev), nil
+}
+
+// cursorValue приводит значение колонки к строке, из которой Postgres
+// восстановит его без потери точности.
+func cursorValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	default:
+		js, _ := json.Marshal(v)
+		return string(js)
+	}
+}
+
+// estimateCount берёт число строк из плана запроса вместо COUNT(*).
+// Оценка тем точнее, чем свежее статистика ANALYZE.
+func estimateCount[T any](db *gorm.DB) (int64, error) {
+	stmt := db.Session(&gorm.Session{DryRun: true}).Select("1").Find(&[]*T{}).Statement
+
+	var plan []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	var raw []byte
+	err := db.Statement.ConnPool.
+		QueryRowContext(db.Statement.Context, "EXPLAIN (FORMAT JSON) "+stmt.SQL.String(), stmt.Vars...).
+		Scan(&raw)
+	if err != nil {
+		return 0, err
+	}
+	if err := json.Unmarshal(raw, &plan); err != nil || len(plan) == 0 {
+		return 0, err
+	}
+	return int64(plan[0].Plan.Rows), nil
+}
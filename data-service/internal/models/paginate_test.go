@@ -0,0 +1,311 @@
+package models
+
+import (
+	"cmp"
+	"context"
+	"data-service/internal/data"
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memTable — таблица в памяти, которая исполняет запросы paginate:
+// count(*), keyset-условие, ORDER BY, LIMIT и OFFSET.
+type memTable struct {
+	cols []string
+	rows [][]driver.Value
+}
+
+var (
+	tupleKeysetRe = regexp.MustCompile(`WHERE \((\w+), id\) ([<>]) \(\$(\d+), \$(\d+)\)`)
+	idKeysetRe    = regexp.MustCompile(`WHERE id ([<>]) \$(\d+)`)
+	orderRe       = regexp.MustCompile(`ORDER BY (\w+) (ASC|DESC)(?:, id (ASC|DESC))?`)
+	limitRe       = regexp.MustCompile(`LIMIT \$(\d+)(?: OFFSET \$(\d+))?`)
+)
+
+func (tbl *memTable) column(name string) int {
+	return slices.Index(tbl.cols, name)
+}
+
+// compare сравнивает значение колонки со значением из запроса. Курсор
+// передаёт значение строкой, как его получил бы Postgres.
+func compare(a, b driver.Value) int {
+	if s, ok := b.(string); ok {
+		switch a := a.(type) {
+		case int64:
+			n, _ := strconv.ParseInt(s, 10, 64)
+			return cmp.Compare(a, n)
+		case float64:
+			f, _ := strconv.ParseFloat(s, 64)
+			return cmp.Compare(a, f)
+		case time.Time:
+			ts, _ := time.Parse(time.RFC3339Nano, s)
+			return a.Compare(ts)
+		}
+	}
+	switch a := a.(type) {
+	case int64:
+		return cmp.Compare(a, b.(int64))
+	case float64:
+		return cmp.Compare(a, b.(float64))
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	default:
+		return cmp.Compare(a.(string), b.(string))
+	}
+}
+
+func (tbl *memTable) query(query string, args []driver.NamedValue) ([]string, [][]driver.Value) {
+	if strings.HasPrefix(query, "SELECT count(*)") {
+		return []string{"count"}, [][]driver.Value{{int64(len(tbl.rows))}}
+	}
+	arg := func(n string) driver.Value {
+		i, _ := strconv.Atoi(n)
+		return args[i-1].Value
+	}
+	id := tbl.column("id")
+
+	rows := slices.Clone(tbl.rows)
+	if m := tupleKeysetRe.FindStringSubmatch(query); m != nil {
+		col, op, value, after := tbl.column(m[1]), m[2], arg(m[3]), arg(m[4])
+		rows = slices.DeleteFunc(rows, func(row []driver.Value) bool {
+			c := cmp.Or(compare(row[col], value), compare(row[id], after))
+			return op == ">" && c <= 0 || op == "<" && c >= 0
+		})
+	} else if m := idKeysetRe.FindStringSubmatch(query); m != nil {
+		op, after := m[1], arg(m[2])
+		rows = slices.DeleteFunc(rows, func(row []driver.Value) bool {
+			c := compare(row[id], after)
+			return op == ">" && c <= 0 || op == "<" && c >= 0
+		})
+	}
+
+	if m := orderRe.FindStringSubmatch(query); m != nil {
+		col := tbl.column(m[1])
+		direction := func(c int, dir string) int {
+			if dir == "DESC" {
+				return -c
+			}
+			return c
+		}
+		slices.SortFunc(rows, func(a, b []driver.Value) int {
+			return cmp.Or(direction(compare(a[col], b[col]), m[2]), direction(compare(a[id], b[id]), m[3]))
+		})
+	}
+
+	if m := limitRe.FindStringSubmatch(query); m != nil {
+		if m[2] != "" {
+			rows = rows[min(int(arg(m[2]).(int64)), len(rows)):]
+		}
+		rows = rows[:min(int(arg(m[1]).(int64)), len(rows))]
+	}
+	return tbl.cols, rows
+}
+
+func reviewTable() *memTable {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 123456000, time.UTC)
+	tbl := &memTable{cols: []string{"id", "created_at", "updated_at", "deleted_at", "correlation_id", "movie_id", "rating", "comment", "author", "version"}}
+	// Повторы rating, author и created_at проверяют, что id разводит
+	// одинаковые значения колонки сортировки.
+	for i, r := range []struct {
+		rating float64
+		author string
+		age    time.Duration
+	}{
+		{3.5, "bob", 0}, {4.5, "alice", time.Hour}, {3.5, "carol", time.Microsecond},
+		{0.5, "bob", time.Hour}, {5, "dave", 2 * time.Hour}, {3.5, "alice", 0}, {4.5, "erin", time.Minute},
+	} {
+		id := int64(i + 1)
+		tbl.rows = append(tbl.rows, []driver.Value{
+			id, created.Add(-r.age), created, nil, fmt.Sprintf("00000000-0000-0000-0000-%012d", id),
+			int64(1), r.rating, "comment", r.author, int64(1),
+		})
+	}
+	return tbl
+}
+
+func movieTable() *memTable {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tbl := &memTable{cols: []string{"id", "created_at", "updated_at", "deleted_at", "correlation_id", "title", "year", "runtime", "genres", "version"}}
+	for i, m := range []struct {
+		title   string
+		year    int64
+		runtime int64
+	}{
+		{"Heat", 1995, 170}, {"Alien", 1979, 117}, {"Heat", 1986, 117}, {"Se7en", 1995, 127},
+		{"Ran", 1985, 162}, {"Alien", 1992, 114}, {"Up", 2009, 96},
+	} {
+		id := int64(i + 1)
+		tbl.rows = append(tbl.rows, []driver.Value{
+			id, created, created, nil, fmt.Sprintf("00000000-0000-0000-0000-%012d", id),
+			m.title, m.year, m.runtime, []byte(`["Drama"]`), int64(1),
+		})
+	}
+	return tbl
+}
+
+// expectedOrder — id строк таблицы в порядке сортировки sort.
+func expectedOrder(tbl *memTable, sort string) []uint {
+	dir := "ASC"
+	if strings.HasPrefix(sort, "-") {
+		dir = "DESC"
+	}
+	_, rows := tbl.query("ORDER BY "+strings.TrimPrefix(sort, "-")+" "+dir+", id "+dir, nil)
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = uint(row[0].(int64))
+	}
+	return ids
+}
+
+// pageFunc выбирает страницу модели и возвращает id её строк.
+type pageFunc func(filters data.Filters) ([]uint, data.Metadata, error)
+
+// checkRoundTrip проходит список вперёд по next_cursor от первой страницы
+// и проверяет, что prev_cursor каждой страницы возвращает предыдущую.
+func checkRoundTrip(t *testing.T, page pageFunc, sort string, safelist []string, want []uint) {
+	t.Helper()
+	const pageSize = 2
+	filters := func(f data.Filters) data.Filters {
+		f.PageSize, f.Sort, f.SortSafelist = pageSize, sort, safelist
+		if f.Page == 0 {
+			f.Page = 1
+		}
+		return f
+	}
+
+	var (
+		pages [][]uint
+		got   []uint
+	)
+	f := filters(data.Filters{})
+	for {
+		ids, meta, err := page(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pages) == 0 && meta.PrevCursor != "" {
+			t.Errorf("first page has prev_cursor")
+		}
+		if len(pages) > 0 {
+			prev, _, err := page(filters(data.Filters{Before: meta.PrevCursor}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(prev, pages[len(pages)-1]) {
+				t.Errorf("prev of page %d = %v, want %v", len(pages)+1, prev, pages[len(pages)-1])
+			}
+		}
+		pages = append(pages, ids)
+		got = append(got, ids...)
+		if meta.NextCursor == "" {
+			break
+		}
+		if len(pages) > len(want) {
+			t.Fatalf("next_cursor does not end, got %v", got)
+		}
+		f = filters(data.Filters{After: meta.NextCursor})
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("forward walk = %v, want %v", got, want)
+	}
+
+	// С offset-страницы курсоры ведут туда же, куда и номера страниц.
+	ids, meta, err := page(filters(data.Filters{Page: 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ids, pages[1]) {
+		t.Errorf("page 2 = %v, want %v", ids, pages[1])
+	}
+	prev, _, err := page(filters(data.Filters{Before: meta.PrevCursor}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(prev, pages[0]) {
+		t.Errorf("prev of offset page 2 = %v, want %v", prev, pages[0])
+	}
+	next, _, err := page(filters(data.Filters{After: meta.NextCursor}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(next, pages[2]) {
+		t.Errorf("next of offset page 2 = %v, want %v", next, pages[2])
+	}
+}
+
+func TestReviewKeysetRoundTrip(t *testing.T) {
+	tbl := reviewTable()
+	m := NewModels(newTestDB(t, tbl.query), SearchOptions{}, time.Hour)
+	page := func(filters data.Filters) ([]uint, data.Metadata, error) {
+		reviews, meta, err := m.Reviews.GetAll(context.Background(), data.ReviewFilter{}, filters)
+		ids := make([]uint, len(reviews))
+		for i, r := range reviews {
+			ids[i] = r.ID
+		}
+		return ids, meta, err
+	}
+
+	safelist := []string{"id", "rating", "author", "created_at", "-id", "-rating", "-author", "-created_at"}
+	for _, sort := range safelist {
+		t.Run(sort, func(t *testing.T) {
+			checkRoundTrip(t, page, sort, safelist, expectedOrder(tbl, sort))
+		})
+	}
+}
+
+func TestMovieKeysetRoundTrip(t *testing.T) {
+	tbl := movieTable()
+	m := NewModels(newTestDB(t, tbl.query), SearchOptions{}, time.Hour)
+	page := func(filters data.Filters) ([]uint, data.Metadata, error) {
+		movies, meta, err := m.Movies.GetAll(context.Background(), "", nil, filters)
+		ids := make([]uint, len(movies))
+		for i, movie := range movies {
+			ids[i] = movie.ID
+		}
+		return ids, meta, err
+	}
+
+	safelist := []string{"id", "title", "year", "runtime", "-id", "-title", "-year", "-runtime"}
+	for _, sort := range safelist {
+		t.Run(sort, func(t *testing.T) {
+			checkRoundTrip(t, page, sort, safelist, expectedOrder(tbl, sort))
+		})
+	}
+}
+
+func TestPaginateRejectsBrokenCursor(t *testing.T) {
+	m := NewModels(newTestDB(t, reviewTable().query), SearchOptions{}, time.Hour)
+	_, _, err := m.Reviews.GetAll(context.Background(), data.ReviewFilter{}, data.Filters{
+		Page: 1, PageSize: 2, Sort: "id", SortSafelist: []string{"id"}, After: "not-a-cursor",
+	})
+	if err == nil {
+		t.Fatal("GetAll with a broken cursor succeeded")
+	}
+}
+
+func TestCursorValue(t *testing.T) {
+	tests := []struct {
+		value any
+		want  string
+	}{
+		{value: float32(3.5), want: "3.5"},
+		{value: float32(0.5), want: "0.5"},
+		{value: float32(4.25), want: "4.25"},
+		{value: float32(0.1), want: "0.1"},
+		{value: int32(1995), want: "1995"},
+		{value: uint(7), want: "7"},
+		{value: "Heat", want: "Heat"},
+		{value: time.Date(2026, 1, 2, 3, 4, 5, 123456000, time.FixedZone("MSK", 3*3600)), want: "2026-01-02T00:04:05.123456Z"},
+	}
+	for _, tt := range tests {
+		if got := cursorValue(tt.value); got != tt.want {
+			t.Errorf("cursorValue(%#v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -3,30 +3,45 @@ package models
 import (
 	"context"
 	"data-service/internal/data"
+	"data-service/internal/data/dto"
 	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/plugin/optimisticlock"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// ReviewFilterFields — поля, доступные в параметре filter списка отзывов.
+var ReviewFilterFields = data.FilterFields{
+	"id":         {Column: "id", Type: data.FilterInt, Ops: data.OrderedOps},
+	"movie_id":   {Column: "movie_id", Type: data.FilterInt, Ops: data.OrderedOps},
+	"rating":     {Column: "rating", Type: data.FilterFloat, Ops: data.OrderedOps},
+	"author":     {Column: "author", Type: data.FilterString, Ops: data.TextOps},
+	"comment":    {Column: "comment", Type: data.FilterString, Ops: []string{"~"}},
+	"created_at": {Column: "created_at", Type: data.FilterTime, Ops: data.OrderedOps},
+	"updated_at": {Column: "updated_at", Type: data.FilterTime, Ops: data.OrderedOps},
+}
+
 type ReviewModel struct {
 	DB *gorm.DB
 }
 
-func (r *ReviewModel) Insert(review *data.Review) error {
+func (r *ReviewModel) Insert(ctx context.Context, review *data.Review) error {
 	var movie data.Movie
-	err := r.DB.First(&movie, review.MovieId).Error
+	err := r.DB.WithContext(ctx).First(&movie, review.MovieId).Error
 	if err != nil {
 		switch {
 		case errors.Is(err, gorm.ErrRecordNotFound):
-			return fmt.Errorf("movie with id %d not found", review.MovieId)
+			return fmt.Errorf("movie with id %d: %w", review.MovieId, ErrRecordNotFound)
 		default:
 			return err
 		}
 	}
 
-	if err := r.DB.Create(review).Error; err != nil {
+	if err := r.DB.WithContext(ctx).Create(review).Error; err != nil {
 		if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
 			return ErrDuplicateKey
 		}
@@ -36,16 +51,18 @@ func (r *ReviewModel) Insert(review *data.Review) error {
 	return nil
 }
 
-func (m *ReviewModel) GetReviewByCorrelation(corrId uuid.UUID) (*data.Review, error) {
+// GetReviewByCorrelation и Get читают только columns, если они заданы. Запись
+// из корзины возвращается как *DeletedError.
+func (m *ReviewModel) GetReviewByCorrelation(ctx context.Context, corrId uuid.UUID, columns ...string) (*data.Review, error) {
 	var review data.Review
 
-	err := m.DB.
+	err := selectColumns(m.DB.WithContext(ctx), columns).
 		Where("correlation_id = ?", corrId).
 		First(&review).Error
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, ErrRecordNotFound
+			return nil, missingReviewError(m.DB.WithContext(ctx), "r.correlation_id = ?", corrId)
 		}
 		return nil, err
 	}
@@ -53,20 +70,51 @@ func (m *ReviewModel) GetReviewByCorrelation(corrId uuid.UUID) (*data.Review, er
 	return &review, nil
 }
 
-func (m *ReviewModel) Get(id uint) (*data.Review, error) {
+func (m *ReviewModel) Get(ctx context.Context, id uint, columns ...string) (*data.Review, error) {
 	var review data.Review
-	err := m.DB.First(&review, id).Error
+	err := selectColumns(m.DB.WithContext(ctx), columns).First(&review, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, ErrRecordNotFound
+			return nil, missingReviewError(m.DB.WithContext(ctx), "r.id = ?", id)
 		}
 		return nil, err
 	}
 	return &review, nil
 }
 
-func (m *ReviewModel) Update(review *data.Review) error {
-	result := m.DB.
+// missingReviewError — missingError для отзывов. Отзыв, удалённый вместе с
+// фильмом, носит его метку deleted_at и восстанавливается только с ним.
+func missingReviewError(db *gorm.DB, query any, args ...any) error {
+	var row struct {
+		ID        uint
+		DeletedAt time.Time
+		MovieID   uint
+		WithMovie bool
+	}
+	err := db.Unscoped().
+		Table("reviews r").
+		Select("r.id, r.deleted_at, r.movie_id, coalesce(r.deleted_at = m.deleted_at, false) AS with_movie").
+		Joins("JOIN movies m ON m.id = r.movie_id").
+		Where(query, args...).
+		Where("r.deleted_at IS NOT NULL").
+		Limit(1).
+		Scan(&row).Error
+	if err != nil {
+		return err
+	}
+	if row.ID == 0 {
+		return ErrRecordNotFound
+	}
+
+	deleted := &DeletedError{Table: "reviews", ID: row.ID, DeletedAt: row.DeletedAt}
+	if row.WithMovie {
+		deleted.MovieID = row.MovieID
+	}
+	return deleted
+}
+
+func (m *ReviewModel) Update(ctx context.Context, review *data.Review) error {
+	result := m.DB.WithContext(ctx).
 		Model(&data.Review{}).
 		Where("id = ? AND version = ?", review.ID, review.Version).
 		Updates(review)
@@ -77,52 +125,237 @@ func (m *ReviewModel) Update(review *data.Review) error {
 	if result.RowsAffected == 0 {
 		return ErrEditConflict
 	}
+	// optimisticlock увеличивает версию только в базе.
+	review.Version.Int64++
 	return nil
 }
 
-func (m *ReviewModel) Delete(id uint) error {
-	result := m.DB.Delete(&data.Review{}, id)
-	return result.Error
+// Delete удаляет запись, только если её версия всё ещё равна version.
+func (m *ReviewModel) Delete(ctx context.Context, id uint, version optimisticlock.Version) error {
+	result := m.DB.WithContext(ctx).
+		Where("version = ?", version.Int64).
+		Delete(&data.Review{}, id)
+
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrEditConflict
+	}
+	return nil
+}
+
+// Restore возвращает отзыв из корзины, если его фильм не удалён.
+// Восстановление меняет версию записи.
+func (m *ReviewModel) Restore(ctx context.Context, id uint) (*data.Review, error) {
+	db := m.DB.WithContext(ctx)
+	result := db.Exec(`
+		UPDATE reviews r SET deleted_at = NULL, version = r.version + 1, updated_at = now()
+		FROM movies m
+		WHERE r.id = ? AND r.deleted_at IS NOT NULL
+			AND m.id = r.movie_id AND m.deleted_at IS NULL`, id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		var review data.Review
+		err := db.Unscoped().Select("id").Where("deleted_at IS NOT NULL").First(&review, id).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return nil, ErrRecordNotFound
+		case err != nil:
+			return nil, err
+		default:
+			return nil, ErrMovieDeleted
+		}
+	}
+	return m.Get(ctx, id)
 }
 
-func (m *ReviewModel) GetAll(ctx context.Context, movieId uint, author string, rating float32, filters data.Filters) ([]*data.Review, data.Metadata, error) {
-	var (
-		reviews      []*data.Review
-		totalRecords int64
-	)
+// Purge окончательно удаляет отзыв из корзины.
+func (m *ReviewModel) Purge(ctx context.Context, id uint) error {
+	result := m.DB.WithContext(ctx).
+		Unscoped().
+		Where("deleted_at IS NOT NULL").
+		Delete(&data.Review{}, id)
 
-	db := m.DB.WithContext(ctx).Model(&data.Review{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
 
-	if author != "" {
-		tsQuery := strings.TrimSpace(author)
-		db = db.Where(
-			"to_tsvector('simple', title) @@ plainto_tsquery('simple', ?)",
-			tsQuery,
-		)
+func (m *ReviewModel) GetAll(ctx context.Context, filter data.ReviewFilter, filters data.Filters) ([]*data.Review, data.Metadata, error) {
+	db, err := filterReviews(m.DB.WithContext(ctx).Model(&data.Review{}), filter, filters)
+	if err != nil {
+		return nil, data.Metadata{}, err
 	}
+	return paginate(db, filters, reviewSortKey)
+}
 
-	if movieId > 0 {
-		db = db.Where("movie_id = ?", movieId)
+// reviewSortKey — значение колонки сортировки для курсора.
+func reviewSortKey(r *data.Review, column string) (uint, any) {
+	switch column {
+	case "rating":
+		return r.ID, r.Rating
+	case "author":
+		return r.ID, r.Author
+	case "created_at":
+		return r.ID, r.CreatedAt
+	default:
+		return r.ID, r.ID
 	}
+}
 
-	if rating > 0 {
-		db = db.Where("rating = ?", rating)
+// Stream отдаёт отзывы по одному через курсор, не загружая выборку в память.
+// Фильтры те же, что у GetAll, но без пагинации. Ошибка fn прерывает выборку.
+func (m *ReviewModel) Stream(ctx context.Context, filter data.ReviewFilter, filters data.Filters, fn func(*data.Review) error) error {
+	db, err := filterReviews(m.DB.WithContext(ctx).Model(&data.Review{}), filter, filters)
+	if err != nil {
+		return err
 	}
 
-	if err := db.Count(&totalRecords).Error; err != nil {
-		return nil, data.Metadata{}, err
+	rows, err := db.Order(filters.KeysetOrder()).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var review data.Review
+		if err := db.ScanRows(rows, &review); err != nil {
+			return err
+		}
+		if err := fn(&review); err != nil {
+			return err
+		}
 	}
+	return rows.Err()
+}
 
-	sortExpr := filters.SortColumn() + " " + filters.SortDirection() + ", id ASC"
-	db = db.Order(sortExpr)
+func filterReviews(db *gorm.DB, f data.ReviewFilter, filters data.Filters) (*gorm.DB, error) {
+	expr, err := filters.FilterClause()
+	if err != nil {
+		return nil, err
+	}
+	if expr != nil {
+		db = db.Where(expr)
+	}
 
-	db = db.Limit(filters.Limit()).Offset(filters.Offset())
+	if author := strings.TrimSpace(f.Author); author != "" {
+		if f.AuthorMatch == data.AuthorPartial {
+			db = db.Where("lower(author) LIKE '%' || lower(?) || '%'", escapeLike(author))
+		} else {
+			db = db.Where("author = ?", author)
+		}
+	}
 
-	if err := db.Find(&reviews).Error; err != nil {
-		return nil, data.Metadata{}, err
+	if f.MovieID > 0 {
+		db = db.Where("movie_id = ?", f.MovieID)
+	}
+
+	// Границы передаются строкой: rating — decimal(2,1), и сравнение
+	// с float32 3.7 (3.70000005) дало бы неверный результат.
+	if f.RatingMin > 0 {
+		db = db.Where("rating >= ?::numeric", strconv.FormatFloat(f.RatingMin, 'f', -1, 64))
+	}
+	if f.RatingMax > 0 {
+		db = db.Where("rating <= ?::numeric", strconv.FormatFloat(f.RatingMax, 'f', -1, 64))
+	}
+
+	if !f.CreatedFrom.IsZero() {
+		db = db.Where("created_at >= ?", f.CreatedFrom)
+	}
+	if !f.CreatedTo.IsZero() {
+		db = db.Where("created_at < ?", f.CreatedTo)
+	}
+
+	if q := strings.TrimSpace(f.Query); q != "" {
+		db = db.Where("search_vector @@ websearch_to_tsquery(?::regconfig, ?)", f.Language, q)
+	}
+	return db, nil
+}
+
+func (m *ReviewModel) GetRatingStats(ctx context.Context, movieId uint) (*dto.MovieStats, error) {
+	stats, err := m.RatingStatsByMovies(ctx, []uint{movieId})
+	if err != nil {
+		return nil, err
 	}
+	return stats[movieId], nil
+}
 
-	metadata := data.CalculateMetadata(int(totalRecords), filters.Page, filters.PageSize)
+// RatingStatsByMovies считает сводку оценок для нескольких фильмов одним
+// запросом. В ответе есть все movieIds, в том числе фильмы без отзывов.
+func (m *ReviewModel) RatingStatsByMovies(ctx context.Context, movieIds []uint) (map[uint]*dto.MovieStats, error) {
+	var rows []struct {
+		MovieId uint
+		Rating  float64
+		Count   int
+	}
 
-	return reviews, metadata, nil
+	err := m.DB.WithContext(ctx).
+		Model(&data.Review{}).
+		Select("movie_id, rating, COUNT(*) AS count").
+		Where("movie_id IN ?", movieIds).
+		Group("movie_id, rating").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[uint]*dto.MovieStats, len(movieIds))
+	for _, id := range movieIds {
+		stats := &dto.MovieStats{
+			MovieID:   id,
+			Histogram: make(map[string]int, 10),
+		}
+		for step := 1; step <= 10; step++ {
+			stats.Histogram[strconv.FormatFloat(float64(step)/2, 'f', 1, 64)] = 0
+		}
+		result[id] = stats
+	}
+
+	sums := make(map[uint]float64, len(movieIds))
+	for _, row := range rows {
+		stats := result[row.MovieId]
+		stats.Histogram[strconv.FormatFloat(row.Rating, 'f', 1, 64)] += row.Count
+		stats.ReviewCount += row.Count
+		sums[row.MovieId] += row.Rating * float64(row.Count)
+	}
+	for id, stats := range result {
+		if stats.ReviewCount > 0 {
+			stats.AvgRating = sums[id] / float64(stats.ReviewCount)
+		}
+	}
+
+	return result, nil
+}
+
+// LatestByMovies возвращает не больше limit последних отзывов на каждый
+// из фильмов movieIds.
+func (m *ReviewModel) LatestByMovies(ctx context.Context, movieIds []uint, limit int) (map[uint][]*data.Review, error) {
+	ranked := m.DB.WithContext(ctx).
+		Model(&data.Review{}).
+		Select("reviews.*, row_number() OVER (PARTITION BY movie_id ORDER BY created_at DESC, id DESC) AS position").
+		Where("movie_id IN ?", movieIds)
+
+	var reviews []*data.Review
+	err := m.DB.WithContext(ctx).
+		Table("(?) AS reviews", ranked).
+		Where("position <= ?", limit).
+		Order("movie_id, position").
+		Find(&reviews).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[uint][]*data.Review, len(movieIds))
+	for _, review := range reviews {
+		result[review.MovieId] = append(result[review.MovieId], review)
+	}
+	return result, nil
 }
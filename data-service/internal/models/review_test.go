@@ -0,0 +1,84 @@
+package models
+
+import (
+	"context"
+	"data-service/internal/data"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFilterReviews(t *testing.T) {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+
+	tests := []struct {
+		name   string
+		filter data.ReviewFilter
+		where  string
+		args   []driver.Value
+	}{
+		{name: "no filter", filter: data.ReviewFilter{AuthorMatch: data.AuthorExact}, where: `WHERE "reviews"."deleted_at" IS NULL`},
+		{
+			name:   "exact author",
+			filter: data.ReviewFilter{Author: " Alice ", AuthorMatch: data.AuthorExact},
+			where:  `WHERE author = $1`,
+			args:   []driver.Value{"Alice"},
+		},
+		{
+			name:   "partial author escapes LIKE",
+			filter: data.ReviewFilter{Author: "al_ice%", AuthorMatch: data.AuthorPartial},
+			where:  `WHERE lower(author) LIKE '%' || lower($1) || '%'`,
+			args:   []driver.Value{`al\_ice\%`},
+		},
+		{
+			name:   "movie and rating bounds as numeric",
+			filter: data.ReviewFilter{MovieID: 7, RatingMin: 3.7, RatingMax: 4.5, AuthorMatch: data.AuthorExact},
+			where:  `WHERE movie_id = $1 AND rating >= $2::numeric AND rating <= $3::numeric`,
+			args:   []driver.Value{int64(7), "3.7", "4.5"},
+		},
+		{
+			name:   "half-open created range",
+			filter: data.ReviewFilter{CreatedFrom: from, CreatedTo: to, AuthorMatch: data.AuthorExact},
+			where:  `WHERE created_at >= $1 AND created_at < $2`,
+			args:   []driver.Value{from, to},
+		},
+		{
+			name:   "query",
+			filter: data.ReviewFilter{Query: " good acting ", Language: "english", AuthorMatch: data.AuthorExact},
+			where:  `WHERE search_vector @@ websearch_to_tsquery($1::regconfig, $2)`,
+			args:   []driver.Value{"english", "good acting"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query string
+			var args []driver.Value
+			db := newTestDB(t, func(q string, named []driver.NamedValue) ([]string, [][]driver.Value) {
+				if !strings.HasPrefix(q, "SELECT count(*)") {
+					return nil, nil
+				}
+				query = q
+				for _, a := range named {
+					args = append(args, a.Value)
+				}
+				return []string{"count"}, [][]driver.Value{{int64(0)}}
+			})
+			m := &ReviewModel{DB: db}
+
+			_, _, err := m.GetAll(context.Background(), tt.filter, data.Filters{Page: 1, PageSize: 20, Sort: "id", SortSafelist: []string{"id"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(query, tt.where) {
+				t.Errorf("query = %s, want %s", query, tt.where)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("args = %v, want %v", args, tt.args)
+			}
+		})
+	}
+}
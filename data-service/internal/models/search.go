@@ -0,0 +1,338 @@
+package models
+
+import (
+	"context"
+	"data-service/internal/data"
+	"data-service/internal/data/dto"
+	"fmt"
+	"gorm.io/gorm"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	SearchMovies  = "movie"
+	SearchReviews = "review"
+
+	// searchSchemaVersion меняется вместе с выражениями search_vector,
+	// чтобы EnsureSchema пересоздал колонки.
+	searchSchemaVersion = "v1"
+
+	headlineOptions = "StartSel=<mark>, StopSel=</mark>, MinWords=10, MaxWords=30, MaxFragments=2"
+)
+
+var configNameRX = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// searchColumn описывает индексируемые поля таблицы и их вес в ts_rank.
+type searchColumn struct {
+	table  string
+	fields []struct{ name, weight string }
+}
+
+var searchColumns = []searchColumn{
+	{table: "movies", fields: []struct{ name, weight string }{{"title", "A"}}},
+	{table: "reviews", fields: []struct{ name, weight string }{{"comment", "A"}, {"author", "C"}}},
+}
+
+// SearchModel — полнотекстовый поиск по названиям фильмов и комментариям
+// отзывов. Поиск идёт по генерируемым колонкам search_vector с GIN-индексами:
+// в вектор попадают лексемы каждой конфигурации из Languages и 'simple' —
+// последняя находит слова, которые словари не знают или стеммят иначе.
+type SearchModel struct {
+	DB        *gorm.DB
+	Languages []string
+	// SuggestThreshold — порог word_similarity для "возможно, вы искали",
+	// SuggestMax — предел limit в Suggest.
+	SuggestThreshold float64
+	SuggestMax       int
+}
+
+// trigramIndexes — триграммные индексы для нечёткого фильтра и
+// автодополнения по названию и частичного совпадения по автору.
+var trigramIndexes = []struct{ name, table, expr string }{
+	{"idx_movies_title_trgm", "movies", "lower(title) gin_trgm_ops"},
+	{"idx_reviews_author_trgm", "reviews", "lower(author) gin_trgm_ops"},
+}
+
+// EnsureSchema создаёт колонки search_vector и индексы. Набор языков
+// записывается в комментарий колонки: если он поменялся, колонка
+// пересоздаётся. Когда схема актуальна, вызов только читает каталог и не
+// берёт блокировок на таблицы.
+func (m *SearchModel) EnsureSchema(ctx context.Context) error {
+	if err := m.checkLanguages(ctx); err != nil {
+		return err
+	}
+
+	fingerprint := searchSchemaVersion + " " + strings.Join(m.Languages, ",")
+	if ok, err := schemaCurrent(m.DB.WithContext(ctx), fingerprint); err != nil || ok {
+		return err
+	}
+
+	return m.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Несколько инстансов стартуют одновременно — DDL выполняет один,
+		// остальные после ожидания видят уже обновлённую схему.
+		if err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('search_schema'))`).Error; err != nil {
+			return err
+		}
+		if ok, err := schemaCurrent(tx, fingerprint); err != nil || ok {
+			return err
+		}
+
+		if err := tx.Exec(`CREATE EXTENSION IF NOT EXISTS pg_trgm`).Error; err != nil {
+			return fmt.Errorf("trigram index: %w", err)
+		}
+		for _, idx := range trigramIndexes {
+			stmt := fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s USING GIN (%s)`, idx.name, idx.table, idx.expr)
+			if err := tx.Exec(stmt).Error; err != nil {
+				return fmt.Errorf("trigram index: %w", err)
+			}
+		}
+
+		for _, col := range searchColumns {
+			current, err := vectorFingerprint(tx, col.table)
+			if err != nil {
+				return err
+			}
+
+			var stmts []string
+			if current == nil || *current != fingerprint {
+				stmts = append(stmts,
+					fmt.Sprintf(`ALTER TABLE %s DROP COLUMN IF EXISTS search_vector`, col.table),
+					fmt.Sprintf(`ALTER TABLE %s ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (%s) STORED`,
+						col.table, m.vectorExpr(col)),
+					fmt.Sprintf(`COMMENT ON COLUMN %s.search_vector IS '%s'`, col.table, fingerprint),
+				)
+			}
+			stmts = append(stmts, fmt.Sprintf(`CREATE INDEX IF NOT EXISTS idx_%s_search_vector ON %s USING GIN (search_vector)`,
+				col.table, col.table))
+			for _, stmt := range stmts {
+				if err := tx.Exec(stmt).Error; err != nil {
+					return fmt.Errorf("search schema for %s: %w", col.table, err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// schemaCurrent сообщает, что все индексы на месте, а колонки
+// search_vector построены для fingerprint.
+func schemaCurrent(db *gorm.DB, fingerprint string) (bool, error) {
+	names := make([]string, 0, len(trigramIndexes)+len(searchColumns))
+	for _, idx := range trigramIndexes {
+		names = append(names, idx.name)
+	}
+	for _, col := range searchColumns {
+		names = append(names, "idx_"+col.table+"_search_vector")
+	}
+
+	var found int64
+	err := db.Raw(`SELECT count(*) FROM pg_indexes WHERE schemaname = current_schema() AND indexname IN ?`, names).
+		Scan(&found).Error
+	if err != nil || found != int64(len(names)) {
+		return false, err
+	}
+
+	for _, col := range searchColumns {
+		current, err := vectorFingerprint(db, col.table)
+		if err != nil || current == nil || *current != fingerprint {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// vectorFingerprint читает комментарий колонки search_vector таблицы; nil —
+// колонки нет.
+func vectorFingerprint(db *gorm.DB, table string) (*string, error) {
+	var current *string
+	err := db.Raw(`SELECT col_description(a.attrelid, a.attnum)
+		FROM pg_attribute a
+		WHERE a.attrelid = ?::regclass AND a.attname = 'search_vector' AND NOT a.attisdropped`,
+		table).Scan(&current).Error
+	return current, err
+}
+
+// checkLanguages проверяет, что конфигурации существуют в базе. Имена
+// подставляются в DDL как литералы, поэтому пропускаются только
+// идентификаторы.
+func (m *SearchModel) checkLanguages(ctx context.Context) error {
+	for _, lang := range m.Languages {
+		if !configNameRX.MatchString(lang) {
+			return fmt.Errorf("invalid text search configuration name %q", lang)
+		}
+	}
+
+	var known []string
+	err := m.DB.WithContext(ctx).Raw(`SELECT cfgname FROM pg_ts_config WHERE cfgname IN ?`, m.Languages).
+		Scan(&known).Error
+	if err != nil {
+		return err
+	}
+	for _, lang := range m.Languages {
+		if !slices.Contains(known, lang) {
+			return fmt.Errorf("text search configuration %q does not exist", lang)
+		}
+	}
+	return nil
+}
+
+func (m *SearchModel) vectorExpr(col searchColumn) string {
+	configs := append(slices.Clone(m.Languages), "simple")
+	configs = slices.Compact(configs)
+
+	var parts []string
+	for _, f := range col.fields {
+		for _, cfg := range configs {
+			parts = append(parts, fmt.Sprintf("setweight(to_tsvector('%s'::regconfig, coalesce(%s, '')), '%s')", cfg, f.name, f.weight))
+		}
+	}
+	return strings.Join(parts, " || ")
+}
+
+// Suggest дополняет начало названия и подбирает похожие названия для
+// запроса с опечатками. Completions — названия, где с prefix начинается
+// первое или любое следующее слово; DidYouMean — остальные названия
+// с word_similarity не ниже SuggestThreshold.
+func (m *SearchModel) Suggest(ctx context.Context, prefix string, limit int) (*dto.MovieSuggestions, error) {
+	res := &dto.MovieSuggestions{
+		Completions: []dto.MovieSuggestion{},
+		DidYouMean:  []dto.MovieSuggestion{},
+	}
+	pattern := escapeLike(strings.ToLower(prefix))
+
+	err := m.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Raw(`
+			SELECT id, title, year
+			FROM movies
+			WHERE deleted_at IS NULL
+				AND (lower(title) LIKE @pattern || '%' OR lower(title) LIKE '% ' || @pattern || '%')
+			ORDER BY lower(title) LIKE @pattern || '%' DESC, length(title), title, id
+			LIMIT @limit`,
+			map[string]any{"pattern": pattern, "limit": limit},
+		).Scan(&res.Completions).Error
+		if err != nil {
+			return err
+		}
+
+		if err := setWordSimilarityThreshold(tx, m.SuggestThreshold); err != nil {
+			return err
+		}
+		exclude := []uint{0}
+		for _, s := range res.Completions {
+			exclude = append(exclude, s.ID)
+		}
+		return tx.Raw(`
+			SELECT id, title, year, word_similarity(lower(@prefix), lower(title)) AS similarity
+			FROM movies
+			WHERE deleted_at IS NULL
+				AND lower(@prefix) <% lower(title)
+				AND id NOT IN @exclude
+			ORDER BY similarity DESC, length(title), id
+			LIMIT @limit`,
+			map[string]any{"prefix": prefix, "exclude": exclude, "limit": limit},
+		).Scan(&res.DidYouMean).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// setWordSimilarityThreshold задаёт порог операторов <% и %> до конца
+// текущей транзакции.
+func setWordSimilarityThreshold(tx *gorm.DB, threshold float64) error {
+	return tx.Exec(`SELECT set_config('pg_trgm.word_similarity_threshold', ?, true)`,
+		strconv.FormatFloat(threshold, 'f', -1, 64)).Error
+}
+
+// escapeLike экранирует метасимволы LIKE, чтобы "%" и "_" в запросе
+// совпадали буквально.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// HasLanguage сообщает, проиндексирована ли конфигурация.
+func (m *SearchModel) HasLanguage(lang string) bool {
+	return slices.Contains(m.Languages, lang)
+}
+
+// Search ищет по запросу в синтаксисе websearch_to_tsquery ("фраза",
+// OR, -исключение) и упорядочивает результаты по ts_rank. types
+// ограничивает выдачу фильмами или отзывами; пустой — оба вида.
+// Snippet — фрагмент HTML: текст экранирован, разметка в нём только <mark>.
+func (m *SearchModel) Search(ctx context.Context, query, lang string, types []string, filters data.Filters) ([]dto.SearchHit, data.Metadata, error) {
+	var branches []string
+	if len(types) == 0 || slices.Contains(types, SearchMovies) {
+		branches = append(branches, `
+			SELECT 'movie' AS type, m.id, m.id AS movie_id, m.title, m.title AS body,
+				ts_rank(m.search_vector, q.query, 1) AS rank
+			FROM movies m, q
+			WHERE m.deleted_at IS NULL AND m.search_vector @@ q.query`)
+	}
+	if len(types) == 0 || slices.Contains(types, SearchReviews) {
+		branches = append(branches, `
+			SELECT 'review' AS type, r.id, r.movie_id, m.title, r.comment AS body,
+				ts_rank(r.search_vector, q.query, 1) AS rank
+			FROM reviews r
+			JOIN movies m ON m.id = r.movie_id AND m.deleted_at IS NULL
+			CROSS JOIN q
+			WHERE r.deleted_at IS NULL AND r.search_vector @@ q.query`)
+	}
+
+	// CAST вместо "::": GORM не подставляет именованный параметр, за
+	// которым сразу идёт двоеточие, и @lang::regconfig ушёл бы в Postgres
+	// как есть.
+	with := `
+		WITH q AS (SELECT websearch_to_tsquery(CAST(@lang AS regconfig), @query) AS query),
+		hits AS (` + strings.Join(branches, "\nUNION ALL") + `)`
+	args := map[string]any{
+		"lang":     lang,
+		"query":    query,
+		"limit":    filters.Limit(),
+		"offset":   filters.Offset(),
+		"headline": headlineOptions,
+	}
+
+	// ts_headline дорогой, поэтому считается только для строк страницы.
+	// Текст экранируется до ts_headline, чтобы в snippet не попала разметка
+	// из комментариев: единственные теги в нём — StartSel и StopSel.
+	sql := with + `,
+		page AS (
+			SELECT hits.*, count(*) OVER () AS total
+			FROM hits
+			ORDER BY rank DESC, type, id
+			LIMIT @limit OFFSET @offset
+		)
+		SELECT page.type, page.id, page.movie_id, page.title, page.rank, page.total,
+			ts_headline(CAST(@lang AS regconfig),
+				replace(replace(replace(page.body, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+				q.query, @headline) AS snippet
+		FROM page, q
+		ORDER BY page.rank DESC, page.type, page.id`
+
+	var rows []struct {
+		dto.SearchHit
+		Total int
+	}
+	db := m.DB.WithContext(ctx)
+	if err := db.Raw(sql, args).Scan(&rows).Error; err != nil {
+		return nil, data.Metadata{}, err
+	}
+
+	hits := make([]dto.SearchHit, len(rows))
+	total := 0
+	for i, r := range rows {
+		hits[i] = r.SearchHit
+		total = r.Total
+	}
+	// На странице за последней оконному count(*) не на чем посчитаться.
+	if len(rows) == 0 && filters.Offset() > 0 {
+		if err := db.Raw(with+` SELECT count(*) FROM hits`, args).Scan(&total).Error; err != nil {
+			return nil, data.Metadata{}, err
+		}
+	}
+	return hits, data.CalculateMetadata(total, filters.Page, filters.PageSize), nil
+}
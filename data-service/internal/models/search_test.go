@@ -0,0 +1,218 @@
+package models
+
+import (
+	"context"
+	"data-service/internal/data"
+	"database/sql/driver"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSearchQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		types   []string
+		movies  bool
+		reviews bool
+	}{
+		{name: "all types", movies: true, reviews: true},
+		{name: "movies", types: []string{SearchMovies}, movies: true},
+		{name: "reviews", types: []string{SearchReviews}, reviews: true},
+		{name: "both listed", types: []string{SearchReviews, SearchMovies}, movies: true, reviews: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var queries []string
+			var args []driver.Value
+			db := newTestDB(t, func(query string, named []driver.NamedValue) ([]string, [][]driver.Value) {
+				queries = append(queries, query)
+				for _, a := range named {
+					args = append(args, a.Value)
+				}
+				return []string{"type", "id", "movie_id", "title", "rank", "total", "snippet"},
+					[][]driver.Value{{"movie", int64(1), int64(1), "Heat", 0.5, int64(41), "<mark>Heat</mark>"}}
+			})
+			m := &SearchModel{DB: db, Languages: []string{"english", "russian"}}
+
+			filters := data.Filters{Page: 3, PageSize: 20}
+			hits, metadata, err := m.Search(context.Background(), `"heat" -remake`, "russian", tt.types, filters)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(queries) != 1 {
+				t.Fatalf("queries = %d, want 1", len(queries))
+			}
+			q := queries[0]
+			if got := strings.Contains(q, "FROM movies m, q"); got != tt.movies {
+				t.Errorf("movies branch = %v, want %v", got, tt.movies)
+			}
+			if got := strings.Contains(q, "FROM reviews r"); got != tt.reviews {
+				t.Errorf("reviews branch = %v, want %v", got, tt.reviews)
+			}
+			if !strings.Contains(q, "websearch_to_tsquery(CAST($1 AS regconfig), $2)") {
+				t.Errorf("query does not parse q with websearch_to_tsquery:\n%s", q)
+			}
+			for _, want := range []driver.Value{"russian", `"heat" -remake`, int64(20), int64(40), headlineOptions} {
+				if !slices.Contains(args, want) {
+					t.Errorf("args %v lack %v", args, want)
+				}
+			}
+			if len(hits) != 1 || metadata.TotalRecords != 41 || metadata.LastPage != 3 {
+				t.Errorf("hits, metadata = %+v, %+v", hits, metadata)
+			}
+		})
+	}
+}
+
+// За последней страницей строк нет, и total берётся отдельным count(*).
+func TestSearchPastLastPage(t *testing.T) {
+	var queries []string
+	db := newTestDB(t, func(query string, _ []driver.NamedValue) ([]string, [][]driver.Value) {
+		queries = append(queries, query)
+		if strings.Contains(query, "SELECT count(*) FROM hits") {
+			return []string{"count"}, [][]driver.Value{{int64(5)}}
+		}
+		return nil, nil
+	})
+	m := &SearchModel{DB: db, Languages: []string{"english"}}
+
+	hits, metadata, err := m.Search(context.Background(), "heat", "english", nil, data.Filters{Page: 2, PageSize: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 0 || metadata.TotalRecords != 5 || len(queries) != 2 {
+		t.Errorf("hits, metadata, queries = %v, %+v, %d", hits, metadata, len(queries))
+	}
+}
+
+func TestVectorExpr(t *testing.T) {
+	tests := []struct {
+		name      string
+		languages []string
+		col       searchColumn
+		want      string
+	}{
+		{
+			name:      "simple is added",
+			languages: []string{"english"},
+			col:       searchColumns[0],
+			want: "setweight(to_tsvector('english'::regconfig, coalesce(title, '')), 'A') || " +
+				"setweight(to_tsvector('simple'::regconfig, coalesce(title, '')), 'A')",
+		},
+		{
+			name:      "simple is not repeated",
+			languages: []string{"simple"},
+			col:       searchColumns[1],
+			want: "setweight(to_tsvector('simple'::regconfig, coalesce(comment, '')), 'A') || " +
+				"setweight(to_tsvector('simple'::regconfig, coalesce(author, '')), 'C')",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &SearchModel{Languages: tt.languages}
+			if got := m.vectorExpr(tt.col); got != tt.want {
+				t.Errorf("vectorExpr =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckLanguages(t *testing.T) {
+	tests := []struct {
+		name      string
+		languages []string
+		known     []string
+		err       string
+	}{
+		{name: "known", languages: []string{"english", "russian"}, known: []string{"english", "russian"}},
+		{name: "missing", languages: []string{"english", "klingon"}, known: []string{"english"}, err: `text search configuration "klingon" does not exist`},
+		{name: "not an identifier", languages: []string{"english'); DROP TABLE movies; --"}, err: "invalid text search configuration name"},
+		{name: "upper case", languages: []string{"English"}, err: "invalid text search configuration name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, func(string, []driver.NamedValue) ([]string, [][]driver.Value) {
+				rows := make([][]driver.Value, len(tt.known))
+				for i, name := range tt.known {
+					rows[i] = []driver.Value{name}
+				}
+				return []string{"cfgname"}, rows
+			})
+			m := &SearchModel{DB: db, Languages: tt.languages}
+
+			err := m.checkLanguages(context.Background())
+			if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("checkLanguages = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestHasLanguage(t *testing.T) {
+	m := &SearchModel{Languages: []string{"english", "russian"}}
+	for lang, want := range map[string]bool{"english": true, "russian": true, "simple": false, "": false, "English": false} {
+		if got := m.HasLanguage(lang); got != want {
+			t.Errorf("HasLanguage(%q) = %v, want %v", lang, got, want)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	var stmts []statement
+	db := newTestDB(t, func(query string, named []driver.NamedValue) ([]string, [][]driver.Value) {
+		args := make([]driver.Value, len(named))
+		for i, a := range named {
+			args[i] = a.Value
+		}
+		stmts = append(stmts, statement{query: query, args: args})
+		switch {
+		case strings.Contains(query, "LIKE"):
+			return []string{"id", "title", "year"}, [][]driver.Value{{int64(4), "100% Wolf", int64(2020)}}
+		case strings.Contains(query, "<%"):
+			return []string{"id", "title", "year", "similarity"}, [][]driver.Value{{int64(9), "1000 Wolves", int64(2011), 0.6}}
+		}
+		return nil, nil
+	})
+	m := &SearchModel{DB: db, SuggestThreshold: 0.3}
+
+	res, err := m.Suggest(context.Background(), "100%_W", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Completions) != 1 || res.Completions[0].ID != 4 || len(res.DidYouMean) != 1 || res.DidYouMean[0].Similarity != 0.6 {
+		t.Errorf("suggestions = %+v", res)
+	}
+
+	if len(stmts) != 3 {
+		t.Fatalf("statements = %d, want 3", len(stmts))
+	}
+	if !slices.Contains(stmts[0].args, driver.Value(`100\%\_w`)) {
+		t.Errorf("completion args = %v, want the escaped lower-case prefix", stmts[0].args)
+	}
+	if !strings.Contains(stmts[1].query, "set_config") || !slices.Equal(stmts[1].args, []driver.Value{"0.3"}) {
+		t.Errorf("threshold statement = %q %v", stmts[1].query, stmts[1].args)
+	}
+	// Уже найденные дополнения не повторяются в "возможно, вы искали".
+	if !strings.Contains(stmts[2].query, "id NOT IN ($3,$4)") || !slices.Equal(stmts[2].args[2:4], []driver.Value{int64(0), int64(4)}) {
+		t.Errorf("did you mean = %q %v", stmts[2].query, stmts[2].args)
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{in: "heat", want: "heat"},
+		{in: "100%", want: `100\%`},
+		{in: "snake_case", want: `snake\_case`},
+		{in: `back\slash`, want: `back\\slash`},
+		{in: `\%_`, want: `\\\%\_`},
+	}
+	for _, tt := range tests {
+		if got := escapeLike(tt.in); got != tt.want {
+			t.Errorf("escapeLike(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
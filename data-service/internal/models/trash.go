@@ -0,0 +1,135 @@
+package models
+
+import (
+	"context"
+	"data-service/internal/data"
+	"data-service/internal/data/dto"
+	"gorm.io/gorm"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Виды записей в корзине.
+const (
+	TrashMovies  = "movie"
+	TrashReviews = "review"
+)
+
+// purgeBatchSize — сколько записей удаляет один запрос очистки, чтобы не
+// держать блокировки на всю корзину сразу.
+const purgeBatchSize = 1000
+
+// TrashModel работает с мягко удалёнными записями. Retention — сколько
+// запись хранится в корзине до окончательного удаления.
+type TrashModel struct {
+	DB        *gorm.DB
+	Retention time.Duration
+}
+
+// List возвращает содержимое корзины, начиная с последних удалённых.
+// types ограничивает выдачу фильмами или отзывами; пустой — оба вида.
+func (m *TrashModel) List(ctx context.Context, types []string, filters data.Filters) ([]dto.TrashItem, data.Metadata, error) {
+	var branches []string
+	if len(types) == 0 || slices.Contains(types, TrashMovies) {
+		branches = append(branches, `
+			SELECT 'movie' AS type, id, id AS movie_id, title, deleted_at, false AS with_movie
+			FROM movies
+			WHERE deleted_at IS NOT NULL`)
+	}
+	if len(types) == 0 || slices.Contains(types, TrashReviews) {
+		// Отзыв, удалённый вместе с фильмом, носит ту же метку deleted_at.
+		branches = append(branches, `
+			SELECT 'review' AS type, r.id, r.movie_id, m.title, r.deleted_at,
+				coalesce(r.deleted_at = m.deleted_at, false) AS with_movie
+			FROM reviews r
+			JOIN movies m ON m.id = r.movie_id
+			WHERE r.deleted_at IS NOT NULL`)
+	}
+
+	// Итог считается отдельным запросом: оконный count(*) на странице за
+	// последней дал бы ноль.
+	items := "(" + strings.Join(branches, "\nUNION ALL") + ") items"
+	db := m.DB.WithContext(ctx)
+
+	var total int
+	if err := db.Raw(`SELECT count(*) FROM ` + items).Scan(&total).Error; err != nil {
+		return nil, data.Metadata{}, err
+	}
+
+	rows := []dto.TrashItem{}
+	err := db.Raw(`
+		SELECT items.*
+		FROM `+items+`
+		ORDER BY deleted_at DESC, type, id
+		LIMIT ? OFFSET ?`, filters.Limit(), filters.Offset()).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, data.Metadata{}, err
+	}
+
+	for i := range rows {
+		rows[i].PurgeAt = rows[i].DeletedAt.Add(m.Retention)
+	}
+	return rows, data.CalculateMetadata(total, filters.Page, filters.PageSize), nil
+}
+
+// PurgeExpired окончательно удаляет записи, удалённые раньше before.
+// Фильмы удаляются вместе со всеми своими отзывами; reviews включает и их.
+// Строки, которые уже чистит другой инстанс, пропускаются.
+func (m *TrashModel) PurgeExpired(ctx context.Context, before time.Time) (movies, reviews int64, err error) {
+	db := m.DB.WithContext(ctx)
+	for {
+		res := db.Exec(`
+			DELETE FROM reviews
+			WHERE id IN (
+				SELECT id FROM reviews
+				WHERE deleted_at < ?
+				ORDER BY id
+				LIMIT ?
+				FOR UPDATE SKIP LOCKED
+			)`, before, purgeBatchSize)
+		if res.Error != nil {
+			return movies, reviews, res.Error
+		}
+		reviews += res.RowsAffected
+		if res.RowsAffected < purgeBatchSize {
+			break
+		}
+	}
+
+	for {
+		var ids []uint
+		var movieRows, reviewRows int64
+		err := db.Transaction(func(tx *gorm.DB) error {
+			err := tx.Raw(`
+				SELECT id FROM movies
+				WHERE deleted_at < ?
+				ORDER BY id
+				LIMIT ?
+				FOR UPDATE SKIP LOCKED`, before, purgeBatchSize).
+				Scan(&ids).Error
+			if err != nil || len(ids) == 0 {
+				return err
+			}
+
+			res := tx.Exec(`DELETE FROM reviews WHERE movie_id IN ?`, ids)
+			if res.Error != nil {
+				return res.Error
+			}
+			reviewRows = res.RowsAffected
+
+			res = tx.Exec(`DELETE FROM movies WHERE id IN ?`, ids)
+			movieRows = res.RowsAffected
+			return res.Error
+		})
+		if err != nil {
+			return movies, reviews, err
+		}
+		movies += movieRows
+		reviews += reviewRows
+		if len(ids) < purgeBatchSize {
+			return movies, reviews, nil
+		}
+	}
+}
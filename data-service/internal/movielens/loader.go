@@ -0,0 +1,303 @@
+package movielens
+
+import (
+	"context"
+	"data-service/internal/data"
+	"data-service/internal/validator"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxCommentLen = 1000
+	resolveChunk  = 1000
+	progressEvery = 100_000
+)
+
+// resolvePoll — пауза между поисками фильмов, которые ещё не создал консьюмер.
+var resolvePoll = time.Second
+
+// namespace делает correlation_id записей детерминированным: повторный
+// запуск загрузчика не плодит дубли ни в Postgres, ни через Kafka.
+var namespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("https://grouplens.org/datasets/movielens/"))
+
+type Options struct {
+	// Dir — каталог с movies.csv, ratings.csv и (необязательно) tags.csv.
+	Dir string
+	// Runtime подставляется всем фильмам: в MovieLens длительности нет,
+	// а data.ValidateMovie её требует.
+	Runtime      int32
+	MoviesLimit  int
+	RatingsLimit int
+	BatchSize    int
+	// ResolveTimeout — сколько ждать появления фильмов в базе перед
+	// загрузкой отзывов. Нужен в режиме Kafka, где фильмы создаются
+	// консьюмером асинхронно.
+	ResolveTimeout time.Duration
+}
+
+type Stats struct {
+	Movies         int
+	MoviesSkipped  int
+	Reviews        int
+	ReviewsSkipped int
+}
+
+type Loader struct {
+	db     *gorm.DB
+	sink   Sink
+	logger *slog.Logger
+}
+
+// New создаёт загрузчик. db нужна в любом режиме: по ней MovieLens movieId
+// сопоставляются с id созданных фильмов.
+func New(db *gorm.DB, sink Sink, logger *slog.Logger) *Loader {
+	return &Loader{db: db, sink: sink, logger: logger}
+}
+
+func (l *Loader) Run(ctx context.Context, opts Options) (Stats, error) {
+	var stats Stats
+
+	tags, err := readTags(filepath.Join(opts.Dir, "tags.csv"))
+	if err != nil {
+		return stats, err
+	}
+
+	corrIDs, err := l.loadMovies(ctx, opts, &stats)
+	if err != nil {
+		return stats, err
+	}
+	l.logger.InfoContext(ctx, "movies loaded", "loaded", stats.Movies, "skipped", stats.MoviesSkipped)
+
+	ids, err := l.resolve(ctx, corrIDs, opts.ResolveTimeout)
+	if err != nil {
+		return stats, err
+	}
+	if missing := len(corrIDs) - len(ids); missing > 0 {
+		l.logger.WarnContext(ctx, "movies not found in database, their ratings are skipped", "count", missing)
+	}
+
+	if err := l.loadRatings(ctx, opts, ids, tags, &stats); err != nil {
+		return stats, err
+	}
+	l.logger.InfoContext(ctx, "ratings loaded", "loaded", stats.Reviews, "skipped", stats.ReviewsSkipped)
+	return stats, nil
+}
+
+// loadMovies возвращает correlation_id принятых фильмов по их movieId.
+func (l *Loader) loadMovies(ctx context.Context, opts Options, stats *Stats) (map[int64]uuid.UUID, error) {
+	r, err := openCSV(filepath.Join(opts.Dir, "movies.csv"), "movieId", "title", "genres")
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	corrIDs := make(map[int64]uuid.UUID)
+	batch := make([]data.Movie, 0, opts.BatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := l.sink.Movies(ctx, batch); err != nil {
+			return fmt.Errorf("load movies: %w", err)
+		}
+		stats.Movies += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	for opts.MoviesLimit <= 0 || stats.Movies+len(batch) < opts.MoviesLimit {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		row, err := r.nextMovie()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		movie, problems := convertMovie(row, opts.Runtime)
+		if problems != nil {
+			stats.MoviesSkipped++
+			l.logger.DebugContext(ctx, "skip movie", "movie_id", row.MovieID, "title", row.Title, "errors", problems)
+			continue
+		}
+		corrIDs[row.MovieID] = movie.CorrelationId
+		batch = append(batch, movie)
+
+		if len(batch) == cap(batch) {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return corrIDs, flush()
+}
+
+func (l *Loader) loadRatings(ctx context.Context, opts Options, ids map[int64]uint, tags map[tagKey][]string, stats *Stats) error {
+	r, err := openCSV(filepath.Join(opts.Dir, "ratings.csv"), "userId", "movieId", "rating", "timestamp")
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	batch := make([]data.Review, 0, opts.BatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := l.sink.Reviews(ctx, batch); err != nil {
+			return fmt.Errorf("load reviews: %w", err)
+		}
+		before := stats.Reviews
+		stats.Reviews += len(batch)
+		if stats.Reviews/progressEvery != before/progressEvery {
+			l.logger.InfoContext(ctx, "ratings progress", "loaded", stats.Reviews, "skipped", stats.ReviewsSkipped)
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for opts.RatingsLimit <= 0 || stats.Reviews+len(batch) < opts.RatingsLimit {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		row, err := r.nextRating()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		movieID, ok := ids[row.MovieID]
+		if !ok {
+			stats.ReviewsSkipped++
+			continue
+		}
+		review, problems := convertRating(row, movieID, tags[tagKey{userID: row.UserID, movieID: row.MovieID}])
+		if problems != nil {
+			stats.ReviewsSkipped++
+			l.logger.DebugContext(ctx, "skip rating", "user_id", row.UserID, "movie_id", row.MovieID, "errors", problems)
+			continue
+		}
+		batch = append(batch, review)
+
+		if len(batch) == cap(batch) {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
+
+// resolve находит id фильмов по correlation_id. С timeout > 0 ищет
+// повторно, пока не найдёт все фильмы или не истечёт время.
+func (l *Loader) resolve(ctx context.Context, corrIDs map[int64]uuid.UUID, timeout time.Duration) (map[int64]uint, error) {
+	byCorr := make(map[uuid.UUID]int64, len(corrIDs))
+	for mlID, corrID := range corrIDs {
+		byCorr[corrID] = mlID
+	}
+
+	ids := make(map[int64]uint, len(corrIDs))
+	deadline := time.Now().Add(timeout)
+	for {
+		pending := make([]uuid.UUID, 0, len(byCorr))
+		for corrID := range byCorr {
+			pending = append(pending, corrID)
+		}
+
+		for start := 0; start < len(pending); start += resolveChunk {
+			chunk := pending[start:min(start+resolveChunk, len(pending))]
+
+			var found []data.Movie
+			err := l.db.WithContext(ctx).
+				Select("id", "correlation_id").
+				Where("correlation_id IN ?", chunk).
+				Find(&found).Error
+			if err != nil {
+				return nil, fmt.Errorf("resolve movies: %w", err)
+			}
+			for _, m := range found {
+				ids[byCorr[m.CorrelationId]] = m.ID
+				delete(byCorr, m.CorrelationId)
+			}
+		}
+
+		if len(byCorr) == 0 || !time.Now().Before(deadline) {
+			return ids, nil
+		}
+		l.logger.InfoContext(ctx, "waiting for movies to be created", "pending", len(byCorr))
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(resolvePoll):
+		}
+	}
+}
+
+func convertMovie(row MovieRow, runtime int32) (data.Movie, map[string]string) {
+	title, year := ParseTitle(row.Title)
+	movie := data.Movie{
+		CorrelationId: uuid.NewSHA1(namespace, []byte("movie:"+strconv.FormatInt(row.MovieID, 10))),
+		Title:         title,
+		Year:          year,
+		Runtime:       runtime,
+		Genres:        ParseGenres(row.Genres),
+	}
+
+	v := validator.New()
+	v.Check(titleFits(title), "title", fmt.Sprintf("must not be more than %d characters long", maxTitleLen))
+	if data.ValidateMovie(v, &movie); !v.Valid() {
+		return movie, v.Errors
+	}
+	return movie, nil
+}
+
+// convertRating превращает оценку в отзыв. Шкала MovieLens (0.5–5.0 с шагом
+// 0.5) совпадает с ограничением reviews.rating. Комментарием служат теги,
+// которые пользователь поставил фильму.
+func convertRating(row RatingRow, movieID uint, tags []string) (data.Review, map[string]string) {
+	comment := fmt.Sprintf("Rated %.1f/5 on MovieLens", row.Rating)
+	if len(tags) > 0 {
+		comment = truncate(strings.Join(tags, ", "), maxCommentLen)
+	}
+
+	review := data.Review{
+		CorrelationId: uuid.NewSHA1(namespace, []byte(fmt.Sprintf("rating:%d:%d", row.UserID, row.MovieID))),
+		MovieId:       movieID,
+		Rating:        row.Rating,
+		Comment:       comment,
+		Author:        "movielens-user-" + strconv.FormatInt(row.UserID, 10),
+	}
+	review.CreatedAt = row.Timestamp
+
+	v := validator.New()
+	if data.ValidateReview(v, &review); !v.Valid() {
+		return review, v.Errors
+	}
+	return review, nil
+}
+
+// truncate обрезает строку до n байт, не разрывая символ UTF-8.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,331 @@
+package movielens
+
+import (
+	"context"
+	"data-service/internal/handler"
+	"data-service/internal/kafka"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	insertRe  = regexp.MustCompile(`^INSERT INTO "(\w+)" \(([^)]*)\) VALUES .* ON CONFLICT \("correlation_id"\) DO NOTHING`)
+	resolveRe = regexp.MustCompile(`^SELECT "id","correlation_id" FROM "movies" WHERE correlation_id IN \(`)
+)
+
+// memDB исполняет запросы загрузчика: вставку пачек с ON CONFLICT и поиск
+// id фильмов по correlation_id. Фильмы из pending становятся видны после
+// delay поисков — так ведёт себя консьюмер в режиме Kafka.
+type memDB struct {
+	mu      sync.Mutex
+	ids     map[string]int64
+	pending []string
+	delay   int
+	selects int
+	rows    map[string][]map[string]driver.Value
+}
+
+func newMemDB() *memDB {
+	return &memDB{ids: make(map[string]int64), rows: make(map[string][]map[string]driver.Value)}
+}
+
+func (db *memDB) query(query string, args []driver.NamedValue) ([]string, [][]driver.Value) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if m := insertRe.FindStringSubmatch(query); m != nil {
+		table, cols := m[1], strings.Split(strings.ReplaceAll(m[2], `"`, ""), ",")
+		var ids [][]driver.Value
+		for start := 0; start+len(cols) <= len(args); start += len(cols) {
+			row := make(map[string]driver.Value, len(cols))
+			for i, col := range cols {
+				row[col] = args[start+i].Value
+			}
+			db.rows[table] = append(db.rows[table], row)
+
+			id := int64(len(db.rows[table]))
+			if table == "movies" {
+				db.ids[row["correlation_id"].(string)] = id
+			}
+			ids = append(ids, []driver.Value{id})
+		}
+		return []string{"id"}, ids
+	}
+
+	if resolveRe.MatchString(query) {
+		db.selects++
+		if db.selects > db.delay {
+			for _, corrID := range db.pending {
+				db.ids[corrID] = int64(len(db.ids) + 1)
+			}
+			db.pending = nil
+		}
+
+		var rows [][]driver.Value
+		for _, arg := range args {
+			if id, ok := db.ids[arg.Value.(string)]; ok {
+				rows = append(rows, []driver.Value{id, arg.Value})
+			}
+		}
+		return []string{"id", "correlation_id"}, rows
+	}
+	panic("unexpected query: " + query)
+}
+
+// fakePublisher складывает сообщения по топикам. Фильмы передаются в memDB
+// как ещё не созданные консьюмером.
+type fakePublisher struct {
+	db       *memDB
+	fail     string
+	messages map[string][]kafka.Message
+}
+
+func (p *fakePublisher) ProduceBatch(ctx context.Context, topic string, messages []kafka.Message) []error {
+	errs := make([]error, len(messages))
+	for i, m := range messages {
+		if p.fail != "" && strings.Contains(string(m.Value), p.fail) {
+			errs[i] = errors.New("broker unavailable")
+			continue
+		}
+		p.messages[topic] = append(p.messages[topic], m)
+		if topic == "movies" {
+			p.db.mu.Lock()
+			p.db.pending = append(p.db.pending, m.Key)
+			p.db.mu.Unlock()
+		}
+	}
+	return errs
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+var smallOptions = Options{Dir: "testdata/small", Runtime: 90, BatchSize: 2}
+
+// smallStats — итог загрузки testdata/small: фильм без года и жанров
+// пропущен, как и оценки этого фильма и оценка 0.0.
+var smallStats = Stats{Movies: 4, MoviesSkipped: 1, Reviews: 3, ReviewsSkipped: 2}
+
+func TestRunDirect(t *testing.T) {
+	db := newMemDB()
+	gdb := newTestDB(t, db.query)
+
+	stats, err := New(gdb, NewDirectSink(gdb), testLogger()).Run(context.Background(), smallOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != smallStats {
+		t.Errorf("stats = %+v, want %+v", stats, smallStats)
+	}
+
+	var titles []string
+	for _, row := range db.rows["movies"] {
+		titles = append(titles, row["title"].(string))
+	}
+	wantTitles := []string{"Toy Story", "The American President", "The City of Lost Children (Cité des enfants perdus, La)", "Babylon 5"}
+	if !reflect.DeepEqual(titles, wantTitles) {
+		t.Errorf("titles = %q, want %q", titles, wantTitles)
+	}
+	if genres := string(db.rows["movies"][2]["genres"].([]byte)); genres != `["Adventure","Drama","Fantasy","Mystery","Sci-Fi"]` {
+		t.Errorf("genres = %s", genres)
+	}
+
+	reviews := db.rows["reviews"]
+	if len(reviews) != 3 {
+		t.Fatalf("reviews = %d, want 3", len(reviews))
+	}
+	tests := []struct {
+		movieID int64
+		comment string
+		author  string
+		created time.Time
+	}{
+		{movieID: 1, comment: "Rated 4.0/5 on MovieLens", author: "movielens-user-1", created: time.Unix(964982703, 0).UTC()},
+		{movieID: 2, comment: "politics, romance", author: "movielens-user-2", created: time.Unix(1445714835, 0).UTC()},
+		{movieID: 4, comment: "Rated 4.5/5 on MovieLens", author: "movielens-user-3", created: time.Unix(1445715000, 0).UTC()},
+	}
+	for i, tt := range tests {
+		r := reviews[i]
+		if r["movie_id"] != tt.movieID || r["comment"] != tt.comment || r["author"] != tt.author || !r["created_at"].(time.Time).Equal(tt.created) {
+			t.Errorf("review %d = %v", i, r)
+		}
+	}
+
+	// Повторный запуск даёт те же correlation_id: дубли отсекает ON CONFLICT.
+	first := db.rows["movies"][0]["correlation_id"]
+	if _, err := New(gdb, NewDirectSink(gdb), testLogger()).Run(context.Background(), smallOptions); err != nil {
+		t.Fatal(err)
+	}
+	if again := db.rows["movies"][4]["correlation_id"]; again != first {
+		t.Errorf("correlation_id = %v on rerun, want %v", again, first)
+	}
+}
+
+func TestRunKafka(t *testing.T) {
+	defer func(poll time.Duration) { resolvePoll = poll }(resolvePoll)
+	resolvePoll = time.Millisecond
+
+	db := newMemDB()
+	db.delay = 2
+	pub := &fakePublisher{db: db, messages: make(map[string][]kafka.Message)}
+
+	opts := smallOptions
+	opts.ResolveTimeout = time.Minute
+	stats, err := New(newTestDB(t, db.query), NewKafkaSink(pub, "movies", "reviews"), testLogger()).Run(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != smallStats {
+		t.Errorf("stats = %+v, want %+v", stats, smallStats)
+	}
+	if db.selects != 3 {
+		t.Errorf("resolve queries = %d, want 3", db.selects)
+	}
+	if len(db.rows) != 0 {
+		t.Errorf("kafka mode wrote to the database: %v", db.rows)
+	}
+
+	movies := pub.messages["movies"]
+	if len(movies) != 4 {
+		t.Fatalf("movie messages = %d, want 4", len(movies))
+	}
+	var movie handler.MovieInput
+	if err := json.Unmarshal(movies[1].Value, &movie); err != nil {
+		t.Fatal(err)
+	}
+	if movie.Title != "The American President" || movie.Year != 1995 || movie.Runtime != 90 || movies[1].Key != movie.CorrelationId.String() {
+		t.Errorf("movie message = %s %+v", movies[1].Key, movie)
+	}
+
+	reviews := pub.messages["reviews"]
+	if len(reviews) != 3 {
+		t.Fatalf("review messages = %d, want 3", len(reviews))
+	}
+	var review handler.ReviewInput
+	if err := json.Unmarshal(reviews[1].Value, &review); err != nil {
+		t.Fatal(err)
+	}
+	if review.Comment != "politics, romance" || review.Rating != 3.5 || reviews[1].Key != "2" || review.MovieId != 2 {
+		t.Errorf("review message = %s %+v", reviews[1].Key, review)
+	}
+}
+
+func TestRunKafkaResolveTimeout(t *testing.T) {
+	defer func(poll time.Duration) { resolvePoll = poll }(resolvePoll)
+	resolvePoll = time.Millisecond
+
+	db := newMemDB()
+	db.delay = 1 << 30
+	pub := &fakePublisher{db: db, messages: make(map[string][]kafka.Message)}
+
+	opts := smallOptions
+	opts.ResolveTimeout = 20 * time.Millisecond
+	stats, err := New(newTestDB(t, db.query), NewKafkaSink(pub, "movies", "reviews"), testLogger()).Run(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Stats{Movies: 4, MoviesSkipped: 1, ReviewsSkipped: 5}
+	if stats != want {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+}
+
+func TestRunKafkaPublishError(t *testing.T) {
+	db := newMemDB()
+	pub := &fakePublisher{db: db, fail: "Toy Story", messages: make(map[string][]kafka.Message)}
+
+	_, err := New(newTestDB(t, db.query), NewKafkaSink(pub, "movies", "reviews"), testLogger()).Run(context.Background(), smallOptions)
+	if err == nil || err.Error() != "load movies: 1 of 2 messages to movies failed: broker unavailable" {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestRunLimits(t *testing.T) {
+	db := newMemDB()
+	gdb := newTestDB(t, db.query)
+
+	opts := smallOptions
+	opts.MoviesLimit, opts.RatingsLimit = 2, 1
+	stats, err := New(gdb, NewDirectSink(gdb), testLogger()).Run(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Stats{Movies: 2, Reviews: 1}); stats != want {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+}
+
+// TestRunMalformedFiles подменяет один файл testdata/small: ошибка формата
+// останавливает загрузку с указанием файла и строки (заголовок — строка 1).
+func TestRunMalformedFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		err     string
+	}{
+		{name: "movies header", file: "movies.csv", content: "id,title,genres\n", err: `movies.csv: unexpected header "id,title,genres", want "movieId,title,genres"`},
+		{name: "movies bom header", file: "movies.csv", content: "\ufeffmovieId,title,genres\n1,Heat (1995),Crime\n"},
+		{name: "movie id", file: "movies.csv", content: "movieId,title,genres\n1,Heat (1995),Crime\nx,Up (2009),Family\n", err: `movies.csv:3: invalid movieId "x"`},
+		{name: "movie fields", file: "movies.csv", content: "movieId,title,genres\n1,Heat (1995)\n", err: "movies.csv: record on line 2: wrong number of fields"},
+		{name: "movie quote", file: "movies.csv", content: "movieId,title,genres\n1,\"Heat\" (1995),Crime\n", err: `movies.csv: parse error on line 2, column 8: extraneous or missing " in quoted-field`},
+		{name: "ratings header", file: "ratings.csv", content: "userId,movieId,rating\n", err: "ratings.csv: record on line 1: wrong number of fields"},
+		{name: "user id", file: "ratings.csv", content: "userId,movieId,rating,timestamp\nu1,1,4.0,964982703\n", err: `ratings.csv:2: invalid userId "u1"`},
+		{name: "rating movie id", file: "ratings.csv", content: "userId,movieId,rating,timestamp\n1,m1,4.0,964982703\n", err: `ratings.csv:2: invalid movieId "m1"`},
+		{name: "rating", file: "ratings.csv", content: "userId,movieId,rating,timestamp\n1,1,four,964982703\n", err: `ratings.csv:2: invalid rating "four"`},
+		{name: "timestamp", file: "ratings.csv", content: "userId,movieId,rating,timestamp\n1,1,4.0,yesterday\n", err: `ratings.csv:2: invalid timestamp "yesterday"`},
+		{name: "tag ids", file: "tags.csv", content: "userId,movieId,tag,timestamp\n1,x,funny,964982703\n", err: `tags.csv:2: invalid userId/movieId "1"/"x"`},
+		{name: "no tags", file: "tags.csv"},
+		{name: "no movies", file: "movies.csv", err: "movies.csv: no such file or directory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, name := range []string{"movies.csv", "ratings.csv", "tags.csv"} {
+				content, err := os.ReadFile(filepath.Join("testdata/small", name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if name == tt.file {
+					if tt.content == "" {
+						continue
+					}
+					content = []byte(tt.content)
+				}
+				if err := os.WriteFile(filepath.Join(dir, name), content, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			db := newMemDB()
+			gdb := newTestDB(t, db.query)
+			opts := smallOptions
+			opts.Dir = dir
+			_, err := New(gdb, NewDirectSink(gdb), testLogger()).Run(context.Background(), opts)
+
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatalf("Run: %v", err)
+			case tt.err != "" && (err == nil || !strings.HasSuffix(err.Error(), tt.err)):
+				t.Fatalf("err = %v, want suffix %q", err, tt.err)
+			}
+			if tt.err != "" && !slices.Contains([]string{"tags.csv", "movies.csv"}, tt.file) && len(db.rows["movies"]) == 0 {
+				t.Error("movies were not loaded before the ratings error")
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package movielens
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// noGenres — так MovieLens помечает фильмы без жанров.
+const noGenres = "(no genres listed)"
+
+// maxTitleLen — длина колонки movies.title.
+const maxTitleLen = 100
+
+var (
+	// "Toy Story (1995)", "Babylon 5 (1994-1998)", "Stranger Things (2016– )".
+	titleYearRX = regexp.MustCompile(`^(.*?)\s*\((\d{4})(?:\s*[-–]\s*\d{0,4}\s*)?\)\s*$`)
+
+	// Артикли, которые MovieLens переносит в конец названия: "Matrix, The".
+	trailingArticles = []string{"The", "A", "An", "Les", "Le", "La", "L'", "Il", "Das", "Der", "Die", "El", "Los", "Las"}
+)
+
+// ParseTitle отделяет год от названия и возвращает артикль на место:
+// "American President, The (1995)" → "The American President", 1995.
+// Год равен нулю, если в названии его нет.
+func ParseTitle(raw string) (string, int32) {
+	title := strings.TrimSpace(raw)
+
+	var year int32
+	if m := titleYearRX.FindStringSubmatch(title); m != nil {
+		y, _ := strconv.Atoi(m[2])
+		title, year = m[1], int32(y)
+	}
+
+	// Альтернативное название в скобках идёт после основного:
+	// "City of Lost Children, The (Cité des enfants perdus, La)".
+	main, alt := title, ""
+	if i := strings.Index(title, " ("); i > 0 {
+		main, alt = title[:i], title[i:]
+	}
+	return moveArticle(main) + alt, year
+}
+
+func moveArticle(title string) string {
+	i := strings.LastIndex(title, ", ")
+	if i < 0 {
+		return title
+	}
+	article := title[i+2:]
+	for _, a := range trailingArticles {
+		if article != a {
+			continue
+		}
+		if strings.HasSuffix(a, "'") {
+			return a + title[:i]
+		}
+		return a + " " + title[:i]
+	}
+	return title
+}
+
+// ParseGenres разбирает список жанров "Adventure|Children|Fantasy".
+// Повторы отбрасываются, список обрезается до лимита data.ValidateMovie.
+func ParseGenres(raw string) []string {
+	genres := make([]string, 0, 5)
+	seen := make(map[string]bool)
+	for _, g := range strings.Split(raw, "|") {
+		g = strings.TrimSpace(g)
+		if g == "" || g == noGenres || seen[g] {
+			continue
+		}
+		seen[g] = true
+		genres = append(genres, g)
+		if len(genres) == 5 {
+			break
+		}
+	}
+	return genres
+}
+
+func titleFits(title string) bool {
+	return utf8.RuneCountInString(title) <= maxTitleLen
+}
@@ -0,0 +1,124 @@
+package movielens
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTitle(t *testing.T) {
+	tests := []struct {
+		raw   string
+		title string
+		year  int32
+	}{
+		{raw: "Toy Story (1995)", title: "Toy Story", year: 1995},
+		{raw: "  Heat (1995)  ", title: "Heat", year: 1995},
+		{raw: "American President, The (1995)", title: "The American President", year: 1995},
+		{raw: "Mummy, A (1932)", title: "A Mummy", year: 1932},
+		{raw: "Armée des ombres, L' (1969)", title: "L'Armée des ombres", year: 1969},
+		{raw: "City of Lost Children, The (Cité des enfants perdus, La) (1995)", title: "The City of Lost Children (Cité des enfants perdus, La)", year: 1995},
+		{raw: "Babylon 5 (1994-1998)", title: "Babylon 5", year: 1994},
+		{raw: "Stranger Things (2016– )", title: "Stranger Things", year: 2016},
+		{raw: "Hyena Road", title: "Hyena Road", year: 0},
+		{raw: "1408 (2007)", title: "1408", year: 2007},
+		{raw: "Love, Actually (2003)", title: "Love, Actually", year: 2003},
+		{raw: "Movie (199)", title: "Movie (199)", year: 0},
+	}
+	for _, tt := range tests {
+		title, year := ParseTitle(tt.raw)
+		if title != tt.title || year != tt.year {
+			t.Errorf("ParseTitle(%q) = %q, %d, want %q, %d", tt.raw, title, year, tt.title, tt.year)
+		}
+	}
+}
+
+func TestParseGenres(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want []string
+	}{
+		{raw: "Adventure|Children|Fantasy", want: []string{"Adventure", "Children", "Fantasy"}},
+		{raw: "(no genres listed)", want: []string{}},
+		{raw: "", want: []string{}},
+		{raw: "Drama| Drama |Comedy||", want: []string{"Drama", "Comedy"}},
+		{raw: "A|B|C|D|E|F|G", want: []string{"A", "B", "C", "D", "E"}},
+	}
+	for _, tt := range tests {
+		if got := ParseGenres(tt.raw); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseGenres(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{s: "short", n: 10, want: "short"},
+		{s: "exactly", n: 7, want: "exactly"},
+		{s: "trimmed", n: 4, want: "trim"},
+		{s: "ёжик", n: 3, want: "ё"},
+		{s: "ёжик", n: 1, want: ""},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.s, tt.n); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestConvertMovie(t *testing.T) {
+	tests := []struct {
+		name   string
+		row    MovieRow
+		errors map[string]string
+	}{
+		{name: "valid", row: MovieRow{MovieID: 1, Title: "Toy Story (1995)", Genres: "Animation"}},
+		{name: "no year", row: MovieRow{MovieID: 2, Title: "Hyena Road", Genres: "Drama"}, errors: map[string]string{"year": "must be provided"}},
+		{name: "no genres", row: MovieRow{MovieID: 3, Title: "Heat (1995)", Genres: "(no genres listed)"}, errors: map[string]string{"genres": "must be provided"}},
+		{name: "long title", row: MovieRow{MovieID: 4, Title: strings.Repeat("ж", 101) + " (1995)", Genres: "Drama"}, errors: map[string]string{"title": "must not be more than 100 characters long"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			movie, errs := convertMovie(tt.row, 90)
+			if !reflect.DeepEqual(errs, tt.errors) {
+				t.Errorf("errors = %v, want %v", errs, tt.errors)
+			}
+			again, _ := convertMovie(tt.row, 90)
+			if movie.CorrelationId != again.CorrelationId {
+				t.Error("correlation_id is not deterministic")
+			}
+		})
+	}
+}
+
+func TestConvertRating(t *testing.T) {
+	long := strings.Repeat("tag ", 300)
+
+	tests := []struct {
+		name    string
+		row     RatingRow
+		tags    []string
+		comment string
+		errors  map[string]string
+	}{
+		{name: "no tags", row: RatingRow{UserID: 1, MovieID: 1, Rating: 4}, comment: "Rated 4.0/5 on MovieLens"},
+		{name: "tags", row: RatingRow{UserID: 1, MovieID: 1, Rating: 3.5}, tags: []string{"funny", "pixar"}, comment: "funny, pixar"},
+		{name: "long tags", row: RatingRow{UserID: 1, MovieID: 1, Rating: 3.5}, tags: []string{long}, comment: long[:maxCommentLen]},
+		{name: "zero rating", row: RatingRow{UserID: 1, MovieID: 1, Rating: 0}, comment: "Rated 0.0/5 on MovieLens", errors: map[string]string{"rating": "must be between 0.5 and 5.0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			review, errs := convertRating(tt.row, 7, tt.tags)
+			if !reflect.DeepEqual(errs, tt.errors) {
+				t.Errorf("errors = %v, want %v", errs, tt.errors)
+			}
+			if review.Comment != tt.comment || review.MovieId != 7 || review.Author != "movielens-user-1" {
+				t.Errorf("review = %+v", review)
+			}
+		})
+	}
+}
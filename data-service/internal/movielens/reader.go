@@ -0,0 +1,156 @@
+package movielens
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MovieRow — строка movies.csv.
+type MovieRow struct {
+	MovieID int64
+	Title   string
+	Genres  string
+}
+
+// RatingRow — строка ratings.csv.
+type RatingRow struct {
+	UserID    int64
+	MovieID   int64
+	Rating    float32
+	Timestamp time.Time
+}
+
+type tagKey struct {
+	userID  int64
+	movieID int64
+}
+
+// reader читает CSV MovieLens и проверяет заголовок файла.
+type reader struct {
+	name string
+	file *os.File
+	csv  *csv.Reader
+	line int
+}
+
+func openCSV(path string, header ...string) (*reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &reader{name: path, file: f, csv: csv.NewReader(f)}
+	r.csv.FieldsPerRecord = len(header)
+	r.csv.ReuseRecord = true
+
+	got, err := r.next()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	for i, col := range header {
+		if strings.TrimPrefix(got[i], "\ufeff") != col {
+			f.Close()
+			return nil, fmt.Errorf("%s: unexpected header %q, want %q", path, strings.Join(got, ","), strings.Join(header, ","))
+		}
+	}
+	return r, nil
+}
+
+// next возвращает очередную запись или io.EOF.
+func (r *reader) next() ([]string, error) {
+	rec, err := r.csv.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("%s: %w", r.name, err)
+	}
+	r.line++
+	return rec, nil
+}
+
+func (r *reader) errorf(format string, args ...any) error {
+	return fmt.Errorf("%s:%d: %s", r.name, r.line, fmt.Sprintf(format, args...))
+}
+
+func (r *reader) Close() error {
+	return r.file.Close()
+}
+
+func (r *reader) nextMovie() (MovieRow, error) {
+	rec, err := r.next()
+	if err != nil {
+		return MovieRow{}, err
+	}
+	id, err := strconv.ParseInt(rec[0], 10, 64)
+	if err != nil {
+		return MovieRow{}, r.errorf("invalid movieId %q", rec[0])
+	}
+	return MovieRow{MovieID: id, Title: rec[1], Genres: rec[2]}, nil
+}
+
+func (r *reader) nextRating() (RatingRow, error) {
+	rec, err := r.next()
+	if err != nil {
+		return RatingRow{}, err
+	}
+	user, err := strconv.ParseInt(rec[0], 10, 64)
+	if err != nil {
+		return RatingRow{}, r.errorf("invalid userId %q", rec[0])
+	}
+	movie, err := strconv.ParseInt(rec[1], 10, 64)
+	if err != nil {
+		return RatingRow{}, r.errorf("invalid movieId %q", rec[1])
+	}
+	rating, err := strconv.ParseFloat(rec[2], 32)
+	if err != nil {
+		return RatingRow{}, r.errorf("invalid rating %q", rec[2])
+	}
+	ts, err := strconv.ParseInt(rec[3], 10, 64)
+	if err != nil {
+		return RatingRow{}, r.errorf("invalid timestamp %q", rec[3])
+	}
+	return RatingRow{UserID: user, MovieID: movie, Rating: float32(rating), Timestamp: time.Unix(ts, 0).UTC()}, nil
+}
+
+// readTags собирает теги tags.csv по паре пользователь–фильм. Отсутствие
+// файла не ошибка: в некоторых выгрузках MovieLens тегов нет.
+func readTags(path string) (map[tagKey][]string, error) {
+	tags := make(map[tagKey][]string)
+
+	r, err := openCSV(path, "userId", "movieId", "tag", "timestamp")
+	if errors.Is(err, os.ErrNotExist) {
+		return tags, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	for {
+		rec, err := r.next()
+		if errors.Is(err, io.EOF) {
+			return tags, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		user, err1 := strconv.ParseInt(rec[0], 10, 64)
+		movie, err2 := strconv.ParseInt(rec[1], 10, 64)
+		if err1 != nil || err2 != nil {
+			return nil, r.errorf("invalid userId/movieId %q/%q", rec[0], rec[1])
+		}
+		tag := strings.TrimSpace(rec[2])
+		if tag == "" {
+			continue
+		}
+		key := tagKey{userID: user, movieID: movie}
+		tags[key] = append(tags[key], tag)
+	}
+}
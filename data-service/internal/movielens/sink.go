@@ -0,0 +1,119 @@
+package movielens
+
+import (
+	"context"
+	"data-service/internal/data"
+	"data-service/internal/handler"
+	"data-service/internal/kafka"
+	"encoding/json"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strconv"
+)
+
+// Sink принимает пачки фильмов и отзывов. Повторная загрузка тех же записей
+// не должна создавать дублей: correlation_id у них детерминированный.
+type Sink interface {
+	Movies(ctx context.Context, movies []data.Movie) error
+	Reviews(ctx context.Context, reviews []data.Review) error
+}
+
+// DirectSink пишет записи в Postgres в обход Kafka. Уже загруженные
+// записи пропускаются по конфликту correlation_id.
+type DirectSink struct {
+	db *gorm.DB
+}
+
+func NewDirectSink(db *gorm.DB) *DirectSink {
+	return &DirectSink{db: db}
+}
+
+func (s *DirectSink) Movies(ctx context.Context, movies []data.Movie) error {
+	return s.insert(ctx, &movies)
+}
+
+func (s *DirectSink) Reviews(ctx context.Context, reviews []data.Review) error {
+	return s.insert(ctx, &reviews)
+}
+
+func (s *DirectSink) insert(ctx context.Context, rows any) error {
+	return s.db.WithContext(ctx).
+		Omit(clause.Associations).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "correlation_id"}}, DoNothing: true}).
+		Create(rows).Error
+}
+
+// Publisher — часть kafka.Producer, нужная KafkaSink.
+type Publisher interface {
+	ProduceBatch(ctx context.Context, topic string, messages []kafka.Message) []error
+}
+
+// KafkaSink публикует записи в топики сервиса в том же формате, что и
+// api-service, — они проходят через консьюмеры с обычной валидацией.
+type KafkaSink struct {
+	producer Publisher
+	topics   struct{ movie, review string }
+}
+
+func NewKafkaSink(producer Publisher, movieTopic, reviewTopic string) *KafkaSink {
+	s := &KafkaSink{producer: producer}
+	s.topics.movie, s.topics.review = movieTopic, reviewTopic
+	return s
+}
+
+func (s *KafkaSink) Movies(ctx context.Context, movies []data.Movie) error {
+	msgs := make([]kafka.Message, len(movies))
+	for i, m := range movies {
+		value, err := json.Marshal(handler.MovieInput{
+			Title:         m.Title,
+			Year:          m.Year,
+			Runtime:       m.Runtime,
+			Genres:        m.Genres,
+			CorrelationId: m.CorrelationId,
+		})
+		if err != nil {
+			return err
+		}
+		msgs[i] = kafka.Message{Key: m.CorrelationId.String(), Value: value}
+	}
+	return s.publish(ctx, s.topics.movie, msgs)
+}
+
+func (s *KafkaSink) Reviews(ctx context.Context, reviews []data.Review) error {
+	msgs := make([]kafka.Message, len(reviews))
+	for i, r := range reviews {
+		value, err := json.Marshal(handler.ReviewInput{
+			CorrelationId: r.CorrelationId,
+			MovieId:       r.MovieId,
+			Rating:        r.Rating,
+			Comment:       r.Comment,
+			Author:        r.Author,
+		})
+		if err != nil {
+			return err
+		}
+		// Ключ — фильм: отзывы одного фильма попадают в одну партицию.
+		msgs[i] = kafka.Message{Key: strconv.FormatUint(uint64(r.MovieId), 10), Value: value}
+	}
+	return s.publish(ctx, s.topics.review, msgs)
+}
+
+func (s *KafkaSink) publish(ctx context.Context, topic string, msgs []kafka.Message) error {
+	var (
+		failed int
+		first  error
+	)
+	for _, err := range s.producer.ProduceBatch(ctx, topic, msgs) {
+		if err != nil {
+			failed++
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d messages to %s failed: %w", failed, len(msgs), topic, first)
+	}
+	return nil
+}
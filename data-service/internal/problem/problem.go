@@ -0,0 +1,122 @@
+package problem
+
+import (
+	"data-service/internal/requestid"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// ContentType — медиатип ответов об ошибках по RFC 7807.
+const ContentType = "application/problem+json"
+
+// Стабильные коды ошибок. Клиенты опираются на них, а не на текст detail,
+// поэтому менять существующие значения нельзя — только добавлять новые.
+const (
+	CodeInvalidRequest       = "invalid_request"
+	CodeValidationFailed     = "validation_failed"
+	CodeUnauthorized         = "unauthorized"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not_found"
+	CodeGone                 = "gone"
+	CodeNotAcceptable        = "not_acceptable"
+	CodeEditConflict         = "edit_conflict"
+	CodePreconditionFailed   = "precondition_failed"
+	CodePreconditionNeeded   = "precondition_required"
+	CodeIdempotencyKeyReused = "idempotency_key_reused"
+	CodeRequestInProgress    = "request_in_progress"
+	CodeInternal             = "internal_error"
+	CodeBadGateway           = "bad_gateway"
+	CodeUpstreamUnavailable  = "upstream_unavailable"
+	CodeUpstreamTimeout      = "upstream_timeout"
+)
+
+type Problem struct {
+	Type      string            `json:"type"`
+	Title     string            `json:"title"`
+	Status    int               `json:"status"`
+	Detail    string            `json:"detail,omitempty"`
+	Instance  string            `json:"instance,omitempty"`
+	Code      string            `json:"code"`
+	RequestID string            `json:"request_id,omitempty"`
+	Errors    map[string]string `json:"errors,omitempty"`
+	// Restore — путь POST-запроса, который вернёт удалённую запись из
+	// корзины; есть только в ответах 410.
+	Restore string `json:"restore,omitempty"`
+}
+
+func New(status int, code, detail string) *Problem {
+	return &Problem{
+		Type:   "urn:reviews-movies:problem:" + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Code + ": " + p.Detail
+	}
+	return p.Code
+}
+
+func Write(c *gin.Context, p *Problem) {
+	if p.Instance == "" {
+		p.Instance = c.Request.URL.Path
+	}
+	p.RequestID = requestid.FromContext(c.Request.Context())
+
+	c.Header("Content-Type", ContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
+
+func BadRequest(c *gin.Context, detail string) {
+	Write(c, New(http.StatusBadRequest, CodeInvalidRequest, detail))
+}
+
+func Validation(c *gin.Context, errors map[string]string) {
+	p := New(http.StatusUnprocessableEntity, CodeValidationFailed, "one or more fields are invalid")
+	p.Errors = errors
+	Write(c, p)
+}
+
+func Unauthorized(c *gin.Context, detail string) {
+	Write(c, New(http.StatusUnauthorized, CodeUnauthorized, detail))
+}
+
+func Forbidden(c *gin.Context, detail string) {
+	Write(c, New(http.StatusForbidden, CodeForbidden, detail))
+}
+
+func NotFound(c *gin.Context, detail string) {
+	Write(c, New(http.StatusNotFound, CodeNotFound, detail))
+}
+
+func NotAcceptable(c *gin.Context, detail string) {
+	Write(c, New(http.StatusNotAcceptable, CodeNotAcceptable, detail))
+}
+
+// Gone отвечает на запрос к записи из корзины. restore — путь, по которому
+// её можно восстановить.
+func Gone(c *gin.Context, detail, restore string) {
+	p := New(http.StatusGone, CodeGone, detail)
+	p.Restore = restore
+	Write(c, p)
+}
+
+func Conflict(c *gin.Context, detail string) {
+	Write(c, New(http.StatusConflict, CodeEditConflict, detail))
+}
+
+func PreconditionFailed(c *gin.Context, detail string) {
+	Write(c, New(http.StatusPreconditionFailed, CodePreconditionFailed, detail))
+}
+
+func PreconditionRequired(c *gin.Context, detail string) {
+	Write(c, New(http.StatusPreconditionRequired, CodePreconditionNeeded, detail))
+}
+
+func Internal(c *gin.Context, detail string) {
+	Write(c, New(http.StatusInternalServerError, CodeInternal, detail))
+}
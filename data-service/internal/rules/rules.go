@@ -0,0 +1,59 @@
+// Package rules содержит правила проверки фильмов и рецензий, общие для
+// data-service и шлюза: шлюз получает копию пакета через cmd/syncshared и
+// отклоняет неверные записи ещё до публикации в Kafka.
+package rules
+
+import (
+	"data-service/internal/validator"
+	"github.com/google/uuid"
+	"strings"
+	"time"
+)
+
+// Movie — проверяемые поля фильма.
+type Movie struct {
+	Title   string
+	Year    int32
+	Runtime int32
+	Genres  []string
+}
+
+// Review — проверяемые поля рецензии.
+type Review struct {
+	CorrelationId uuid.UUID
+	MovieId       uint
+	Rating        float32
+	Comment       string
+	Author        string
+}
+
+func ValidateMovie(v *validator.Validator, movie Movie) {
+	v.Check(movie.Title != "", "title", "must be provided")
+	v.Check(len(movie.Title) <= 500, "title", "must not be more than 500 bytes long")
+
+	v.Check(movie.Year != 0, "year", "must be provided")
+	v.Check(movie.Year >= 1888, "year", "must be greater than 1888")
+	v.Check(movie.Year <= int32(time.Now().Year()), "year", "must not be in the future")
+
+	v.Check(movie.Runtime != 0, "runtime", "must be provided")
+	v.Check(movie.Runtime > 0, "runtime", "must be a positive integer")
+
+	v.Check(movie.Genres != nil, "genres", "must be provided")
+	v.Check(len(movie.Genres) != 0, "genres", "must be provided")
+	v.Check(len(movie.Genres) <= 5, "genres", "must not contain more than 5 genres")
+	v.Check(validator.Unique(movie.Genres), "genres", "must not contain duplicate values")
+}
+
+func ValidateReview(v *validator.Validator, review Review) {
+	v.Check(review.CorrelationId != uuid.Nil, "correlation_id", "must be a valid UUID")
+
+	v.Check(review.MovieId != 0, "movie_id", "must be provided")
+
+	v.Check(review.Rating >= 0.5 && review.Rating <= 5.0, "rating", "must be between 0.5 and 5.0")
+
+	v.Check(strings.TrimSpace(review.Comment) != "", "comment", "must be provided")
+	v.Check(len(review.Comment) <= 1000, "comment", "must not be more than 1000 characters")
+
+	v.Check(strings.TrimSpace(review.Author) != "", "author", "must be provided")
+	v.Check(len(review.Author) <= 50, "author", "must not be more than 50 characters")
+}
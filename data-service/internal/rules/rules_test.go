@@ -0,0 +1,79 @@
+package rules
+
+import (
+	"data-service/internal/validator"
+	"github.com/google/uuid"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateMovie(t *testing.T) {
+	valid := Movie{Title: "Heat", Year: 1995, Runtime: 170, Genres: []string{"crime", "drama"}}
+
+	tests := []struct {
+		name   string
+		modify func(m *Movie)
+		errors map[string]string
+	}{
+		{name: "valid", modify: func(m *Movie) {}, errors: map[string]string{}},
+		{name: "no title", modify: func(m *Movie) { m.Title = "" }, errors: map[string]string{"title": "must be provided"}},
+		{name: "long title", modify: func(m *Movie) { m.Title = strings.Repeat("a", 501) }, errors: map[string]string{"title": "must not be more than 500 bytes long"}},
+		{name: "no year", modify: func(m *Movie) { m.Year = 0 }, errors: map[string]string{"year": "must be provided"}},
+		{name: "early year", modify: func(m *Movie) { m.Year = 1887 }, errors: map[string]string{"year": "must be greater than 1888"}},
+		{name: "future year", modify: func(m *Movie) { m.Year = int32(time.Now().Year() + 1) }, errors: map[string]string{"year": "must not be in the future"}},
+		{name: "negative runtime", modify: func(m *Movie) { m.Runtime = -1 }, errors: map[string]string{"runtime": "must be a positive integer"}},
+		{name: "nil genres", modify: func(m *Movie) { m.Genres = nil }, errors: map[string]string{"genres": "must be provided"}},
+		{name: "empty genres", modify: func(m *Movie) { m.Genres = []string{} }, errors: map[string]string{"genres": "must be provided"}},
+		{name: "too many genres", modify: func(m *Movie) { m.Genres = []string{"a", "b", "c", "d", "e", "f"} }, errors: map[string]string{"genres": "must not contain more than 5 genres"}},
+		{name: "duplicate genres", modify: func(m *Movie) { m.Genres = []string{"drama", "drama"} }, errors: map[string]string{"genres": "must not contain duplicate values"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := valid
+			tt.modify(&m)
+
+			v := validator.New()
+			ValidateMovie(v, m)
+			if !reflect.DeepEqual(v.Errors, tt.errors) {
+				t.Errorf("errors = %v, want %v", v.Errors, tt.errors)
+			}
+		})
+	}
+}
+
+func TestValidateReview(t *testing.T) {
+	valid := Review{CorrelationId: uuid.New(), MovieId: 1, Rating: 4.5, Comment: "good", Author: "ann"}
+
+	tests := []struct {
+		name   string
+		modify func(r *Review)
+		errors map[string]string
+	}{
+		{name: "valid", modify: func(r *Review) {}, errors: map[string]string{}},
+		{name: "no correlation id", modify: func(r *Review) { r.CorrelationId = uuid.Nil }, errors: map[string]string{"correlation_id": "must be a valid UUID"}},
+		{name: "no movie", modify: func(r *Review) { r.MovieId = 0 }, errors: map[string]string{"movie_id": "must be provided"}},
+		{name: "rating too low", modify: func(r *Review) { r.Rating = 0.4 }, errors: map[string]string{"rating": "must be between 0.5 and 5.0"}},
+		{name: "rating too high", modify: func(r *Review) { r.Rating = 5.1 }, errors: map[string]string{"rating": "must be between 0.5 and 5.0"}},
+		{name: "rating bounds", modify: func(r *Review) { r.Rating = 0.5 }, errors: map[string]string{}},
+		{name: "blank comment", modify: func(r *Review) { r.Comment = "  " }, errors: map[string]string{"comment": "must be provided"}},
+		{name: "long comment", modify: func(r *Review) { r.Comment = strings.Repeat("a", 1001) }, errors: map[string]string{"comment": "must not be more than 1000 characters"}},
+		{name: "blank author", modify: func(r *Review) { r.Author = "" }, errors: map[string]string{"author": "must be provided"}},
+		{name: "long author", modify: func(r *Review) { r.Author = strings.Repeat("a", 51) }, errors: map[string]string{"author": "must not be more than 50 characters"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := valid
+			tt.modify(&r)
+
+			v := validator.New()
+			ValidateReview(v, r)
+			if !reflect.DeepEqual(v.Errors, tt.errors) {
+				t.Errorf("errors = %v, want %v", v.Errors, tt.errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package trash
+
+import (
+	"context"
+	"data-service/internal/metrics"
+	"data-service/internal/models"
+	"log/slog"
+	"time"
+)
+
+// Purger раз в Interval окончательно удаляет записи, пролежавшие в
+// корзине дольше Retention. Несколько инстансов могут работать
+// одновременно: каждую строку удаляет кто-то один.
+type Purger struct {
+	Trash     *models.TrashModel
+	Retention time.Duration
+	Interval  time.Duration
+	Logger    *slog.Logger
+}
+
+// Run очищает корзину до отмены ctx. Первый проход — сразу после запуска.
+func (p *Purger) Run(ctx context.Context) {
+	if p.Interval <= 0 {
+		p.Logger.Info("trash purger disabled")
+		return
+	}
+	// Без срока хранения очистка стёрла бы всю корзину.
+	if p.Retention <= 0 {
+		p.Logger.Error("trash purger disabled: retention must be positive", "retention", p.Retention)
+		return
+	}
+
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		p.purge(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Purger) purge(ctx context.Context) {
+	movies, reviews, err := p.Trash.PurgeExpired(ctx, time.Now().Add(-p.Retention))
+	metrics.TrashPurged.WithLabelValues("movies").Add(float64(movies))
+	metrics.TrashPurged.WithLabelValues("reviews").Add(float64(reviews))
+	if err != nil {
+		if ctx.Err() == nil {
+			p.Logger.ErrorContext(ctx, "trash purge failed", "movies", movies, "reviews", reviews, "error", err)
+		}
+		return
+	}
+	if movies > 0 || reviews > 0 {
+		p.Logger.InfoContext(ctx, "trash purged", "movies", movies, "reviews", reviews)
+	}
+}
@@ -1,15 +1,38 @@
 package database
 
 import (
+	"context"
 	"data-service/internal/config"
+	"data-service/internal/logger"
+	"data-service/internal/metrics"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	gormtracing "gorm.io/plugin/opentelemetry/tracing"
+	"log/slog"
 )
 
-func OpenDB(cfg *config.Config) (*gorm.DB, error) {
-	db, err := gorm.Open(postgres.Open(cfg.DB.Dsn), &gorm.Config{})
+func OpenDB(cfg *config.Config, log *slog.Logger) (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.Open(cfg.DB.Dsn), &gorm.Config{
+		Logger: logger.NewGormLogger(log),
+	})
 	if err != nil {
 		return nil, err
 	}
+	if err := db.Use(gormtracing.NewPlugin(gormtracing.WithoutMetrics())); err != nil {
+		return nil, err
+	}
+	if err := metrics.InstrumentGorm(db, "postgres"); err != nil {
+		return nil, err
+	}
 	return db, nil
 }
+
+func Ping(db *gorm.DB) func(context.Context) error {
+	return func(ctx context.Context) error {
+		conn, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return conn.PingContext(ctx)
+	}
+}